@@ -2,75 +2,330 @@ package main
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/certs"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clicks"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/health"
 	apphttp "github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/http"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/jobs"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/rate"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/rpc"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/postgres"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/traced"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/tracing"
 )
 
 func main() {
 	// 1. Load .env file
 	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, relying on system environment variables")
+		slog.Info("no .env file found, relying on system environment variables")
 	}
 
 	// 2. Load Config
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("config error: %v", err)
+		slog.Error("config error", "error", err)
+		os.Exit(1)
 	}
 
-	// 3. Setup Logger
-	logger := observability.NewLogger()
+	// 3. Setup Logger (the level follows config reloads)
+	level := new(slog.LevelVar)
+	level.Set(cfg.LogLevel)
+	logger, err := observability.NewLogger(os.Stdout, cfg.LogFormat, level)
+	if err != nil {
+		slog.Error("logger setup error", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
 
 	// 4. Connect to Database (Using your robust internal package)
 	// We increase the timeout to 30s in case Supabase is "waking up"
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	logger.Println("Connecting to Database...")
+	logger.Info("connecting to database")
 	pool, err := postgres.Open(ctx, cfg.DatabaseURL)
 	if err != nil {
-		logger.Fatalf("db connect error: %v", err)
+		logger.Error("db connect error", "error", err)
+		os.Exit(1)
 	}
 	defer pool.Close()
-	logger.Println("Database connection established successfully.")
+	logger.Info("database connection established")
+
+	// 5. Setup Click Pipeline, Router & Server
+	pipeline := clicks.NewPipeline(postgres.NewClicksRepo(pool), logger, clicks.Options{
+		QueueSize:    cfg.ClickQueueSize,
+		Workers:      cfg.ClickWorkers,
+		DedupeWindow: cfg.DedupeWindow,
+	})
+	pipeline.Start()
+
+	metrics := observability.NewMetrics()
+	registerRuntimeMetrics(metrics, pool, pipeline)
+
+	tracer := newTracer(cfg, logger)
+
+	// Readiness: DB reachable, schema migrated, click queue has room, not shutting down
+	checker := health.NewChecker(
+		health.Database(pool),
+		health.Migrations(pool),
+		health.ClickPipeline(pipeline, 0.9),
+	)
+
+	// Rate limit counters, shared by the HTTP and gRPC servers (and with postgres, by all instances)
+	var limits rate.Backend = rate.NewMemory(0)
+	var rateLimitRepo storage.RateLimitRepo
+	if cfg.RateLimitStore == "postgres" {
+		rateLimitRepo = traced.NewRateLimitRepo(postgres.NewRateLimitRepo(pool), tracer)
+		limits = rate.NewShared(rateLimitRepo)
+	}
+	if mem, ok := limits.(*rate.Memory); ok {
+		metrics.GaugeFunc("rate_limit_keys", "Clients tracked by the in-memory rate limiter.", func() float64 { return float64(mem.Len()) })
+	}
+
+	// Settings replaced on SIGHUP, shared by the HTTP and gRPC servers
+	live := liveSettings{
+		level:  level,
+		limits: rate.NewSettings(cfg.RateLimits),
+		rules:  links.NewRules(cfg.ReservedWords, cfg.BlockedHosts),
+	}
 
-	// 5. Setup Router & Server
 	router := apphttp.NewRouter(apphttp.Deps{
-		Config: cfg,
-		Logger: logger,
-		DB:     pool,
+		Config:     cfg,
+		Logger:     logger,
+		DB:         pool,
+		Clicks:     pipeline,
+		Metrics:    metrics,
+		Tracer:     tracer,
+		Health:     checker,
+		Limits:     limits,
+		RateLimits: live.limits,
+		Rules:      live.rules,
 	})
 
-	srv := apphttp.NewServer(cfg, logger, router)
+	// TLS: certificates are picked by SNI and reloaded when their files change
+	var certStore *certs.Store
+	var tlsConfig *tls.Config
+	if cfg.TLSEnabled() {
+		certStore, err = certs.New(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSCertDir, logger)
+		if err != nil {
+			logger.Error("tls setup error", "error", err)
+			os.Exit(1)
+		}
+		tlsConfig = certStore.TLSConfig()
+		warnUncoveredDomains(ctx, logger, certStore, postgres.NewDomainsRepo(pool))
+	}
+
+	srv := apphttp.NewServer(cfg, logger, router, tlsConfig)
+	var redirectSrv *http.Server
+	if cfg.HTTPRedirectPort > 0 {
+		redirectSrv = apphttp.NewRedirectServer(cfg, logger)
+	}
+
+	var grpcSrv *grpc.Server
+	if cfg.GRPCPort > 0 {
+		grpcSrv = rpc.NewServer(rpc.Deps{
+			Config:     cfg,
+			Logger:     logger,
+			DB:         pool,
+			Metrics:    metrics,
+			Tracer:     tracer,
+			Limits:     limits,
+			TLS:        tlsConfig,
+			RateLimits: live.limits,
+			Rules:      live.rules,
+		})
+	}
+
+	// Background jobs (analytics rollups, raw click retention, expired rate limit counters)
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	scheduler := jobs.NewScheduler(logger)
+	scheduler.Add(jobs.Rollup(postgres.NewRollupsRepo(pool), cfg.RollupInterval))
+	retention := time.Duration(cfg.ClickRetention) * 24 * time.Hour
+	scheduler.Add(jobs.PurgeClicks(postgres.NewClicksRepo(pool), logger, retention, cfg.PurgeBatchSize, cfg.PurgeInterval))
+	if rateLimitRepo != nil {
+		scheduler.Add(jobs.PurgeRateLimits(rateLimitRepo, cfg.PurgeInterval))
+	}
+	scheduler.Start(jobsCtx)
+	if certStore != nil {
+		go certStore.Watch(jobsCtx, cfg.TLSReloadInterval)
+	}
 
 	// 6. Start Server in Background
 	go func() {
-		logger.Printf("http listening on %s", srv.Addr)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatalf("http server error: %v", err)
+		logger.Info("http listening", "addr", srv.Addr, "tls", tlsConfig != nil)
+		serve := srv.ListenAndServe
+		if tlsConfig != nil {
+			serve = func() error { return srv.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && err != http.ErrServerClosed {
+			logger.Error("http server error", "error", err)
+			os.Exit(1)
 		}
 	}()
+	if redirectSrv != nil {
+		go func() {
+			logger.Info("https redirect listening", "addr", redirectSrv.Addr)
+			if err := redirectSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("https redirect server error", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
 
-	// 7. Graceful Shutdown
+	if grpcSrv != nil {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			logger.Error("grpc listen error", "error", err)
+			os.Exit(1)
+		}
+		go func() {
+			logger.Info("grpc listening", "addr", lis.Addr().String(), "tls", tlsConfig != nil)
+			if err := grpcSrv.Serve(lis); err != nil {
+				logger.Error("grpc server error", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// 7. Reload on SIGHUP, Graceful Shutdown on SIGINT/SIGTERM
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-	<-stop
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+wait:
+	for {
+		select {
+		case <-hup:
+			cfg = live.reload(logger, cfg)
+		case <-stop:
+			break wait
+		}
+	}
 
+	logger.Info("shutting down")
+	// Fail readiness first so load balancers stop sending new requests
+	checker.SetDraining()
+	if cfg.DrainDelay > 0 {
+		time.Sleep(cfg.DrainDelay)
+	}
 	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancelShutdown()
-	logger.Println("shutting down...")
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		logger.Printf("graceful shutdown error: %v", err)
+		logger.Error("graceful shutdown error", "error", err)
+	}
+	if redirectSrv != nil {
+		_ = redirectSrv.Shutdown(shutdownCtx)
+	}
+	if grpcSrv != nil {
+		stopGRPC(shutdownCtx, grpcSrv)
+	}
+	// Store clicks still queued before the pool closes
+	pipeline.Close()
+	stopJobs()
+	scheduler.Wait()
+	if err := tracer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("trace flush error", "error", err)
+	}
+}
+
+// liveSettings are the settings a running server takes from a reloaded configuration
+type liveSettings struct {
+	level  *slog.LevelVar
+	limits *rate.Settings
+	rules  *links.Rules
+}
+
+// reload reads the configuration again and applies its reloadable settings, returning
+// the configuration now in use. An invalid configuration is logged and ignored. The
+// process environment cannot change, so in practice the new values come from CONFIG_FILE.
+func (s liveSettings) reload(logger *slog.Logger, cur config.Config) config.Config {
+	next, err := config.Load()
+	if err != nil {
+		logger.Error("config reload failed, keeping the current configuration", "error", err)
+		return cur
+	}
+	changed, restart := config.Diff(cur, next)
+	if len(restart) > 0 {
+		logger.Warn("config changes need a restart to apply", "settings", restart)
+	}
+	s.level.Set(next.LogLevel)
+	s.limits.Set(next.RateLimits)
+	s.rules.Set(next.ReservedWords, next.BlockedHosts)
+	logger.Info("config reloaded", "changed", changed)
+	return config.Reload(cur, next)
+}
+
+// stopGRPC lets in-flight calls finish, cutting them off when ctx expires
+func stopGRPC(ctx context.Context, s *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.Stop()
+	}
+}
+
+// warnUncoveredDomains logs the custom domains no certificate is valid for; their
+// visitors would get the default certificate and a browser warning
+func warnUncoveredDomains(ctx context.Context, logger *slog.Logger, store *certs.Store, repo storage.DomainsRepo) {
+	ds, err := repo.List(ctx)
+	if err != nil {
+		logger.Warn("could not list custom domains to check certificates", "error", err)
+		return
+	}
+	for _, d := range ds {
+		if !store.Covers(d.Host) {
+			logger.Warn("no tls certificate for custom domain", "domain", d.Host)
+		}
+	}
+}
+
+// newTracer builds the configured span exporter; nil (tracing off) for "none"
+func newTracer(cfg config.Config, logger *slog.Logger) *tracing.Tracer {
+	switch cfg.TraceExporter {
+	case "stdout":
+		return tracing.New(tracing.NewStdoutExporter(os.Stdout), logger)
+	case "otlp":
+		return tracing.New(tracing.NewOTLPExporter(cfg.OTLPEndpoint, cfg.ServiceName), logger)
 	}
+	return nil
+}
+
+// registerRuntimeMetrics exposes click pipeline and connection pool state, sampled on every scrape
+func registerRuntimeMetrics(m *observability.Metrics, pool *pgxpool.Pool, pipeline *clicks.Pipeline) {
+	m.GaugeFunc("click_queue_depth", "Clicks waiting to be stored.", func() float64 { return float64(pipeline.QueueDepth()) })
+	m.GaugeFunc("click_queue_capacity", "Maximum number of queued clicks.", func() float64 { return float64(pipeline.Capacity()) })
+	m.CounterFunc("clicks_dropped_total", "Clicks dropped because the queue was full.", func() float64 { return float64(pipeline.Dropped()) })
+
+	m.GaugeFunc("db_pool_total_conns", "Open database connections.", func() float64 { return float64(pool.Stat().TotalConns()) })
+	m.GaugeFunc("db_pool_acquired_conns", "Database connections in use.", func() float64 { return float64(pool.Stat().AcquiredConns()) })
+	m.GaugeFunc("db_pool_idle_conns", "Idle database connections.", func() float64 { return float64(pool.Stat().IdleConns()) })
+	m.GaugeFunc("db_pool_max_conns", "Maximum database connections.", func() float64 { return float64(pool.Stat().MaxConns()) })
+	m.CounterFunc("db_pool_acquires_total", "Successful connection acquires.", func() float64 { return float64(pool.Stat().AcquireCount()) })
+	m.CounterFunc("db_pool_empty_acquires_total", "Acquires that had to wait for a connection.", func() float64 { return float64(pool.Stat().EmptyAcquireCount()) })
+	m.CounterFunc("db_pool_canceled_acquires_total", "Acquires canceled by their context.", func() float64 { return float64(pool.Stat().CanceledAcquireCount()) })
+	m.CounterFunc("db_pool_acquire_seconds_total", "Total time spent acquiring connections.", func() float64 { return pool.Stat().AcquireDuration().Seconds() })
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+)
+
+// configCheck loads the configuration the way the server does and reports every
+// problem; it needs no database
+func configCheck(args []string) error {
+	if _, err := parse(flag.NewFlagSet("config check", flag.ContinueOnError), args, 0, ""); err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.ReplaceAll(err.Error(), "\n", "\n  "))
+	}
+	if cfg.File != "" {
+		fmt.Printf("configuration ok (environment over %s)\n", cfg.File)
+	} else {
+		fmt.Println("configuration ok (environment only)")
+	}
+	return nil
+}
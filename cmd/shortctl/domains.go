@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/postgres"
+)
+
+func (a *app) domains() *postgres.DomainsRepo {
+	return postgres.NewDomainsRepo(a.pool)
+}
+
+// domainFlag registers -domain, the custom domain a key belongs to
+func domainFlag(fs *flag.FlagSet) *string {
+	return fs.String("domain", "", "custom domain of the link (default BASE_URL)")
+}
+
+// parseHost normalizes and validates a domain argument
+func parseHost(s string) (string, error) {
+	host := domain.NormalizeHost(s)
+	if !domain.ValidateHost(host) {
+		return "", fmt.Errorf("invalid domain %q: want a host name like go.example.com", s)
+	}
+	return host, nil
+}
+
+func domainsAdd(ctx context.Context, a *app, args []string) error {
+	pos, err := parse(flag.NewFlagSet("domains add", flag.ContinueOnError), args, 1, "<host>")
+	if err != nil {
+		return err
+	}
+	host, err := parseHost(pos[0])
+	if err != nil {
+		return err
+	}
+	d, err := a.domains().Create(ctx, host)
+	if errors.Is(err, domain.ErrDomainExists) {
+		return fmt.Errorf("domain %q already registered", host)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("added %s; point its DNS at this server and grant API keys with: shortctl domains grant %s <key-id>\n", d.Host, d.Host)
+	return nil
+}
+
+func domainsList(ctx context.Context, a *app, args []string) error {
+	if _, err := parse(flag.NewFlagSet("domains list", flag.ContinueOnError), args, 0, ""); err != nil {
+		return err
+	}
+	ds, err := a.domains().List(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tCREATED\tAPI KEYS")
+	for _, d := range ds {
+		ids := make([]string, 0, len(d.KeyIDs))
+		for _, id := range d.KeyIDs {
+			ids = append(ids, strconv.FormatInt(id, 10))
+		}
+		keys := strings.Join(ids, ",")
+		if keys == "" {
+			keys = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", d.Host, d.CreatedAt.Format(time.RFC3339), keys)
+	}
+	return tw.Flush()
+}
+
+func domainsRemove(ctx context.Context, a *app, args []string) error {
+	pos, err := parse(flag.NewFlagSet("domains remove", flag.ContinueOnError), args, 1, "<host>")
+	if err != nil {
+		return err
+	}
+	host := domain.NormalizeHost(pos[0])
+	if err := a.domains().Delete(ctx, host); err != nil {
+		if errors.Is(err, domain.ErrDomainInUse) {
+			return fmt.Errorf("domain %q still has links; delete them first", host)
+		}
+		return domainErr(host, err)
+	}
+	fmt.Printf("removed %s\n", host)
+	return nil
+}
+
+func domainsGrant(ctx context.Context, a *app, args []string) error {
+	host, id, err := domainKeyArgs("domains grant", args)
+	if err != nil {
+		return err
+	}
+	if err := a.domains().Grant(ctx, host, id); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return fmt.Errorf("domain %q or key %d not found", host, id)
+		}
+		return err
+	}
+	fmt.Printf("key %d may now create links on %s\n", id, host)
+	return nil
+}
+
+func domainsRevoke(ctx context.Context, a *app, args []string) error {
+	host, id, err := domainKeyArgs("domains revoke", args)
+	if err != nil {
+		return err
+	}
+	if err := a.domains().Revoke(ctx, host, id); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return fmt.Errorf("key %d has no grant on %q", id, host)
+		}
+		return err
+	}
+	fmt.Printf("key %d may no longer create links on %s; existing links stay\n", id, host)
+	return nil
+}
+
+func domainKeyArgs(name string, args []string) (string, int64, error) {
+	pos, err := parse(flag.NewFlagSet(name, flag.ContinueOnError), args, 2, "<host> <key-id>")
+	if err != nil {
+		return "", 0, err
+	}
+	id, err := strconv.ParseInt(pos[1], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid key id %q", pos[1])
+	}
+	return domain.NormalizeHost(pos[0]), id, nil
+}
+
+func domainErr(host string, err error) error {
+	if errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("domain %q not registered", host)
+	}
+	return err
+}
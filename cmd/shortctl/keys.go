@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/apikey"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/postgres"
+)
+
+func keysCreate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("keys create", flag.ContinueOnError)
+	tier := fs.String("tier", "default", "rate limit tier")
+	pos, err := parse(fs, args, 1, "[-tier t] <name>")
+	if err != nil {
+		return err
+	}
+	key, prefix, hash := apikey.Generate()
+	k, err := postgres.NewAPIKeysRepo(a.pool).Create(ctx, pos[0], *tier, prefix, hash)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created key %d (%s, tier %s). Store it now, it cannot be shown again:\n\n  %s\n", k.ID, k.Name, k.Tier, key)
+	return nil
+}
+
+func keysList(ctx context.Context, a *app, args []string) error {
+	if _, err := parse(flag.NewFlagSet("keys list", flag.ContinueOnError), args, 0, ""); err != nil {
+		return err
+	}
+	keys, err := postgres.NewAPIKeysRepo(a.pool).List(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tTIER\tCREATED\tLAST USED\tREVOKED")
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%d\t%s\t%s…\t%s\t%s\t%s\t%s\n",
+			k.ID, k.Name, k.Prefix, k.Tier, k.CreatedAt.Format(time.RFC3339), fmtTime(k.LastUsedAt), fmtTime(k.RevokedAt))
+	}
+	return tw.Flush()
+}
+
+func keysRevoke(ctx context.Context, a *app, args []string) error {
+	pos, err := parse(flag.NewFlagSet("keys revoke", flag.ContinueOnError), args, 1, "<id>")
+	if err != nil {
+		return err
+	}
+	id, err := strconv.ParseInt(pos[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid key id %q", pos[0])
+	}
+	if err := postgres.NewAPIKeysRepo(a.pool).Revoke(ctx, id); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return fmt.Errorf("key %d not found or already revoked", id)
+		}
+		return err
+	}
+	fmt.Printf("revoked key %d\n", id)
+	return nil
+}
+
+func fmtTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
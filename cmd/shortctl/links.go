@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/id"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/postgres"
+)
+
+// exportPageSize is the number of links read per query when exporting
+const exportPageSize = 1000
+
+var exportHeader = []string{"original_url", "key", "expires_at", "is_custom", "is_disabled", "created_at", "domain"}
+
+func (a *app) links() *postgres.LinksRepo {
+	return postgres.NewLinksRepo(a.pool, a.cfg.KeyOptions())
+}
+
+// parseExpiry accepts RFC3339, "never", or "" for the API default lifetime
+func parseExpiry(s string) (*time.Time, error) {
+	switch s {
+	case "":
+		t := time.Now().UTC().AddDate(0, 0, domain.DefaultLifetimeDays)
+		return &t, nil
+	case "never":
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("expires must be RFC3339 or \"never\": %w", err)
+	}
+	return &t, nil
+}
+
+// rules are the reserved words and blocked hosts of the configuration
+func (a *app) rules() *links.Rules {
+	return links.NewRules(a.cfg.ReservedWords, a.cfg.BlockedHosts)
+}
+
+// service runs the link operations of the API against the database
+func (a *app) service() links.Service {
+	return links.Service{Repo: a.links(), Domains: a.domains(), Rules: a.rules(), Logger: slog.Default()}
+}
+
+// createLink creates a link through links.Service.Create, with the checks of POST
+// /v1/links. host is a registered custom domain, or "" for BASE_URL; operators may use
+// any domain. A nil expiresAt never expires.
+func (a *app) createLink(ctx context.Context, svc links.Service, host, rawURL, alias, alphabet string, expiresAt *time.Time, dedupe *time.Duration) (*domain.Link, error) {
+	req := links.CreateRequest{
+		URL:         rawURL,
+		Domain:      host,
+		Alias:       alias,
+		KeyAlphabet: alphabet,
+		ExpiresAt:   expiresAt,
+		NoExpiry:    expiresAt == nil,
+		Operator:    true,
+	}
+	if dedupe != nil {
+		secs := int(dedupe.Seconds())
+		req.DedupeWindowSeconds = &secs
+	}
+	l, _, err := svc.Create(ctx, req)
+	return l, err
+}
+
+func linksCreate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("links create", flag.ContinueOnError)
+	alias := fs.String("alias", "", "custom alias")
+	expires := fs.String("expires", "", `expiry (RFC3339, or "never"); default 90 days`)
+	dedupe := fs.Duration("dedupe", -1, "click de-duplication window (0 disables); default server setting")
+	alphabet := fs.String("alphabet", "", "alphabet of a generated key: "+strings.Join(id.Alphabets, ", ")+"; default KEY_ALPHABET")
+	host := domainFlag(fs)
+	pos, err := parse(fs, args, 1, "[flags] <url>")
+	if err != nil {
+		return err
+	}
+	expiresAt, err := parseExpiry(*expires)
+	if err != nil {
+		return err
+	}
+	var window *time.Duration
+	if *dedupe >= 0 {
+		window = dedupe
+	}
+	l, err := a.createLink(ctx, a.service(), domain.NormalizeHost(*host), pos[0], *alias, *alphabet, expiresAt, window)
+	if err != nil {
+		return err
+	}
+	printLink(a, l)
+	return nil
+}
+
+func linksGet(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("links get", flag.ContinueOnError)
+	host := domainFlag(fs)
+	pos, err := parse(fs, args, 1, "[-domain host] <key>")
+	if err != nil {
+		return err
+	}
+	l, err := a.links().GetByKey(ctx, domain.NormalizeHost(*host), pos[0])
+	if err != nil {
+		return keyErr(pos[0], err)
+	}
+	printLink(a, l)
+	return nil
+}
+
+func linksDisable(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("links disable", flag.ContinueOnError)
+	host := domainFlag(fs)
+	pos, err := parse(fs, args, 1, "[-domain host] <key>")
+	if err != nil {
+		return err
+	}
+	if err := a.links().Disable(ctx, domain.NormalizeHost(*host), pos[0]); err != nil {
+		return keyErr(pos[0], err)
+	}
+	fmt.Printf("disabled %s\n", pos[0])
+	return nil
+}
+
+func linksEnable(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("links enable", flag.ContinueOnError)
+	host := domainFlag(fs)
+	pos, err := parse(fs, args, 1, "[-domain host] <key>")
+	if err != nil {
+		return err
+	}
+	if err := a.links().Enable(ctx, domain.NormalizeHost(*host), pos[0]); err != nil {
+		return keyErr(pos[0], err)
+	}
+	fmt.Printf("enabled %s\n", pos[0])
+	return nil
+}
+
+func linksDelete(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("links delete", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	host := domainFlag(fs)
+	pos, err := parse(fs, args, 1, "[-yes] [-domain host] <key>")
+	if err != nil {
+		return err
+	}
+	key := pos[0]
+	if !*yes {
+		fmt.Printf("Delete %s with all its clicks and stats? Type the key to confirm: ", key)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != key {
+			return errAborted
+		}
+	}
+	if err := a.links().Delete(ctx, domain.NormalizeHost(*host), key); err != nil {
+		return keyErr(key, err)
+	}
+	fmt.Printf("deleted %s\n", key)
+	return nil
+}
+
+func linksExpired(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("links expired", flag.ContinueOnError)
+	limit := fs.Int("limit", 100, "maximum links to list")
+	if _, err := parse(fs, args, 0, "[-limit n]"); err != nil {
+		return err
+	}
+	expired, err := a.links().ListExpired(ctx, time.Now().UTC(), *limit)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tEXPIRED\tDISABLED\tORIGINAL URL")
+	for _, l := range expired {
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%s\n", l.Key, l.ExpiresAt.Format(time.RFC3339), l.IsDisabled, l.LongURL)
+	}
+	return tw.Flush()
+}
+
+// linksImport creates links from CSV. With a header row, columns are matched by name
+// (original_url, alias or key, expires_at, is_custom, is_disabled, domain), so an export
+// can be re-imported; without one they are original_url[,alias[,expires_at]]. Rows are
+// independent: a bad row is reported and skipped, and so are links that have expired.
+func linksImport(ctx context.Context, a *app, args []string) error {
+	pos, err := parse(flag.NewFlagSet("links import", flag.ContinueOnError), args, 1, "<file.csv|->")
+	if err != nil {
+		return err
+	}
+	var in io.Reader = os.Stdin
+	if pos[0] != "-" {
+		f, err := os.Open(pos[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = -1
+
+	svc := a.service()
+	cols := map[string]int{"original_url": 0, "alias": 1, "expires_at": 2}
+	var created, expired, failed int
+	for line := 1; ; line++ {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if line == 1 && len(rec) > 0 && strings.EqualFold(strings.TrimSpace(rec[0]), "original_url") {
+			cols = map[string]int{}
+			for i, name := range rec {
+				name = strings.ToLower(strings.TrimSpace(name))
+				if name == "key" {
+					name = "alias"
+				}
+				cols[name] = i
+			}
+			continue
+		}
+		field := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		alias := field("alias")
+		if custom, err := strconv.ParseBool(field("is_custom")); err == nil && !custom {
+			alias = "" // system keys are regenerated
+		}
+		expires := field("expires_at")
+		if expires == "" {
+			expires = "never"
+		}
+		expiresAt, err := parseExpiry(expires)
+		if err == nil && expiresAt != nil && expiresAt.Before(time.Now()) {
+			expired++
+			fmt.Fprintf(os.Stderr, "line %d: skipped, expired at %s\n", line, expiresAt.Format(time.RFC3339))
+			continue
+		}
+		host := domain.NormalizeHost(field("domain"))
+		var l *domain.Link
+		if err == nil {
+			l, err = a.createLink(ctx, svc, host, field("original_url"), alias, "", expiresAt, nil)
+		}
+		if disabled, _ := strconv.ParseBool(field("is_disabled")); err == nil && disabled {
+			err = a.links().Disable(ctx, host, l.Key)
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", line, err)
+			continue
+		}
+		created++
+		fmt.Printf("%s\t%s\n", l.Key, l.LongURL)
+	}
+	fmt.Fprintf(os.Stderr, "imported %d links, skipped %d expired, %d failed\n", created, expired, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d rows failed", failed)
+	}
+	return nil
+}
+
+func linksExport(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("links export", flag.ContinueOnError)
+	out := fs.String("o", "-", "output file (- for stdout)")
+	if _, err := parse(fs, args, 0, "[-o file.csv]"); err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(exportHeader)
+	var after int64
+	var n int
+	for {
+		page, err := a.links().ListAfter(ctx, after, exportPageSize)
+		if err != nil {
+			return err
+		}
+		for _, l := range page {
+			expires := ""
+			if l.ExpiresAt != nil {
+				expires = l.ExpiresAt.UTC().Format(time.RFC3339)
+			}
+			_ = cw.Write([]string{
+				l.LongURL, l.Key, expires,
+				strconv.FormatBool(l.IsCustom), strconv.FormatBool(l.IsDisabled),
+				l.CreatedAt.UTC().Format(time.RFC3339), l.Domain,
+			})
+			after = l.ID
+		}
+		n += len(page)
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+	if *out != "-" {
+		fmt.Fprintf(os.Stderr, "exported %d links to %s\n", n, *out)
+	}
+	return nil
+}
+
+func printLink(a *app, l *domain.Link) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Key:\t%s\n", l.Key)
+	fmt.Fprintf(tw, "Short URL:\t%s\n", links.ShortURL(a.cfg.BaseURL, l))
+	fmt.Fprintf(tw, "Original URL:\t%s\n", l.LongURL)
+	fmt.Fprintf(tw, "Custom:\t%t\n", l.IsCustom)
+	fmt.Fprintf(tw, "Disabled:\t%t\n", l.IsDisabled)
+	fmt.Fprintf(tw, "Created:\t%s\n", l.CreatedAt.Format(time.RFC3339))
+	if l.ExpiresAt != nil {
+		fmt.Fprintf(tw, "Expires:\t%s\n", l.ExpiresAt.Format(time.RFC3339))
+	} else {
+		fmt.Fprintf(tw, "Expires:\tnever\n")
+	}
+	if l.DedupeWindow != nil {
+		fmt.Fprintf(tw, "Dedupe window:\t%s\n", *l.DedupeWindow)
+	}
+	tw.Flush()
+}
+
+func keyErr(key string, err error) error {
+	if errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("link %q not found", key)
+	}
+	return err
+}
@@ -0,0 +1,168 @@
+// Command shortctl is the operator CLI: it manages links, custom domains, API keys and migrations
+// directly against the configured database (DATABASE_URL, .env is honoured), and checks the
+// configuration.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/joho/godotenv"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/postgres"
+)
+
+const usage = `Usage: shortctl <command> [flags] [args]
+
+Links:
+  links create [-alias a | -alphabet words] [-expires RFC3339|never] [-dedupe 30s] [-domain host] <url>
+  links get [-domain host] <key>
+  links disable [-domain host] <key>
+  links enable [-domain host] <key>
+  links delete [-yes] [-domain host] <key>
+  links expired [-limit 100]
+  links import <file.csv|->      columns: original_url[,alias[,expires_at]]
+  links export [-o file.csv]
+
+Stats:
+  stats [-days 30] [-granularity day] [-tz UTC] [-bots] [-domain host] <key>
+
+Custom domains:
+  domains add <host>
+  domains list
+  domains remove <host>
+  domains grant <host> <key-id>
+  domains revoke <host> <key-id>
+
+API keys:
+  keys create [-tier default] <name>
+  keys list
+  keys revoke <id>
+
+Configuration:
+  config check                   validate the environment and CONFIG_FILE
+
+Migrations (db/migrations, embedded):
+  migrate status
+  migrate up [-to version]
+  migrate down
+  migrate baseline <version>
+`
+
+// app holds what every command needs
+type app struct {
+	cfg  config.Config
+	pool *pgxpool.Pool
+}
+
+type command func(ctx context.Context, a *app, args []string) error
+
+var commands = map[string]map[string]command{
+	"links": {
+		"create":  linksCreate,
+		"get":     linksGet,
+		"disable": linksDisable,
+		"enable":  linksEnable,
+		"delete":  linksDelete,
+		"expired": linksExpired,
+		"import":  linksImport,
+		"export":  linksExport,
+	},
+	"domains": {
+		"add":    domainsAdd,
+		"list":   domainsList,
+		"remove": domainsRemove,
+		"grant":  domainsGrant,
+		"revoke": domainsRevoke,
+	},
+	"keys": {
+		"create": keysCreate,
+		"list":   keysList,
+		"revoke": keysRevoke,
+	},
+	"migrate": {
+		"status":   migrateStatus,
+		"up":       migrateUp,
+		"down":     migrateDown,
+		"baseline": migrateBaseline,
+	},
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "shortctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+		fmt.Print(usage)
+		return nil
+	}
+
+	if args[0] == "config" {
+		if len(args) < 2 || args[1] != "check" {
+			return fmt.Errorf("unknown command %q\n\n%s", strings.Join(args, " "), usage)
+		}
+		_ = godotenv.Load()
+		return configCheck(args[2:])
+	}
+
+	var cmd command
+	rest := args[1:]
+	if args[0] == "stats" {
+		cmd = statsShow
+	} else if group, ok := commands[args[0]]; ok {
+		if len(rest) == 0 {
+			return fmt.Errorf("%s: missing subcommand\n\n%s", args[0], usage)
+		}
+		if cmd, ok = group[rest[0]]; !ok {
+			return fmt.Errorf("unknown command %q\n\n%s", args[0]+" "+rest[0], usage)
+		}
+		rest = rest[1:]
+	} else {
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+	}
+
+	_ = godotenv.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	connectCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	pool, err := postgres.Open(connectCtx, cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("db connect: %w", err)
+	}
+	defer pool.Close()
+
+	return cmd(ctx, &app{cfg: cfg, pool: pool}, rest)
+}
+
+// parse parses flags and checks the number of positional arguments
+func parse(fs *flag.FlagSet, args []string, positional int, names string) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != positional {
+		return nil, fmt.Errorf("usage: %s %s", fs.Name(), names)
+	}
+	return fs.Args(), nil
+}
+
+var errAborted = errors.New("aborted")
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"strconv"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/db"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/migrate"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/postgres"
+)
+
+func (a *app) migrator() (*migrate.Migrator, error) {
+	sub, err := fs.Sub(db.Migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := migrate.Load(sub)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(a.pool, migrations), nil
+}
+
+func migrateStatus(ctx context.Context, a *app, args []string) error {
+	if _, err := parse(flag.NewFlagSet("migrate status", flag.ContinueOnError), args, 0, ""); err != nil {
+		return err
+	}
+	m, err := a.migrator()
+	if err != nil {
+		return err
+	}
+	cur, err := m.Current(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("current version: %d (this build expects %d)\n", cur, postgres.SchemaVersion)
+	if cur == 0 {
+		fmt.Println("no version recorded; see `shortctl migrate baseline`")
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	for _, mig := range pending {
+		fmt.Printf("pending: %02d_%s\n", mig.Version, mig.Name)
+	}
+	return nil
+}
+
+func migrateUp(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+	to := fs.Int("to", 0, "stop at this version (default: latest)")
+	if _, err := parse(fs, args, 0, "[-to version]"); err != nil {
+		return err
+	}
+	m, err := a.migrator()
+	if err != nil {
+		return err
+	}
+	n := 0
+	err = m.Up(ctx, *to, func(mig migrate.Migration) {
+		n++
+		fmt.Printf("applied %02d_%s\n", mig.Version, mig.Name)
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Println("already up to date")
+	}
+	return nil
+}
+
+func migrateDown(ctx context.Context, a *app, args []string) error {
+	if _, err := parse(flag.NewFlagSet("migrate down", flag.ContinueOnError), args, 0, ""); err != nil {
+		return err
+	}
+	m, err := a.migrator()
+	if err != nil {
+		return err
+	}
+	mig, err := m.Down(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("reverted %02d_%s\n", mig.Version, mig.Name)
+	return nil
+}
+
+func migrateBaseline(ctx context.Context, a *app, args []string) error {
+	pos, err := parse(flag.NewFlagSet("migrate baseline", flag.ContinueOnError), args, 1, "<version>")
+	if err != nil {
+		return err
+	}
+	v, err := strconv.Atoi(pos[0])
+	if err != nil || v < 1 {
+		return fmt.Errorf("invalid version %q", pos[0])
+	}
+	m, err := a.migrator()
+	if err != nil {
+		return err
+	}
+	if err := m.Baseline(ctx, v); err != nil {
+		return err
+	}
+	fmt.Printf("recorded version %d\n", v)
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/postgres"
+)
+
+// statsShow prints a link's totals and a zero-filled click series, like GET /v1/links/{key}/stats
+func statsShow(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	days := fs.Int("days", 30, "number of days to show, ending today")
+	granularity := fs.String("granularity", "day", "bucket size: hour, day, week or month")
+	tz := fs.String("tz", "UTC", "IANA time zone for buckets")
+	bots := fs.Bool("bots", false, "include bot, crawler and previewer clicks")
+	host := domainFlag(fs)
+	pos, err := parse(fs, args, 1, "[flags] <key>")
+	if err != nil {
+		return err
+	}
+	gran, ok := domain.ParseGranularity(*granularity)
+	if !ok {
+		return errors.New("granularity must be one of hour, day, week, month")
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil || *tz == "Local" {
+		return errors.New("tz must be an IANA time zone name")
+	}
+	if *days < 1 {
+		return errors.New("days must be at least 1")
+	}
+
+	to := domain.GranularityDay.Truncate(time.Now(), loc).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -*days)
+	buckets, ok := gran.Buckets(from, to, loc)
+	if !ok {
+		return fmt.Errorf("range spans more than %d %s buckets", domain.MaxSeriesBuckets, gran)
+	}
+
+	link, err := a.links().GetByKey(ctx, domain.NormalizeHost(*host), pos[0])
+	if err != nil {
+		return keyErr(pos[0], err)
+	}
+	stats := postgres.NewStatsRepo(a.pool)
+	totals, err := stats.Totals(ctx, link.ID, *bots)
+	if err != nil {
+		return err
+	}
+	counts, err := stats.Series(ctx, link.ID, from, to, gran, loc, *bots)
+	if errors.Is(err, domain.ErrClicksPurged) {
+		return fmt.Errorf("the last %d days in %s need raw clicks older than the retention period; use a time zone with a whole-hour offset or fewer days", *days, loc)
+	}
+	if err != nil {
+		return err
+	}
+	byStart := make(map[int64]storage.BucketCount, len(counts))
+	for _, c := range counts {
+		byStart[c.Start.Unix()] = c
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Key:\t%s\n", link.Key)
+	fmt.Fprintf(tw, "Total clicks:\t%d\n", totals.Clicks)
+	fmt.Fprintf(tw, "Deduplicated:\t%d\n", totals.DedupedClicks)
+	if totals.LastClickedAt != nil {
+		fmt.Fprintf(tw, "Last click:\t%s\n", totals.LastClickedAt.In(loc).Format(time.RFC3339))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintf(tw, "%s (%s)\tCLICKS\tVISITORS\n", gran, loc)
+	layout := "2006-01-02"
+	if gran == domain.GranularityHour {
+		layout = "2006-01-02 15:04"
+	}
+	for _, b := range buckets {
+		c := byStart[b.Unix()]
+		visitors := "-" // distinct visitors are only known per UTC hour and day
+		if gran.CountsUniqueVisitors(b, from, to) {
+			visitors = strconv.FormatInt(c.UniqueVisitors, 10)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", b.Format(layout), c.Clicks, visitors)
+	}
+	return tw.Flush()
+}
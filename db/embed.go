@@ -0,0 +1,7 @@
+// Package db embeds the SQL migrations so binaries can apply them without a checkout.
+package db
+
+import "embed"
+
+//go:embed migrations/*.sql
+var Migrations embed.FS
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_clicks_created_at;
+DROP TABLE IF EXISTS rollup_watermarks;
+DROP TABLE IF EXISTS click_rollup_dimensions;
+DROP TABLE IF EXISTS click_rollups;
@@ -0,0 +1,37 @@
+-- Pre-aggregated click analytics, maintained by the rollup background job.
+-- Buckets are UTC-aligned; only closed buckets (before the watermark) are stored.
+
+-- Per link per hour/day totals
+CREATE TABLE IF NOT EXISTS click_rollups (
+  link_id          BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  granularity      TEXT NOT NULL,                  -- 'hour' or 'day'
+  bucket_start     TIMESTAMPTZ NOT NULL,
+  clicks           BIGINT NOT NULL DEFAULT 0,
+  unique_visitors  BIGINT NOT NULL DEFAULT 0,
+  last_clicked_at  TIMESTAMPTZ NULL,
+  PRIMARY KEY (link_id, granularity, bucket_start),
+  CONSTRAINT rollup_granularity CHECK (granularity IN ('hour', 'day'))
+);
+
+-- Per link per hour/day breakdowns ('country' -> ISO code or 'ZZ', 'referrer' -> host or '' for direct)
+CREATE TABLE IF NOT EXISTS click_rollup_dimensions (
+  link_id       BIGINT NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+  granularity   TEXT NOT NULL,
+  bucket_start  TIMESTAMPTZ NOT NULL,
+  dimension     TEXT NOT NULL,
+  value         TEXT NOT NULL,
+  clicks        BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (link_id, granularity, bucket_start, dimension, value),
+  CONSTRAINT rollup_dimension_granularity CHECK (granularity IN ('hour', 'day')),
+  CONSTRAINT rollup_dimension_name CHECK (dimension IN ('country', 'referrer'))
+);
+
+-- Raw clicks before rolled_up_to have been aggregated for that granularity
+CREATE TABLE IF NOT EXISTS rollup_watermarks (
+  granularity   TEXT PRIMARY KEY,
+  rolled_up_to  TIMESTAMPTZ NOT NULL
+);
+
+-- Raw scans for the open bucket and the rollup job filter clicks by time
+CREATE INDEX IF NOT EXISTS idx_clicks_created_at
+  ON clicks (created_at);
@@ -0,0 +1,11 @@
+DELETE FROM click_rollup_dimensions WHERE is_bot;
+ALTER TABLE click_rollup_dimensions DROP CONSTRAINT IF EXISTS click_rollup_dimensions_pkey;
+ALTER TABLE click_rollup_dimensions ADD PRIMARY KEY (link_id, granularity, bucket_start, dimension, value);
+ALTER TABLE click_rollup_dimensions DROP COLUMN IF EXISTS is_bot;
+
+DELETE FROM click_rollups WHERE is_bot;
+ALTER TABLE click_rollups DROP CONSTRAINT IF EXISTS click_rollups_pkey;
+ALTER TABLE click_rollups ADD PRIMARY KEY (link_id, granularity, bucket_start);
+ALTER TABLE click_rollups DROP COLUMN IF EXISTS is_bot;
+
+ALTER TABLE clicks DROP COLUMN IF EXISTS is_bot;
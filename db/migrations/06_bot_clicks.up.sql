@@ -0,0 +1,11 @@
+-- Clicks classified as bots, crawlers or link previewers are stored but excluded from stats by default
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Rollups keep human and bot clicks in separate rows
+ALTER TABLE click_rollups ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE click_rollups DROP CONSTRAINT IF EXISTS click_rollups_pkey;
+ALTER TABLE click_rollups ADD PRIMARY KEY (link_id, granularity, bucket_start, is_bot);
+
+ALTER TABLE click_rollup_dimensions ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE click_rollup_dimensions DROP CONSTRAINT IF EXISTS click_rollup_dimensions_pkey;
+ALTER TABLE click_rollup_dimensions ADD PRIMARY KEY (link_id, granularity, bucket_start, dimension, value, is_bot);
@@ -0,0 +1,4 @@
+ALTER TABLE click_rollups DROP COLUMN IF EXISTS duplicate_clicks;
+ALTER TABLE clicks DROP COLUMN IF EXISTS is_duplicate;
+ALTER TABLE links DROP CONSTRAINT IF EXISTS dedupe_window_non_negative;
+ALTER TABLE links DROP COLUMN IF EXISTS dedupe_window_seconds;
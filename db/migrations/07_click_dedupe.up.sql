@@ -0,0 +1,9 @@
+-- Per-link click de-duplication window in seconds (NULL = server default, 0 = disabled)
+ALTER TABLE links ADD COLUMN IF NOT EXISTS dedupe_window_seconds INTEGER NULL;
+ALTER TABLE links ADD CONSTRAINT dedupe_window_non_negative
+  CHECK (dedupe_window_seconds IS NULL OR dedupe_window_seconds >= 0);
+
+-- Repeated clicks of a visitor inside the window are stored but flagged
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS is_duplicate BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE click_rollups ADD COLUMN IF NOT EXISTS duplicate_clicks BIGINT NOT NULL DEFAULT 0;
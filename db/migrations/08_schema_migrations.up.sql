@@ -0,0 +1,8 @@
+-- Records the applied schema version (the number prefix of the last migration file).
+-- Readiness fails until the database is at the version the binary expects.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations (version) VALUES (8) ON CONFLICT (version) DO NOTHING;
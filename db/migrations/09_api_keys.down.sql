@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS api_keys;
+DELETE FROM schema_migrations WHERE version = 9;
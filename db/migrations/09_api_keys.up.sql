@@ -0,0 +1,14 @@
+-- API keys for programmatic access. Only a SHA-256 hash of the key is stored;
+-- prefix is its first characters, kept to recognise a key in listings and logs.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           BIGSERIAL PRIMARY KEY,
+    name         TEXT NOT NULL,
+    prefix       TEXT NOT NULL,
+    key_hash     TEXT NOT NULL UNIQUE,
+    tier         TEXT NOT NULL DEFAULT 'default',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ NULL,
+    revoked_at   TIMESTAMPTZ NULL
+);
+
+INSERT INTO schema_migrations (version) VALUES (9) ON CONFLICT (version) DO NOTHING;
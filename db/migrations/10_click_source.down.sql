@@ -0,0 +1,3 @@
+ALTER TABLE click_rollups DROP COLUMN IF EXISTS qr_clicks;
+ALTER TABLE clicks DROP COLUMN IF EXISTS source;
+DELETE FROM schema_migrations WHERE version = 10;
@@ -0,0 +1,7 @@
+-- Where a click came from when the short URL carries a marker (e.g. 'qr' for ?src=qr
+-- in QR codes served by the API); NULL for plain visits
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS source TEXT NULL;
+
+ALTER TABLE click_rollups ADD COLUMN IF NOT EXISTS qr_clicks BIGINT NOT NULL DEFAULT 0;
+
+INSERT INTO schema_migrations (version) VALUES (10) ON CONFLICT (version) DO NOTHING;
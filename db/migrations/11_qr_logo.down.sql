@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS qr_logo;
+DELETE FROM schema_migrations WHERE version = 11;
@@ -0,0 +1,9 @@
+-- The workspace logo overlaid on QR codes; the table holds at most one row.
+CREATE TABLE IF NOT EXISTS qr_logo (
+    id           BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    content_type TEXT NOT NULL,
+    image        BYTEA NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations (version) VALUES (11) ON CONFLICT (version) DO NOTHING;
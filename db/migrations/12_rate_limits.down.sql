@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rate_limit_counters;
+DELETE FROM schema_migrations WHERE version = 12;
@@ -0,0 +1,14 @@
+-- Sliding window rate limit counters shared by all instances (RATE_LIMIT_STORE=postgres).
+-- One row per key and fixed window; rows past expires_at are deleted by the purge job.
+-- UNLOGGED: counters are short-lived, so losing them in a crash only forgives recent requests.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_counters (
+  key           TEXT NOT NULL,
+  window_start  TIMESTAMPTZ NOT NULL,
+  count         INTEGER NOT NULL,
+  expires_at    TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (key, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_counters_expires_at ON rate_limit_counters(expires_at);
+
+INSERT INTO schema_migrations (version) VALUES (12) ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,24 @@
+-- Keys become globally unique again, so links on custom domains (whose keys may clash
+-- with BASE_URL keys) must be deleted before migrating down
+DO $$
+DECLARE
+    key_col TEXT := 'key';
+    url_col TEXT := 'long_url';
+BEGIN
+    IF EXISTS (SELECT 1 FROM links WHERE domain IS NOT NULL) THEN
+        RAISE EXCEPTION 'links on custom domains exist; delete them before migrating down';
+    END IF;
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'links' AND column_name = 'short_code') THEN
+        key_col := 'short_code';
+        url_col := 'original_url';
+    END IF;
+    DROP INDEX IF EXISTS uq_links_domain_short_code;
+    DROP INDEX IF EXISTS uq_links_domain_canonical_system;
+    EXECUTE format('CREATE UNIQUE INDEX IF NOT EXISTS idx_links_short_code ON links (%I)', key_col);
+    EXECUTE format('CREATE UNIQUE INDEX IF NOT EXISTS uq_links_canonical_system ON links (%I) WHERE is_custom = FALSE', url_col);
+END $$;
+
+ALTER TABLE links DROP COLUMN IF EXISTS domain;
+DROP TABLE IF EXISTS domain_keys;
+DROP TABLE IF EXISTS domains;
+DELETE FROM schema_migrations WHERE version = 13;
@@ -0,0 +1,37 @@
+-- Custom domains. Each domain has its own key namespace: go.team-a.com/x and
+-- links.team-b.com/x are different links. Links with a NULL domain live on BASE_URL.
+CREATE TABLE IF NOT EXISTS domains (
+    id         BIGSERIAL PRIMARY KEY,
+    host       TEXT NOT NULL UNIQUE, -- lower case, without port
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- API keys allowed to create links on a domain
+CREATE TABLE IF NOT EXISTS domain_keys (
+    domain_id  BIGINT NOT NULL REFERENCES domains(id) ON DELETE CASCADE,
+    api_key_id BIGINT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    PRIMARY KEY (domain_id, api_key_id)
+);
+
+ALTER TABLE links ADD COLUMN IF NOT EXISTS domain TEXT NULL REFERENCES domains(host) ON DELETE RESTRICT;
+
+-- Keys, and the one system link per canonical URL, are unique per domain instead of
+-- globally. Databases created by 01_init_links name the columns key/long_url, later
+-- ones short_code/original_url; both are handled.
+DO $$
+DECLARE
+    key_col TEXT := 'key';
+    url_col TEXT := 'long_url';
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'links' AND column_name = 'short_code') THEN
+        key_col := 'short_code';
+        url_col := 'original_url';
+    END IF;
+    EXECUTE format('ALTER TABLE links DROP CONSTRAINT IF EXISTS %I', 'links_' || key_col || '_key');
+    DROP INDEX IF EXISTS idx_links_short_code;
+    DROP INDEX IF EXISTS uq_links_canonical_system;
+    EXECUTE format('CREATE UNIQUE INDEX IF NOT EXISTS uq_links_domain_short_code ON links (COALESCE(domain, %L), %I)', '', key_col);
+    EXECUTE format('CREATE UNIQUE INDEX IF NOT EXISTS uq_links_domain_canonical_system ON links (COALESCE(domain, %L), %I) WHERE is_custom = FALSE', '', url_col);
+END $$;
+
+INSERT INTO schema_migrations (version) VALUES (13) ON CONFLICT (version) DO NOTHING;
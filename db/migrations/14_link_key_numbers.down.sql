@@ -0,0 +1,2 @@
+DROP SEQUENCE IF EXISTS link_key_numbers;
+DELETE FROM schema_migrations WHERE version = 14;
@@ -0,0 +1,5 @@
+-- Numbers behind counter keys (KEY_STRATEGY=counter). Each is turned into a key once;
+-- numbers drawn for keys that turn out to be taken are skipped.
+CREATE SEQUENCE IF NOT EXISTS link_key_numbers;
+
+INSERT INTO schema_migrations (version) VALUES (14) ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_clicks_inserted_at;
+ALTER TABLE clicks DROP COLUMN IF EXISTS inserted_at;
+
+DELETE FROM schema_migrations WHERE version = 15;
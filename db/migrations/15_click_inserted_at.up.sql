@@ -0,0 +1,12 @@
+-- When a click row was written. The async click pipeline stores clicks after they happen
+-- (created_at is the request time), so the rollup job tracks its progress by insertion
+-- time and late clicks are still added to the hour and day they belong to.
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS inserted_at TIMESTAMPTZ NULL;
+UPDATE clicks SET inserted_at = created_at WHERE inserted_at IS NULL;
+ALTER TABLE clicks ALTER COLUMN inserted_at SET DEFAULT NOW();
+ALTER TABLE clicks ALTER COLUMN inserted_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_clicks_inserted_at
+  ON clicks (inserted_at);
+
+INSERT INTO schema_migrations (version) VALUES (15) ON CONFLICT (version) DO NOTHING;
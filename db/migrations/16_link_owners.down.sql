@@ -0,0 +1,16 @@
+-- System links are shared by everyone again, so a URL shortened by several owners must
+-- be left with one system link per domain before migrating down
+DO $$
+DECLARE
+    url_col TEXT := 'long_url';
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'links' AND column_name = 'original_url') THEN
+        url_col := 'original_url';
+    END IF;
+    DROP INDEX IF EXISTS uq_links_domain_owner_canonical_system;
+    EXECUTE format('CREATE UNIQUE INDEX IF NOT EXISTS uq_links_domain_canonical_system ON links (COALESCE(domain, %L), %I) WHERE is_custom = FALSE', '', url_col);
+END $$;
+
+DROP INDEX IF EXISTS idx_links_api_key_id;
+ALTER TABLE links DROP COLUMN IF EXISTS api_key_id;
+DELETE FROM schema_migrations WHERE version = 16;
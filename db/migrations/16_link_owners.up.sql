@@ -0,0 +1,20 @@
+-- The API key that created a link (NULL for anonymous, CLI and earlier links). Only the
+-- owner may change a link, and system links are shared per owner instead of by everyone
+-- who shortens the same URL. Keys are revoked, never deleted.
+ALTER TABLE links ADD COLUMN IF NOT EXISTS api_key_id BIGINT NULL REFERENCES api_keys(id);
+
+CREATE INDEX IF NOT EXISTS idx_links_api_key_id
+  ON links (api_key_id);
+
+DO $$
+DECLARE
+    url_col TEXT := 'long_url';
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'links' AND column_name = 'original_url') THEN
+        url_col := 'original_url';
+    END IF;
+    DROP INDEX IF EXISTS uq_links_domain_canonical_system;
+    EXECUTE format('CREATE UNIQUE INDEX IF NOT EXISTS uq_links_domain_owner_canonical_system ON links (COALESCE(domain, %L), COALESCE(api_key_id, 0), %I) WHERE is_custom = FALSE', '', url_col);
+END $$;
+
+INSERT INTO schema_migrations (version) VALUES (16) ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,16 @@
+-- Only the most recently updated logo survives as the workspace logo
+CREATE TABLE IF NOT EXISTS qr_logo (
+    id           BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    content_type TEXT NOT NULL,
+    image        BYTEA NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO qr_logo (content_type, image, updated_at)
+SELECT content_type, image, updated_at FROM qr_logos
+ORDER BY updated_at DESC
+LIMIT 1
+ON CONFLICT (id) DO NOTHING;
+
+DROP TABLE IF EXISTS qr_logos;
+DELETE FROM schema_migrations WHERE version = 17;
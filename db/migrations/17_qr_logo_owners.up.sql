@@ -0,0 +1,18 @@
+-- Each API key keeps its own logo for the QR codes of the links it created. The single
+-- workspace logo uploaded before becomes the logo of every key that has not been revoked.
+CREATE TABLE IF NOT EXISTS qr_logos (
+    api_key_id   BIGINT PRIMARY KEY REFERENCES api_keys(id),
+    content_type TEXT NOT NULL,
+    image        BYTEA NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO qr_logos (api_key_id, content_type, image, updated_at)
+SELECT k.id, l.content_type, l.image, l.updated_at
+FROM api_keys k CROSS JOIN qr_logo l
+WHERE k.revoked_at IS NULL
+ON CONFLICT (api_key_id) DO NOTHING;
+
+DROP TABLE IF EXISTS qr_logo;
+
+INSERT INTO schema_migrations (version) VALUES (17) ON CONFLICT (version) DO NOTHING;
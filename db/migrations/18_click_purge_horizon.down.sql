@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS click_purge_horizon;
+
+DELETE FROM schema_migrations WHERE version = 18;
@@ -0,0 +1,9 @@
+-- Raw clicks created before purged_before may have been deleted by the retention purge.
+-- Stats that would read such clicks instead of the hourly rollups (partial edge hours and
+-- zones without whole-hour offsets) are refused rather than undercounted.
+CREATE TABLE IF NOT EXISTS click_purge_horizon (
+    id            BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    purged_before TIMESTAMPTZ NOT NULL
+);
+
+INSERT INTO schema_migrations (version) VALUES (18) ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,37 @@
+// Package apikey generates API keys and the hashes they are stored and looked up by.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// Prefix marks a string as one of our API keys
+	Prefix = "sk_"
+	// displayLen is how much of a key is kept in clear for listings
+	displayLen = len(Prefix) + 8
+)
+
+// Generate returns a new random key, its display prefix and its hash
+func Generate() (key, prefix, hash string) {
+	var b [32]byte
+	_, _ = rand.Read(b[:])
+	key = Prefix + base64.RawURLEncoding.EncodeToString(b[:])
+	return key, key[:displayLen], Hash(key)
+}
+
+// Hash returns the hex SHA-256 of key. Keys carry 256 random bits, so a fast hash
+// is sufficient and lets requests be authenticated with a single indexed lookup.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// LooksValid reports whether s has the shape of a key, to reject garbage before a lookup
+func LooksValid(s string) bool {
+	return strings.HasPrefix(s, Prefix) && len(s) == len(Prefix)+43
+}
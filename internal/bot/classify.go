@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Reasons reported by Classify
+const (
+	ReasonUserAgent = "user_agent"
+	ReasonEmptyUA   = "empty_user_agent"
+	ReasonHead      = "head_request"
+	ReasonPrefetch  = "prefetch"
+)
+
+// Classify reports whether a redirect request comes from a bot, crawler or link previewer,
+// together with the first heuristic that matched.
+func Classify(r *http.Request) (bool, string) {
+	// Previewers often only probe the target with HEAD
+	if r.Method == http.MethodHead {
+		return true, ReasonHead
+	}
+	if isPrefetch(r.Header) {
+		return true, ReasonPrefetch
+	}
+	ua := strings.TrimSpace(r.UserAgent())
+	if ua == "" {
+		return true, ReasonEmptyUA
+	}
+	if IsBotUserAgent(ua) {
+		return true, ReasonUserAgent
+	}
+	return false, ""
+}
+
+// IsBotUserAgent matches ua against the maintained pattern list
+func IsBotUserAgent(ua string) bool {
+	ua = strings.ToLower(ua)
+	for _, p := range uaPatterns {
+		if strings.Contains(ua, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrefetch detects speculative loads by browsers and proxies
+func isPrefetch(h http.Header) bool {
+	for _, name := range []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"} {
+		v := strings.ToLower(h.Get(name))
+		if strings.Contains(v, "prefetch") || strings.Contains(v, "preview") {
+			return true
+		}
+	}
+	return false
+}
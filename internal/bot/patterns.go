@@ -0,0 +1,70 @@
+package bot
+
+// uaPatterns are lower-case User-Agent substrings of link previewers, crawlers,
+// monitoring tools and HTTP libraries. Keep entries grouped and sorted when adding new ones.
+var uaPatterns = []string{
+	// Generic markers
+	"bot",
+	"crawler",
+	"spider",
+	"scraper",
+	"preview",
+	"headless",
+
+	// Link previewers / unfurlers
+	"discordbot",
+	"embedly",
+	"facebookexternalhit",
+	"facebookcatalog",
+	"iframely",
+	"linkedinbot",
+	"mastodon",
+	"pinterest",
+	"redditbot",
+	"skypeuripreview",
+	"slack-imgproxy",
+	"slackbot",
+	"snapchat",
+	"telegrambot",
+	"twitterbot",
+	"vkshare",
+	"whatsapp",
+	"xing-contenttabreceiver",
+
+	// Search engines and SEO crawlers
+	"ahrefs",
+	"applebot",
+	"baiduspider",
+	"bingpreview",
+	"duckduckbot",
+	"google-inspectiontool",
+	"googlebot",
+	"mj12bot",
+	"petalbot",
+	"semrush",
+	"yandex",
+
+	// Security scanners and mail link checkers
+	"barracuda",
+	"mimecast",
+	"proofpoint",
+	"safelinks",
+
+	// Uptime monitors
+	"pingdom",
+	"statuscake",
+	"uptimerobot",
+
+	// HTTP clients and automation
+	"curl/",
+	"go-http-client",
+	"httpclient",
+	"java/",
+	"libwww-perl",
+	"node-fetch",
+	"okhttp",
+	"phantomjs",
+	"python-requests",
+	"python-urllib",
+	"wget/",
+}
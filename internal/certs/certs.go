@@ -0,0 +1,199 @@
+// Package certs serves TLS certificates from files. A default pair is served to
+// clients that send no or an unknown server name; a directory of {name}.crt/{name}.key
+// pairs adds certificates picked by SNI, e.g. for custom domains. The files are polled,
+// so renewed certificates are served without a restart.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store picks the certificate of a TLS handshake. It is safe for concurrent use.
+type Store struct {
+	certFile, keyFile string
+	dir               string
+	logger            *slog.Logger
+
+	mu     sync.RWMutex
+	def    *tls.Certificate
+	byName map[string]*tls.Certificate // DNS names of the directory's certificates, wildcards included
+	stamp  string                      // files as of the last successful load, see files
+}
+
+// New loads the default pair (certFile and keyFile, both optional) and every pair in
+// dir (optional). At least one certificate must load.
+func New(certFile, keyFile, dir string, logger *slog.Logger) (*Store, error) {
+	s := &Store{certFile: certFile, keyFile: keyFile, dir: dir, logger: logger}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// TLSConfig returns a server configuration serving the store's certificates
+func (s *Store) TLSConfig() *tls.Config {
+	return &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: s.GetCertificate}
+}
+
+// GetCertificate returns the directory certificate for the server name (an exact name
+// before a wildcard), or the default one
+func (s *Store) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if c := s.lookup(name); c != nil {
+		return c, nil
+	}
+	if s.def != nil {
+		return s.def, nil
+	}
+	return nil, fmt.Errorf("no certificate for %q", name)
+}
+
+// Covers reports whether a certificate of the store is valid for host
+func (s *Store) Covers(host string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lookup(host) != nil || (s.def != nil && s.def.Leaf.VerifyHostname(host) == nil)
+}
+
+func (s *Store) lookup(name string) *tls.Certificate {
+	if name == "" {
+		return nil
+	}
+	if c, ok := s.byName[name]; ok {
+		return c
+	}
+	if _, parent, ok := strings.Cut(name, "."); ok {
+		return s.byName["*."+parent]
+	}
+	return nil
+}
+
+// Reload reads all files again. On error the certificates loaded before stay in use.
+func (s *Store) Reload() error {
+	stamp, err := s.files()
+	if err != nil {
+		return err
+	}
+	var def *tls.Certificate
+	if s.certFile != "" {
+		c, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		if err != nil {
+			return fmt.Errorf("load %s: %w", s.certFile, err)
+		}
+		def = &c
+	}
+	byName := map[string]*tls.Certificate{}
+	for _, certFile := range s.pairs() {
+		keyFile := strings.TrimSuffix(certFile, ".crt") + ".key"
+		c, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("load %s: %w", certFile, err)
+		}
+		for _, name := range c.Leaf.DNSNames {
+			byName[strings.ToLower(name)] = &c
+		}
+	}
+	if def == nil && len(byName) == 0 {
+		return errors.New("no TLS certificate: set TLS_CERT_FILE/TLS_KEY_FILE or put {name}.crt/{name}.key pairs in TLS_CERT_DIR")
+	}
+
+	s.mu.Lock()
+	s.def, s.byName, s.stamp = def, byName, stamp
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the certificates whenever their files change, checking every interval
+// until ctx is done. Failed reloads are logged and retried on the next check, so a
+// pair caught half-written is picked up once both files are complete.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		stamp, err := s.files()
+		s.mu.RLock()
+		changed := stamp != s.stamp
+		s.mu.RUnlock()
+		if err == nil && !changed {
+			continue
+		}
+		if err == nil {
+			err = s.Reload()
+		}
+		if err != nil {
+			s.logger.ErrorContext(ctx, "tls certificate reload failed", "error", err)
+			continue
+		}
+		s.logger.InfoContext(ctx, "tls certificates reloaded", "names", s.names())
+	}
+}
+
+// pairs lists the certificate files of the directory that have a key next to them
+func (s *Store) pairs() []string {
+	if s.dir == "" {
+		return nil
+	}
+	certs, _ := filepath.Glob(filepath.Join(s.dir, "*.crt"))
+	out := certs[:0]
+	for _, c := range certs {
+		if _, err := os.Stat(strings.TrimSuffix(c, ".crt") + ".key"); err == nil {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// files describes every certificate and key file by name, size and modification
+// time; any renewal changes it
+func (s *Store) files() (string, error) {
+	var paths []string
+	if s.certFile != "" {
+		paths = append(paths, s.certFile, s.keyFile)
+	}
+	if s.dir != "" {
+		if _, err := os.Stat(s.dir); err != nil {
+			return "", err
+		}
+		for _, c := range s.pairs() {
+			paths = append(paths, c, strings.TrimSuffix(c, ".crt")+".key")
+		}
+	}
+	var b strings.Builder
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", p, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+func (s *Store) names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.byName))
+	for n := range s.byName {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return names
+}
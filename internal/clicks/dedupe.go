@@ -0,0 +1,53 @@
+package clicks
+
+import (
+	"sync"
+	"time"
+)
+
+// dedupeKey identifies one visitor of one link
+type dedupeKey struct {
+	linkID  int64
+	visitor string
+}
+
+// deduper remembers recent visitors in memory so repeated clicks inside a link's
+// window are flagged without a database round-trip. State is per process.
+type deduper struct {
+	mu        sync.Mutex
+	seen      map[dedupeKey]time.Time // key -> end of its window
+	lastSweep time.Time
+}
+
+// sweepEvery bounds how often expired entries are evicted
+const sweepEvery = time.Minute
+
+func newDeduper() *deduper {
+	return &deduper{seen: make(map[dedupeKey]time.Time), lastSweep: time.Now()}
+}
+
+// duplicate reports whether visitor already clicked linkID within window of now.
+// A first click opens the window; duplicates do not extend it.
+func (d *deduper) duplicate(linkID int64, visitor string, window time.Duration, now time.Time) bool {
+	if window <= 0 || visitor == "" {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now.Sub(d.lastSweep) >= sweepEvery {
+		for k, until := range d.seen {
+			if !now.Before(until) {
+				delete(d.seen, k)
+			}
+		}
+		d.lastSweep = now
+	}
+
+	k := dedupeKey{linkID: linkID, visitor: visitor}
+	if until, ok := d.seen[k]; ok && now.Before(until) {
+		return true
+	}
+	d.seen[k] = now.Add(window)
+	return false
+}
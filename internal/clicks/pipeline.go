@@ -0,0 +1,127 @@
+package clicks
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+)
+
+// insertTimeout bounds a single click insert
+const insertTimeout = 5 * time.Second
+
+// Options configures a Pipeline
+type Options struct {
+	QueueSize    int           // clicks buffered before new ones are dropped
+	Workers      int           // concurrent inserters
+	DedupeWindow time.Duration // default window for links without their own; 0 disables
+}
+
+// Pipeline records clicks asynchronously off the redirect path through a bounded queue
+// drained by a fixed pool of workers, flagging repeated clicks of the same visitor.
+type Pipeline struct {
+	repo   storage.ClicksRepo
+	logger *slog.Logger
+	opts   Options
+	dedupe *deduper
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *domain.Click
+	wg     sync.WaitGroup
+
+	dropped atomic.Int64
+}
+
+// NewPipeline creates a pipeline; call Start before recording clicks
+func NewPipeline(repo storage.ClicksRepo, logger *slog.Logger, opts Options) *Pipeline {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	return &Pipeline{
+		repo:   repo,
+		logger: logger,
+		opts:   opts,
+		dedupe: newDeduper(),
+		queue:  make(chan *domain.Click, opts.QueueSize),
+	}
+}
+
+// Start launches the workers
+func (p *Pipeline) Start() {
+	for i := 0; i < p.opts.Workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+}
+
+// Record flags c as a duplicate if its visitor already clicked the link inside the
+// link's dedupe window, then enqueues it. It never blocks and reports false if the
+// click was dropped because the queue is full or the pipeline is closed.
+func (p *Pipeline) Record(link *domain.Link, c *domain.Click) bool {
+	window := p.opts.DedupeWindow
+	if link.DedupeWindow != nil {
+		window = *link.DedupeWindow
+	}
+	if c.VisitorHash != nil {
+		c.IsDuplicate = p.dedupe.duplicate(link.ID, *c.VisitorHash, window, c.OccurredAt)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+	select {
+	case p.queue <- c:
+		return true
+	default:
+		p.dropped.Add(1)
+		p.logger.Warn("click pipeline full, dropping click", "capacity", cap(p.queue), "link_id", link.ID)
+		return false
+	}
+}
+
+// QueueDepth returns the number of clicks waiting to be stored
+func (p *Pipeline) QueueDepth() int {
+	return len(p.queue)
+}
+
+// Capacity returns the maximum number of queued clicks
+func (p *Pipeline) Capacity() int {
+	return cap(p.queue)
+}
+
+// Dropped returns the number of clicks dropped because the queue was full
+func (p *Pipeline) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// Close stops accepting clicks and waits until the queued ones are stored
+func (p *Pipeline) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *Pipeline) work() {
+	defer p.wg.Done()
+	for c := range p.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), insertTimeout)
+		if err := p.repo.Insert(ctx, c); err != nil {
+			p.logger.Error("click insert failed", "link_id", c.LinkID, "error", err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,190 @@
+// Package clientip finds the address of the client behind a request. Forwarding headers
+// are only believed when the connection comes from a trusted proxy, and an
+// X-Forwarded-For or Forwarded chain is walked from the right, skipping trusted proxies,
+// so a client cannot pick its own address by sending the header itself.
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Forwarding headers the resolver can read
+const (
+	XForwardedFor  = "X-Forwarded-For"
+	Forwarded      = "Forwarded" // RFC 7239
+	CFConnectingIP = "CF-Connecting-IP"
+)
+
+// Headers lists the accepted header names, for configuration checks
+var Headers = []string{XForwardedFor, Forwarded, CFConnectingIP}
+
+// Named groups accepted by ParseTrusted next to CIDRs and addresses
+var groups = map[string][]netip.Prefix{
+	"loopback": {netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")},
+	"private": {
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("172.16.0.0/12"),
+		netip.MustParsePrefix("192.168.0.0/16"),
+		netip.MustParsePrefix("fc00::/7"),
+	},
+}
+
+// Resolver resolves client addresses. The zero Resolver trusts no proxy and always
+// returns the connection's address.
+type Resolver struct {
+	trusted []netip.Prefix
+	header  string
+}
+
+// New trusts forwarding headers from the given networks. header is one of Headers in
+// any case; anything else means X-Forwarded-For.
+func New(trusted []netip.Prefix, header string) *Resolver {
+	r := &Resolver{trusted: trusted, header: XForwardedFor}
+	for _, name := range Headers {
+		if strings.EqualFold(header, name) {
+			r.header = name
+		}
+	}
+	return r
+}
+
+// ParseTrusted reads a comma separated list of CIDRs, single addresses and the names
+// loopback and private
+func ParseTrusted(s string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case groups[entry] != nil:
+			out = append(out, groups[entry]...)
+		case strings.Contains(entry, "/"):
+			p, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a CIDR", entry)
+			}
+			out = append(out, p.Masked())
+		default:
+			a, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an address, CIDR, loopback or private", entry)
+			}
+			a = a.Unmap()
+			out = append(out, netip.PrefixFrom(a, a.BitLen()))
+		}
+	}
+	return out, nil
+}
+
+// Resolve returns the client address of a request received from remoteAddr (host:port
+// or a bare address) with the given headers
+func (r *Resolver) Resolve(remoteAddr string, h http.Header) string {
+	peer, ok := parseAddr(remoteAddr)
+	if !ok {
+		return remoteAddr
+	}
+	if r == nil || !r.isTrusted(peer) {
+		return peer.String()
+	}
+	switch r.header {
+	case CFConnectingIP:
+		// Set by Cloudflare to the single address it received the request from
+		if a, ok := parseAddr(strings.TrimSpace(h.Get(CFConnectingIP))); ok {
+			return a.String()
+		}
+		return peer.String()
+	case Forwarded:
+		return r.walk(peer, forwardedFor(h.Values(Forwarded))).String()
+	default:
+		return r.walk(peer, forwardedList(h.Values(XForwardedFor))).String()
+	}
+}
+
+// walk goes through the hops a chain of proxies recorded, nearest first, and returns
+// the first one not trusted. Past an unparseable entry nothing can be believed, so the
+// last good hop is returned; if every hop is trusted the farthest one is the client.
+func (r *Resolver) walk(peer netip.Addr, hops []string) netip.Addr {
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		a, ok := parseAddr(hops[i])
+		if !ok {
+			return client
+		}
+		client = a
+		if !r.isTrusted(a) {
+			return a
+		}
+	}
+	return client
+}
+
+func (r *Resolver) isTrusted(a netip.Addr) bool {
+	for _, p := range r.trusted {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedList splits X-Forwarded-For values (the header may be repeated) into hops
+func forwardedList(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for hop := range strings.SplitSeq(v, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// forwardedFor returns the for= parameter of each Forwarded element, in order. Hops
+// without one are kept as empty entries so they stop the walk.
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for elem := range strings.SplitSeq(v, ",") {
+			hop := ""
+			for pair := range strings.SplitSeq(elem, ";") {
+				k, val, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(k, "for") {
+					hop = strings.Trim(val, `"`)
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseAddr accepts an address with or without a port, IPv6 optionally in brackets
+// ("[2001:db8::1]:4711" as Forwarded writes it). IPv4-mapped IPv6 addresses are unmapped.
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return a.Unmap().WithZone(""), true
+}
+
+type ctxKey struct{}
+
+// NewContext returns ctx carrying the resolved client address
+func NewContext(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, ip)
+}
+
+// FromContext returns the address stored by NewContext, or ""
+func FromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(ctxKey{}).(string)
+	return ip
+}
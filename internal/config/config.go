@@ -1,67 +1,272 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
+	"net/netip"
+	"net/url"
 	"os"
-	"strconv"
+	"reflect"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clientip"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/id"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/rate"
 )
 
+// Config is read from environment variables and, under them, the optional CONFIG_FILE.
+// The setting tags name the variable (or variables) each field comes from.
 type Config struct {
-	Port            int
-	BaseURL         string
-	DatabaseURL     string
-	ReadTimeout     time.Duration
-	WriteTimeout    time.Duration
-	IdleTimeout     time.Duration
-	RateLimitCreate int           // requests per minute per IP for POST /v1/links
-	RateLimitWindow time.Duration // e.g., 1m
-	KeyMinLen       int
-	KeyMaxLen       int
-	WebDir          string
+	File            string         `setting:"CONFIG_FILE"` // YAML file read under the environment; empty for none
+	Port            int            `setting:"PORT"`
+	GRPCPort        int            `setting:"GRPC_PORT"` // gRPC API port; 0 (the default) disables it
+	BaseURL         string         `setting:"BASE_URL"`
+	DatabaseURL     string         `setting:"DATABASE_URL"`
+	ReadTimeout     time.Duration  `setting:"READ_TIMEOUT"`
+	WriteTimeout    time.Duration  `setting:"WRITE_TIMEOUT"`
+	IdleTimeout     time.Duration  `setting:"IDLE_TIMEOUT"`
+	RateLimitCreate int            `setting:"RATE_LIMIT_CREATE"`            // requests per window for POST /v1/links, unless RATE_LIMITS sets create
+	RateLimitWindow time.Duration  `setting:"RATE_LIMIT_WINDOW"`            // e.g., 1m
+	RateLimits      rate.Tiers     `setting:"RATE_LIMITS,RATE_LIMIT_TIERS"` // per route, for anonymous clients and per API key tier
+	RateLimitStore  string         `setting:"RATE_LIMIT_STORE"`             // memory (per instance) or postgres (shared by all instances)
+	TrustedProxies  []netip.Prefix `setting:"TRUSTED_PROXIES"`              // forwarding headers are only believed from these peers
+	ClientIPHeader  string         `setting:"CLIENT_IP_HEADER"`             // X-Forwarded-For, Forwarded or CF-Connecting-IP
+	KeyMinLen       int            `setting:"KEY_MIN_LEN"`
+	KeyMaxLen       int            `setting:"KEY_MAX_LEN"`
+	KeyStrategy     string         `setting:"KEY_STRATEGY"`     // random, counter or hash
+	KeySeed         string         `setting:"KEY_SEED"`         // keys the counter permutation
+	KeyAlphabet     string         `setting:"KEY_ALPHABET"`     // base62, crockford, lower or words; requests may pick another
+	KeyBannedWords  []string       `setting:"KEY_BANNED_WORDS"` // generated keys never contain these, next to the built-in list
+	ReservedWords   []string       `setting:"RESERVED_WORDS"`   // aliases refused next to the built-in ones (api, admin, ...)
+	BlockedHosts    []string       `setting:"BLOCKED_HOSTS"`    // destinations refused, subdomains included
+	WebDir          string         `setting:"WEB_DIR"`
+	RollupInterval  time.Duration  `setting:"ROLLUP_INTERVAL"`      // how often new clicks are added to the rollups; 0 disables
+	ClickRetention  int            `setting:"CLICK_RETENTION_DAYS"` // days raw clicks are kept; 0 keeps them forever (rollups are always kept)
+	PurgeInterval   time.Duration  `setting:"PURGE_INTERVAL"`
+	PurgeBatchSize  int            `setting:"PURGE_BATCH_SIZE"`
+	AdminToken      string         `setting:"ADMIN_TOKEN"` // bearer token for destructive endpoints and /metrics; empty disables them
+	ClickQueueSize  int            `setting:"CLICK_QUEUE_SIZE"`
+	ClickWorkers    int            `setting:"CLICK_WORKERS"`
+	DedupeWindow    time.Duration  `setting:"CLICK_DEDUPE_WINDOW"` // default per-link click de-duplication window; 0 disables
+	TraceExporter   string         `setting:"TRACE_EXPORTER"`      // none, stdout or otlp
+	OTLPEndpoint    string         `setting:"OTLP_ENDPOINT"`       // OTLP/HTTP collector base URL, e.g. http://localhost:4318
+	ServiceName     string         `setting:"SERVICE_NAME"`
+	LogFormat       string         `setting:"LOG_FORMAT"`           // json or text
+	LogLevel        slog.Level     `setting:"LOG_LEVEL"`            // debug, info, warn or error
+	DrainDelay      time.Duration  `setting:"SHUTDOWN_DRAIN_DELAY"` // readiness fails this long before the server stops accepting requests
+
+	TLSCertFile       string        `setting:"TLS_CERT_FILE"`       // default certificate; with TLSKeyFile or TLSCertDir, PORT serves HTTPS
+	TLSKeyFile        string        `setting:"TLS_KEY_FILE"`        // key of TLSCertFile
+	TLSCertDir        string        `setting:"TLS_CERT_DIR"`        // {name}.crt/{name}.key pairs picked by SNI, e.g. for custom domains
+	TLSReloadInterval time.Duration `setting:"TLS_RELOAD_INTERVAL"` // how often certificate files are checked for changes
+	HTTPRedirectPort  int           `setting:"HTTP_REDIRECT_PORT"`  // plain HTTP port redirecting to HTTPS; 0 disables it
+	HSTSMaxAge        time.Duration `setting:"HSTS_MAX_AGE"`        // Strict-Transport-Security max-age on HTTPS responses; 0 omits the header
+	HSTSSubdomains    bool          `setting:"HSTS_INCLUDE_SUBDOMAINS"`
+	HSTSPreload       bool          `setting:"HSTS_PRELOAD"`
 }
 
+// reloadable are the fields a running server applies on SIGHUP; changes to the others
+// need a restart
+var reloadable = []string{"RateLimitCreate", "RateLimitWindow", "RateLimits", "ReservedWords", "BlockedHosts", "LogLevel"}
+
+// KeyOptions selects the keys of system links
+func (c Config) KeyOptions() id.Options {
+	return id.Options{
+		Strategy: c.KeyStrategy,
+		Alphabet: c.KeyAlphabet,
+		MinLen:   c.KeyMinLen,
+		MaxLen:   c.KeyMaxLen,
+		Seed:     c.KeySeed,
+		Banned:   c.KeyBannedWords,
+	}
+}
+
+// TLSEnabled reports whether PORT serves HTTPS
+func (c Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || c.TLSCertDir != ""
+}
+
+// Load reads the configuration. Environment variables win over CONFIG_FILE, which wins
+// over the defaults. Every malformed or invalid setting is reported, joined into one error.
 func Load() (Config, error) {
+	src, err := newSource(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return Config{}, err
+	}
 	cfg := Config{
-		Port:            intFromEnv("PORT", 8080),
-		BaseURL:         strFromEnv("BASE_URL", "http://localhost:8080"),
-		DatabaseURL:     os.Getenv("DATABASE_URL"),
-		ReadTimeout:     durationFromEnv("READ_TIMEOUT", 5*time.Second),
-		WriteTimeout:    durationFromEnv("WRITE_TIMEOUT", 10*time.Second),
-		IdleTimeout:     durationFromEnv("IDLE_TIMEOUT", 60*time.Second),
-		RateLimitCreate: intFromEnv("RATE_LIMIT_CREATE", 10),
-		RateLimitWindow: durationFromEnv("RATE_LIMIT_WINDOW", time.Minute),
-		KeyMinLen:       intFromEnv("KEY_MIN_LEN", 6),
-		KeyMaxLen:       intFromEnv("KEY_MAX_LEN", 8),
-		WebDir:          strFromEnv("WEB_DIR", "web"),
+		File:            src.path,
+		Port:            src.int("PORT", 8080),
+		GRPCPort:        src.int("GRPC_PORT", 0),
+		BaseURL:         src.str("BASE_URL", "http://localhost:8080"),
+		DatabaseURL:     src.str("DATABASE_URL", ""),
+		ReadTimeout:     src.duration("READ_TIMEOUT", 5*time.Second),
+		WriteTimeout:    src.duration("WRITE_TIMEOUT", 10*time.Second),
+		IdleTimeout:     src.duration("IDLE_TIMEOUT", 60*time.Second),
+		RateLimitCreate: src.int("RATE_LIMIT_CREATE", 10),
+		RateLimitWindow: src.duration("RATE_LIMIT_WINDOW", time.Minute),
+		RateLimitStore:  src.str("RATE_LIMIT_STORE", "memory"),
+		ClientIPHeader:  src.str("CLIENT_IP_HEADER", clientip.XForwardedFor),
+		KeyMinLen:       src.int("KEY_MIN_LEN", 6),
+		KeyMaxLen:       src.int("KEY_MAX_LEN", 8),
+		KeyStrategy:     src.str("KEY_STRATEGY", id.Random),
+		KeySeed:         src.str("KEY_SEED", ""),
+		KeyAlphabet:     src.str("KEY_ALPHABET", id.Base62),
+		KeyBannedWords:  src.list("KEY_BANNED_WORDS"),
+		ReservedWords:   src.list("RESERVED_WORDS"),
+		BlockedHosts:    src.list("BLOCKED_HOSTS"),
+		WebDir:          src.str("WEB_DIR", "web"),
+		RollupInterval:  src.duration("ROLLUP_INTERVAL", 5*time.Minute),
+		ClickRetention:  src.int("CLICK_RETENTION_DAYS", 0),
+		PurgeInterval:   src.duration("PURGE_INTERVAL", time.Hour),
+		PurgeBatchSize:  src.int("PURGE_BATCH_SIZE", 1000),
+		AdminToken:      src.str("ADMIN_TOKEN", ""),
+		ClickQueueSize:  src.int("CLICK_QUEUE_SIZE", 10000),
+		ClickWorkers:    src.int("CLICK_WORKERS", 4),
+		DedupeWindow:    src.duration("CLICK_DEDUPE_WINDOW", 30*time.Second),
+		TraceExporter:   src.str("TRACE_EXPORTER", "none"),
+		OTLPEndpoint:    src.str("OTLP_ENDPOINT", "http://localhost:4318"),
+		ServiceName:     src.str("SERVICE_NAME", "url-shortener"),
+		LogFormat:       src.str("LOG_FORMAT", "text"),
+		DrainDelay:      src.duration("SHUTDOWN_DRAIN_DELAY", 0),
+
+		TLSCertFile:       src.str("TLS_CERT_FILE", ""),
+		TLSKeyFile:        src.str("TLS_KEY_FILE", ""),
+		TLSCertDir:        src.str("TLS_CERT_DIR", ""),
+		TLSReloadInterval: src.duration("TLS_RELOAD_INTERVAL", time.Minute),
+		HTTPRedirectPort:  src.int("HTTP_REDIRECT_PORT", 0),
+		HSTSMaxAge:        src.duration("HSTS_MAX_AGE", 0),
+		HSTSSubdomains:    src.bool("HSTS_INCLUDE_SUBDOMAINS", false),
+		HSTSPreload:       src.bool("HSTS_PRELOAD", false),
 	}
-	if cfg.DatabaseURL == "" {
-		return cfg, fmt.Errorf("DATABASE_URL is required")
+	if err := cfg.LogLevel.UnmarshalText([]byte(src.str("LOG_LEVEL", "info"))); err != nil {
+		src.fail("LOG_LEVEL", "must be debug, info, warn or error")
 	}
-	return cfg, nil
+	var perr error
+	if cfg.TrustedProxies, perr = clientip.ParseTrusted(src.str("TRUSTED_PROXIES", "")); perr != nil {
+		src.fail("TRUSTED_PROXIES", "%v", perr)
+	}
+	cfg.RateLimits = loadRateLimits(cfg, src)
+	src.checkUnknown()
+
+	return cfg, errors.Join(append(src.errs, cfg.validate()...)...)
 }
 
-func strFromEnv(key, def string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// loadRateLimits builds the route policies: create from RATE_LIMIT_CREATE per
+// RATE_LIMIT_WINDOW, overridden and extended by RATE_LIMITS, and tier policies from
+// RATE_LIMIT_TIERS
+func loadRateLimits(cfg Config, src *source) rate.Tiers {
+	defaults := rate.Policies{}
+	if cfg.RateLimitCreate > 0 {
+		defaults[rate.CreateLinks] = rate.Policy{Limit: cfg.RateLimitCreate, Window: cfg.RateLimitWindow}
+	}
+	routes, err := rate.ParsePolicies(src.str("RATE_LIMITS", ""))
+	if err != nil {
+		src.fail("RATE_LIMITS", "%v", err)
 	}
-	return def
+	maps.Copy(defaults, routes)
+	tiers, err := rate.ParseTiers(src.str("RATE_LIMIT_TIERS", ""))
+	if err != nil {
+		src.fail("RATE_LIMIT_TIERS", "%v", err)
+	}
+	return rate.Tiers{Default: defaults, ByTier: tiers}
 }
 
-func intFromEnv(key string, def int) int {
-	if v := os.Getenv(key); v != "" {
-		if i, err := strconv.Atoi(v); err == nil {
-			return i
+// validate checks the settings against each other and their ranges
+func (c Config) validate() []error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
 		}
 	}
-	return def
+
+	check(c.DatabaseURL != "", "DATABASE_URL", "is required")
+	check(validPort(c.Port, false), "PORT", "must be a port between 1 and 65535")
+	check(validPort(c.GRPCPort, true), "GRPC_PORT", "must be a port between 1 and 65535, or 0 to disable gRPC")
+	check(c.GRPCPort == 0 || c.GRPCPort != c.Port, "GRPC_PORT", "must differ from PORT")
+	u, err := url.Parse(c.BaseURL)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && !strings.HasSuffix(c.BaseURL, "/"),
+		"BASE_URL", "must be an absolute http(s) URL without a trailing slash")
+	check(c.ReadTimeout > 0, "READ_TIMEOUT", "must be positive")
+	check(c.WriteTimeout > 0, "WRITE_TIMEOUT", "must be positive")
+	check(c.IdleTimeout > 0, "IDLE_TIMEOUT", "must be positive")
+
+	check(c.RateLimitCreate >= 0, "RATE_LIMIT_CREATE", "must not be negative (0 disables the limit)")
+	check(c.RateLimitCreate == 0 || c.RateLimitWindow >= time.Second, "RATE_LIMIT_WINDOW", "must be at least 1s")
+	check(c.RateLimitStore == "memory" || c.RateLimitStore == "postgres", "RATE_LIMIT_STORE", "must be memory or postgres")
+	check(slices.ContainsFunc(clientip.Headers, func(h string) bool { return strings.EqualFold(h, c.ClientIPHeader) }),
+		"CLIENT_IP_HEADER", "must be X-Forwarded-For, Forwarded or CF-Connecting-IP")
+
+	// Keys are stored under the links.key_format constraint, 3 to 32 characters
+	check(c.KeyMinLen >= 3 && c.KeyMinLen <= 32, "KEY_MIN_LEN", "must be between 3 and 32")
+	check(c.KeyMaxLen >= 3 && c.KeyMaxLen <= 32, "KEY_MAX_LEN", "must be between 3 and 32")
+	check(c.KeyMinLen <= c.KeyMaxLen, "KEY_MIN_LEN", "must not exceed KEY_MAX_LEN (%d > %d)", c.KeyMinLen, c.KeyMaxLen)
+	check(slices.Contains(id.Strategies, c.KeyStrategy), "KEY_STRATEGY", "must be random, counter or hash")
+	check(c.KeyStrategy != id.Counter || c.KeyMinLen <= 10, "KEY_MIN_LEN", "must be at most 10 with KEY_STRATEGY=counter")
+	check(id.IsAlphabet(c.KeyAlphabet), "KEY_ALPHABET", "must be base62, crockford, lower or words")
+	for _, h := range c.BlockedHosts {
+		check(domain.ValidateHost(h), "BLOCKED_HOSTS", "%q is not a host name like example.com", h)
+	}
+
+	check(c.RollupInterval >= 0, "ROLLUP_INTERVAL", "must not be negative (0 disables rollups)")
+	check(c.ClickRetention >= 0, "CLICK_RETENTION_DAYS", "must not be negative (0 keeps clicks forever)")
+	check(c.PurgeInterval > 0, "PURGE_INTERVAL", "must be positive")
+	check(c.PurgeBatchSize > 0, "PURGE_BATCH_SIZE", "must be positive")
+	check(c.ClickQueueSize > 0, "CLICK_QUEUE_SIZE", "must be positive")
+	check(c.ClickWorkers > 0, "CLICK_WORKERS", "must be positive")
+	check(c.DedupeWindow >= 0 && c.DedupeWindow <= 24*time.Hour, "CLICK_DEDUPE_WINDOW", "must be between 0 and 24h")
+	check(slices.Contains([]string{"none", "stdout", "otlp"}, c.TraceExporter), "TRACE_EXPORTER", "must be none, stdout or otlp")
+	check(c.LogFormat == "json" || c.LogFormat == "text", "LOG_FORMAT", "must be json or text")
+	check(c.DrainDelay >= 0, "SHUTDOWN_DRAIN_DELAY", "must not be negative")
+
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "TLS_CERT_FILE", "and TLS_KEY_FILE must be set together")
+	check(c.TLSReloadInterval >= time.Second, "TLS_RELOAD_INTERVAL", "must be at least 1s")
+	check(c.TLSEnabled() || (c.HTTPRedirectPort == 0 && c.HSTSMaxAge == 0),
+		"HTTP_REDIRECT_PORT", "and HSTS_MAX_AGE need TLS (TLS_CERT_FILE or TLS_CERT_DIR)")
+	check(validPort(c.HTTPRedirectPort, true), "HTTP_REDIRECT_PORT", "must be a port between 1 and 65535, or 0 to disable it")
+	check(c.HTTPRedirectPort == 0 || c.HTTPRedirectPort != c.Port, "HTTP_REDIRECT_PORT", "must differ from PORT")
+	check(c.HSTSMaxAge >= 0, "HSTS_MAX_AGE", "must not be negative")
+	// Requirements of the browsers' preload lists (hstspreload.org)
+	check(!c.HSTSPreload || (c.HSTSMaxAge >= 365*24*time.Hour && c.HSTSSubdomains),
+		"HSTS_PRELOAD", "needs HSTS_MAX_AGE of at least 8760h and HSTS_INCLUDE_SUBDOMAINS=true")
+	return errs
+}
+
+func validPort(p int, zeroOK bool) bool {
+	return (zeroOK && p == 0) || (p >= 1 && p <= 65535)
 }
 
-func durationFromEnv(key string, def time.Duration) time.Duration {
-	if v := os.Getenv(key); v != "" {
-		if d, err := time.ParseDuration(v); err == nil {
-			return d
+// Diff returns the settings (variable names) whose values differ between a and b, and
+// of those the ones a running server cannot apply without a restart
+func Diff(a, b Config) (changed, restart []string) {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	t := va.Type()
+	for i := range t.NumField() {
+		if reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			continue
+		}
+		name := t.Field(i).Tag.Get("setting")
+		changed = append(changed, name)
+		if !slices.Contains(reloadable, t.Field(i).Name) {
+			restart = append(restart, name)
 		}
 	}
-	return def
+	return changed, restart
+}
+
+// Reload returns cur with the reloadable settings taken from next: the configuration a
+// running server uses after applying next
+func Reload(cur, next Config) Config {
+	cur.RateLimitCreate = next.RateLimitCreate
+	cur.RateLimitWindow = next.RateLimitWindow
+	cur.RateLimits = next.RateLimits
+	cur.ReservedWords = next.ReservedWords
+	cur.BlockedHosts = next.BlockedHosts
+	cur.LogLevel = next.LogLevel
+	return cur
 }
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// listSeparators joins YAML lists of settings whose entries are not comma separated
+var listSeparators = map[string]string{"RATE_LIMIT_TIERS": ";"}
+
+// source reads settings from the environment, falling back to the config file, and
+// collects every problem instead of stopping at the first
+type source struct {
+	path string
+	file map[string]string // file values by variable name
+	used map[string]bool
+	errs []error
+}
+
+// newSource reads the YAML file at path (none if empty). Its keys are the variable
+// names in any case (port, rate_limits, ...); values are scalars, or lists for
+// settings that take several entries.
+func newSource(path string) (*source, error) {
+	s := &source{path: path, file: map[string]string{}, used: map[string]bool{}}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("CONFIG_FILE: %w", err)
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("CONFIG_FILE %s: %w", path, err)
+	}
+	for k, v := range raw {
+		key := strings.ToUpper(k)
+		switch v := v.(type) {
+		case nil:
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			sep, ok := listSeparators[key]
+			if !ok {
+				sep = ","
+			}
+			s.file[key] = strings.Join(items, sep)
+		case map[string]any:
+			s.fail(key, "must be a value or a list, not a mapping (%s)", path)
+		default:
+			s.file[key] = fmt.Sprint(v)
+		}
+	}
+	return s, nil
+}
+
+// lookup returns the environment variable key or the file's value; empty values count as unset
+func (s *source) lookup(key string) (string, bool) {
+	s.used[key] = true
+	if v := os.Getenv(key); v != "" {
+		return v, true
+	}
+	v := s.file[key]
+	return v, v != ""
+}
+
+func (s *source) fail(key, format string, args ...any) {
+	s.errs = append(s.errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+}
+
+// checkUnknown reports file keys no setting read, most likely typos
+func (s *source) checkUnknown() {
+	var unknown []string
+	for k := range s.file {
+		if !s.used[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	slices.Sort(unknown)
+	for _, k := range unknown {
+		s.fail(k, "unknown setting in %s", s.path)
+	}
+}
+
+func (s *source) str(key, def string) string {
+	if v, ok := s.lookup(key); ok {
+		return v
+	}
+	return def
+}
+
+func (s *source) int(key string, def int) int {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		s.fail(key, "%q is not an integer", v)
+		return def
+	}
+	return i
+}
+
+func (s *source) bool(key string, def bool) bool {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		s.fail(key, "%q is not true or false", v)
+		return def
+	}
+	return b
+}
+
+func (s *source) duration(key string, def time.Duration) time.Duration {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil {
+		s.fail(key, "%q is not a duration like 30s, 5m or 1h", v)
+		return def
+	}
+	return d
+}
+
+// list reads a comma separated list, lower-cased, without empty entries
+func (s *source) list(key string) []string {
+	v, _ := s.lookup(key)
+	var out []string
+	for item := range strings.SplitSeq(v, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// APIKey is an issued API key. The key itself is only shown once, at creation.
+type APIKey struct {
+	ID         int64
+	Name       string
+	Prefix     string // first characters of the key, for recognising it
+	Tier       string // rate limit tier
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (k *APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
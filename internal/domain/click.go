@@ -12,8 +12,15 @@ type Click struct {
 	VisitorHash *string
 	CountryCode *string
 	UserAgent   *string
+	Referer     *string
+	IsBot       bool   // classified as a bot, crawler or link previewer
+	IsDuplicate bool   // same visitor clicked the link within its dedupe window
+	Source      string // marker from the short URL (ClickSourceQR), empty for plain visits
 }
 
+// ClickSourceQR marks clicks from scanned QR codes, whose URL carries ?src=qr
+const ClickSourceQR = "qr"
+
 // ClickStats represents aggregated click statistics
 type ClickStats struct {
 	Total         int64
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CustomDomain is a host that serves its own key namespace next to BASE_URL
+type CustomDomain struct {
+	ID        int64
+	Host      string  // lower case, without port
+	KeyIDs    []int64 // API keys allowed to create links on the domain
+	CreatedAt time.Time
+}
+
+// CanUse reports whether the API key may create and change links on the domain
+func (d *CustomDomain) CanUse(keyID int64) bool {
+	return slices.Contains(d.KeyIDs, keyID)
+}
+
+var hostLabelRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// NormalizeHost lower-cases a host and drops any port and trailing dot, so a Host
+// header and a registered domain compare equal
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// ValidateHost accepts DNS names of at least two labels (go.example.com), as produced
+// by NormalizeHost
+func ValidateHost(host string) bool {
+	if len(host) > 253 || !strings.Contains(host, ".") {
+		return false
+	}
+	for label := range strings.SplitSeq(host, ".") {
+		if !hostLabelRe.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
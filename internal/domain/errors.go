@@ -10,4 +10,7 @@ var (
 	ErrNotFound     = errors.New("not_found")
 	ErrExpired      = errors.New("expired")
 	ErrDisabled     = errors.New("disabled")
+	ErrDomainExists = errors.New("domain_exists")
+	ErrDomainInUse  = errors.New("domain_in_use") // links still use the domain
+	ErrClicksPurged = errors.New("clicks_purged") // stats need raw clicks the retention purge deleted
 )
@@ -2,12 +2,19 @@ package domain
 
 import "time"
 
+// DefaultLifetimeDays is how long a link lives when created without an expiry
+const DefaultLifetimeDays = 90
+
 type Link struct {
 	ID         int64
+	Domain     string // custom domain host; empty for BASE_URL
 	Key        string
 	LongURL    string
 	IsCustom   bool
 	CreatedAt  time.Time
 	ExpiresAt  *time.Time
 	IsDisabled bool
+	APIKeyID   int64 // API key that created the link; 0 when it has no owner
+	// DedupeWindow overrides the default click de-duplication window; 0 disables it
+	DedupeWindow *time.Duration
 }
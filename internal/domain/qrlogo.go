@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// QRLogo is the image overlaid on QR codes rendered with logo=true
+type QRLogo struct {
+	ContentType string // image/png or image/jpeg
+	Image       []byte
+	UpdatedAt   time.Time
+}
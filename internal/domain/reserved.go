@@ -7,6 +7,7 @@ var reserved = map[string]struct{}{
 	"v1":          {},
 	"healthz":     {},
 	"readyz":      {},
+	"livez":       {},
 	"metrics":     {},
 	"admin":       {},
 	"docs":        {},
@@ -16,6 +17,7 @@ var reserved = map[string]struct{}{
 	"static":      {},
 	"assets":      {},
 	"app":         {},
+	"dashboard":   {},
 }
 
 func IsReserved(key string) bool {
@@ -32,3 +32,93 @@ func NewStatsRange(days int) StatsRange {
 		To:   now,
 	}
 }
+
+// Granularity is the bucket size of a click time series
+type Granularity string
+
+const (
+	GranularityHour  Granularity = "hour"
+	GranularityDay   Granularity = "day"
+	GranularityWeek  Granularity = "week"
+	GranularityMonth Granularity = "month"
+)
+
+// MaxSeriesBuckets caps the number of buckets a single series may span
+const MaxSeriesBuckets = 2000
+
+// ParseGranularity validates a granularity name, defaulting to day when empty
+func ParseGranularity(s string) (Granularity, bool) {
+	switch g := Granularity(s); g {
+	case "":
+		return GranularityDay, true
+	case GranularityHour, GranularityDay, GranularityWeek, GranularityMonth:
+		return g, true
+	}
+	return "", false
+}
+
+// Truncate returns the start of the bucket containing t, using the wall clock of loc.
+// Weeks start on Monday, matching Postgres date_trunc('week', ...). Hours are cut in
+// absolute time, so the repeated hour of a DST fall-back is a bucket of its own.
+func (g Granularity) Truncate(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	y, m, d := t.Date()
+	switch g {
+	case GranularityHour:
+		intoHour := time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+		return t.Add(-intoHour)
+	case GranularityWeek:
+		offset := (int(t.Weekday()) + 6) % 7 // days since Monday
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, loc)
+	case GranularityMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+}
+
+// Next returns the start of the bucket following the one starting at start
+func (g Granularity) Next(start time.Time) time.Time {
+	loc := start.Location()
+	y, m, d := start.Date()
+	switch g {
+	case GranularityHour:
+		return start.Add(time.Hour)
+	case GranularityWeek:
+		return time.Date(y, m, d+7, 0, 0, 0, 0, loc)
+	case GranularityMonth:
+		return time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+}
+
+// Buckets lists the start of every bucket overlapping [from, to).
+// It reports false if the range spans more than MaxSeriesBuckets buckets.
+func (g Granularity) Buckets(from, to time.Time, loc *time.Location) ([]time.Time, bool) {
+	var out []time.Time
+	for b := g.Truncate(from, loc); b.Before(to); b = g.Next(b) {
+		if len(out) == MaxSeriesBuckets {
+			return nil, false
+		}
+		out = append(out, b)
+	}
+	return out, true
+}
+
+// CountsUniqueVisitors reports whether the bucket of g starting at start lies inside
+// [from, to) and spans exactly one UTC hour or UTC day, the buckets unique visitors are
+// rolled up in. Distinct counts cannot be added up, so other buckets have none.
+func (g Granularity) CountsUniqueVisitors(start, from, to time.Time) bool {
+	end := g.Next(start)
+	if start.Before(from) || end.After(to) {
+		return false
+	}
+	switch g {
+	case GranularityHour:
+		return start.Truncate(time.Hour).Equal(start)
+	case GranularityDay:
+		return start.Truncate(24*time.Hour).Equal(start) && end.Sub(start) == 24*time.Hour
+	}
+	return false
+}
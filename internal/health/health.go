@@ -0,0 +1,133 @@
+// Package health runs the dependency checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clicks"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/postgres"
+)
+
+// checkTimeout bounds a single check so one slow dependency cannot stall the probe
+const checkTimeout = 2 * time.Second
+
+// Check is a named readiness condition; Run returns nil when it holds
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"` // ok or fail
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Checker holds the readiness checks and the draining flag set during shutdown
+type Checker struct {
+	checks   []Check
+	draining atomic.Bool
+	started  time.Time
+}
+
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks, started: time.Now()}
+}
+
+// SetDraining marks the instance as shutting down so readiness fails and load
+// balancers stop routing new traffic to it
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Uptime returns the time since the checker was created
+func (c *Checker) Uptime() time.Duration {
+	return time.Since(c.started)
+}
+
+// Ready runs every check concurrently and reports whether all of them passed
+func (c *Checker) Ready(ctx context.Context) (bool, []Result) {
+	all := append([]Check{{Name: "draining", Run: c.notDraining}}, c.checks...)
+	results := make([]Result, len(all))
+
+	var wg sync.WaitGroup
+	for i, chk := range all {
+		wg.Add(1)
+		go func(i int, chk Check) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			start := time.Now()
+			err := chk.Run(cctx)
+			res := Result{
+				Name:      chk.Name,
+				Status:    "ok",
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000.0,
+			}
+			if err != nil {
+				res.Status = "fail"
+				res.Error = err.Error()
+			}
+			results[i] = res
+		}(i, chk)
+	}
+	wg.Wait()
+
+	ok := true
+	for _, r := range results {
+		if r.Status != "ok" {
+			ok = false
+		}
+	}
+	return ok, results
+}
+
+func (c *Checker) notDraining(context.Context) error {
+	if c.draining.Load() {
+		return errors.New("shutting down")
+	}
+	return nil
+}
+
+// Database checks that a connection can be acquired and used
+func Database(db *pgxpool.Pool) Check {
+	return Check{Name: "database", Run: func(ctx context.Context) error {
+		return db.Ping(ctx)
+	}}
+}
+
+// Migrations checks that the schema is at least at the version this build expects.
+// A newer schema passes so instances of the previous build keep serving during a rollout.
+func Migrations(db *pgxpool.Pool) Check {
+	return Check{Name: "migrations", Run: func(ctx context.Context) error {
+		v, err := postgres.CurrentSchemaVersion(ctx, db)
+		if err != nil {
+			return err
+		}
+		if v < postgres.SchemaVersion {
+			return fmt.Errorf("schema version %d, expected at least %d", v, postgres.SchemaVersion)
+		}
+		return nil
+	}}
+}
+
+// ClickPipeline fails while the click queue is at least threshold (0..1) full,
+// since new clicks are about to be dropped
+func ClickPipeline(p *clicks.Pipeline, threshold float64) Check {
+	return Check{Name: "click_pipeline", Run: func(context.Context) error {
+		depth, capacity := p.QueueDepth(), p.Capacity()
+		if float64(depth) >= threshold*float64(capacity) {
+			return fmt.Errorf("click queue saturated (%d/%d)", depth, capacity)
+		}
+		return nil
+	}}
+}
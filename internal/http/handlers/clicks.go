@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/http/middleware"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/useragent"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/util"
+)
+
+type ClicksDeps struct {
+	Config      config.Config
+	Logger      *slog.Logger
+	LinksRepo   storage.LinksRepo
+	DomainsRepo storage.DomainsRepo
+	ClicksRepo  storage.ClicksRepo
+}
+
+type deleteClicksResponse struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// Handles DELETE /v1/links/{key}/clicks: erases all raw clicks and rollups of a link
+func DeleteLinkClicks(d ClicksDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		link, err := d.LinksRepo.GetByKey(r.Context(), linkDomain(r), r.PathValue("key"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		n, err := d.ClicksRepo.DeleteByLink(r.Context(), link.ID)
+		if err != nil {
+			d.Logger.ErrorContext(r.Context(), "delete link clicks failed", "error", err)
+			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not delete clicks")
+			return
+		}
+		d.Logger.InfoContext(r.Context(), "erased link clicks", "count", n)
+		util.WriteJSON(w, http.StatusOK, deleteClicksResponse{Deleted: n})
+	})
+}
+
+// Handles DELETE /v1/clicks?visitor={visitor_hash}: erases every raw click of one visitor
+func DeleteVisitorClicks(d ClicksDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		visitor := r.URL.Query().Get("visitor")
+		if visitor == "" {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", "visitor is required")
+			return
+		}
+
+		n, err := d.ClicksRepo.DeleteByVisitor(r.Context(), visitor)
+		if err != nil {
+			d.Logger.ErrorContext(r.Context(), "delete visitor clicks failed", "error", err)
+			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not delete clicks")
+			return
+		}
+		d.Logger.InfoContext(r.Context(), "erased visitor clicks", "count", n)
+		util.WriteJSON(w, http.StatusOK, deleteClicksResponse{Deleted: n})
+	})
+}
+
+const (
+	exportDefaultLimit = 1000
+	exportMaxLimit     = 10000
+	exportFlushEvery   = 500
+	// exportWriteTimeout replaces the server WriteTimeout for long exports
+	exportWriteTimeout = 5 * time.Minute
+)
+
+// clickEvent is one exported click. Raw IPs and visitor hashes are never exported.
+type clickEvent struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Country     string    `json:"country"`
+	Referrer    string    `json:"referrer"`
+	Device      string    `json:"device"`
+	Browser     string    `json:"browser"`
+	OS          string    `json:"os"`
+	IsBot       bool      `json:"is_bot"`
+	IsDuplicate bool      `json:"is_duplicate"`
+	Source      string    `json:"source"` // "qr" for QR code scans, empty otherwise
+}
+
+var clickEventCSVHeader = []string{"timestamp", "country", "referrer", "device", "browser", "os", "is_bot", "is_duplicate", "source"}
+
+func newClickEvent(c *domain.Click) clickEvent {
+	ua := ""
+	if c.UserAgent != nil {
+		ua = *c.UserAgent
+	}
+	info := useragent.Parse(ua, c.IsBot)
+	ev := clickEvent{
+		Timestamp:   c.OccurredAt.UTC(),
+		Device:      info.Device,
+		Browser:     info.Browser,
+		OS:          info.OS,
+		IsBot:       c.IsBot,
+		IsDuplicate: c.IsDuplicate,
+		Source:      c.Source,
+	}
+	if c.CountryCode != nil {
+		ev.Country = *c.CountryCode
+	}
+	if c.Referer != nil {
+		ev.Referrer = *c.Referer
+	}
+	return ev
+}
+
+func (ev clickEvent) csvRecord() []string {
+	return []string{
+		ev.Timestamp.Format(time.RFC3339Nano), ev.Country, ev.Referrer, ev.Device, ev.Browser, ev.OS,
+		strconv.FormatBool(ev.IsBot), strconv.FormatBool(ev.IsDuplicate), ev.Source,
+	}
+}
+
+// Handles GET /v1/links/{key}/clicks[?from=&to=&cursor=&limit=&format=csv|ndjson]
+// Streams one page of raw click events of a link the caller's API key created, in time
+// order. When more may follow, the next page is advertised in the X-Next-Cursor and Link headers.
+func ExportClicks(d ClicksDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		format := q.Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "ndjson" {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", "format must be csv or ndjson")
+			return
+		}
+		limit := exportDefaultLimit
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > exportMaxLimit {
+				util.WriteError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("limit must be between 1 and %d", exportMaxLimit))
+				return
+			}
+			limit = n
+		}
+		cr := storage.ClickRange{From: time.Unix(0, 0).UTC(), To: time.Now().UTC()}
+		if v := q.Get("from"); v != "" {
+			f, err := parseRangeBound(v, time.UTC, false)
+			if err != nil {
+				util.WriteError(w, http.StatusBadRequest, "bad_request", "invalid from date")
+				return
+			}
+			cr.From = f
+		}
+		if v := q.Get("to"); v != "" {
+			t, err := parseRangeBound(v, time.UTC, true)
+			if err != nil {
+				util.WriteError(w, http.StatusBadRequest, "bad_request", "invalid to date")
+				return
+			}
+			cr.To = t
+		}
+		if v := q.Get("cursor"); v != "" {
+			c, err := decodeClickCursor(v)
+			if err != nil {
+				util.WriteError(w, http.StatusBadRequest, "bad_request", "invalid cursor")
+				return
+			}
+			cr.After = c
+		}
+
+		link, err := d.LinksRepo.GetByKey(r.Context(), linkDomain(r), r.PathValue("key"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		svc := links.Service{Repo: d.LinksRepo, Domains: d.DomainsRepo, Logger: d.Logger}
+		if err := svc.Authorize(r.Context(), link, middleware.GetAPIKey(r.Context()).ID); err != nil {
+			writeLinkError(w, err)
+			return
+		}
+		cr.LinkID = link.ID
+
+		end, err := d.ClicksRepo.PageEnd(r.Context(), cr, limit)
+		if err != nil {
+			d.Logger.ErrorContext(r.Context(), "export clicks failed", "error", err)
+			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not export clicks")
+			return
+		}
+		if end != nil {
+			next := encodeClickCursor(end)
+			nq := r.URL.Query()
+			nq.Set("cursor", next)
+			w.Header().Set("X-Next-Cursor", next)
+			w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, nq.Encode()))
+		}
+
+		filename := link.Key + "-clicks." + format
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+
+		rc := http.NewResponseController(w)
+		_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+
+		var write func(clickEvent) error
+		var flush func() error
+		if format == "csv" {
+			cw := csv.NewWriter(w)
+			if err := cw.Write(clickEventCSVHeader); err != nil {
+				return
+			}
+			write = func(ev clickEvent) error { return cw.Write(ev.csvRecord()) }
+			flush = func() error { cw.Flush(); return cw.Error() }
+		} else {
+			enc := json.NewEncoder(w)
+			write = func(ev clickEvent) error { return enc.Encode(ev) }
+			flush = func() error { return nil }
+		}
+
+		n := 0
+		err = d.ClicksRepo.Each(r.Context(), cr, end, func(c *domain.Click) error {
+			if err := write(newClickEvent(c)); err != nil {
+				return err
+			}
+			n++
+			if n%exportFlushEvery == 0 {
+				if err := flush(); err != nil {
+					return err
+				}
+				_ = rc.Flush()
+			}
+			return nil
+		})
+		if ferr := flush(); err == nil {
+			err = ferr
+		}
+		if err != nil {
+			// Headers are already sent; the client sees a truncated body
+			d.Logger.ErrorContext(r.Context(), "export clicks stream failed", "rows", n, "error", err)
+		}
+	})
+}
+
+// Cursors are opaque to clients: base64url("<unix nanos>|<click id>")
+func encodeClickCursor(c *storage.ClickCursor) string {
+	raw := strconv.FormatInt(c.OccurredAt.UnixNano(), 10) + "|" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeClickCursor(s string) (*storage.ClickCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	nanos, rawID, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+	ns, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.ClickCursor{OccurredAt: time.Unix(0, ns).UTC(), ID: id}, nil
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/http/middleware"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/util"
+)
+
+// The dashboard covers the links created with the caller's API key.
+type DashboardDeps struct {
+	Config        config.Config
+	Logger        *slog.Logger
+	LinksRepo     storage.LinksRepo
+	DashboardRepo storage.DashboardRepo
+}
+
+const (
+	dashboardDefaultLimit = 10
+	dashboardMaxLimit     = 100
+)
+
+// dashboardPeriod holds the query parameters shared by the dashboard endpoints:
+// from/to (as in stats), tz, includeBots and limit.
+type dashboardPeriod struct {
+	From        time.Time
+	To          time.Time
+	Loc         *time.Location
+	IncludeBots bool
+	Limit       int
+}
+
+func parseDashboardPeriod(q url.Values) (dashboardPeriod, error) {
+	p := dashboardPeriod{Loc: time.UTC, Limit: dashboardDefaultLimit}
+	if tz := q.Get("tz"); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			return p, errors.New("tz must be an IANA time zone name")
+		}
+		p.Loc = l
+	}
+	today := domain.GranularityDay.Truncate(time.Now(), p.Loc)
+	p.From = today.AddDate(0, 0, -30)
+	p.To = today.AddDate(0, 0, 1)
+	if v := q.Get("from"); v != "" {
+		f, err := parseRangeBound(v, p.Loc, false)
+		if err != nil {
+			return p, errors.New("invalid from date")
+		}
+		p.From = f
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := parseRangeBound(v, p.Loc, true)
+		if err != nil {
+			return p, errors.New("invalid to date")
+		}
+		p.To = t
+	}
+	if !p.From.Before(p.To) {
+		return p, errors.New("from must be before to")
+	}
+	if v := q.Get("includeBots"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return p, errors.New("includeBots must be true or false")
+		}
+		p.IncludeBots = b
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > dashboardMaxLimit {
+			return p, fmt.Errorf("limit must be between 1 and %d", dashboardMaxLimit)
+		}
+		p.Limit = n
+	}
+	return p, nil
+}
+
+type dashboardLink struct {
+	Key         string     `json:"key"`
+	Domain      string     `json:"domain,omitempty"`
+	ShortURL    string     `json:"short_url"`
+	OriginalURL string     `json:"original_url"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	IsDisabled  bool       `json:"is_disabled"`
+	Clicks      *int64     `json:"clicks,omitempty"`
+}
+
+func newDashboardLink(cfg config.Config, l *domain.Link) dashboardLink {
+	return dashboardLink{
+		Key:         l.Key,
+		Domain:      l.Domain,
+		ShortURL:    links.ShortURL(cfg.BaseURL, l),
+		OriginalURL: l.LongURL,
+		CreatedAt:   l.CreatedAt,
+		ExpiresAt:   l.ExpiresAt,
+		IsDisabled:  l.IsDisabled,
+	}
+}
+
+type dashboardLinksResponse struct {
+	From  string          `json:"from"`
+	To    string          `json:"to"`
+	Links []dashboardLink `json:"links"`
+}
+
+type dashboardSeriesResponse struct {
+	From        string         `json:"from"`
+	To          string         `json:"to"`
+	Granularity string         `json:"granularity"`
+	Timezone    string         `json:"timezone"`
+	IncludeBots bool           `json:"include_bots"`
+	TotalClicks int64          `json:"total_clicks"`
+	Series      []bucketRecord `json:"series"`
+}
+
+type valueRecord struct {
+	Value  string `json:"value"`
+	Clicks int64  `json:"clicks"`
+}
+
+type dashboardValuesResponse struct {
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Dimension string        `json:"dimension"`
+	Values    []valueRecord `json:"values"`
+}
+
+// Handles GET /v1/dashboard/top-links[?from=&to=&tz=&includeBots=&limit=]
+func DashboardTopLinks(d DashboardDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := parseDashboardPeriod(r.URL.Query())
+		if err != nil {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		top, err := d.DashboardRepo.TopLinks(r.Context(), middleware.GetAPIKey(r.Context()).ID, p.From, p.To, p.IncludeBots, p.Limit)
+		if errors.Is(err, domain.ErrClicksPurged) {
+			util.WriteError(w, http.StatusBadRequest, "clicks_purged", clicksPurgedMessage)
+			return
+		}
+		if err != nil {
+			d.Logger.ErrorContext(r.Context(), "dashboard top links failed", "error", err)
+			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not fetch dashboard")
+			return
+		}
+		out := make([]dashboardLink, 0, len(top))
+		for _, lc := range top {
+			dl := newDashboardLink(d.Config, lc.Link)
+			clicks := lc.Clicks
+			dl.Clicks = &clicks
+			out = append(out, dl)
+		}
+		util.WriteJSON(w, http.StatusOK, dashboardLinksResponse{
+			From:  p.From.In(p.Loc).Format(time.RFC3339),
+			To:    p.To.In(p.Loc).Format(time.RFC3339),
+			Links: out,
+		})
+	})
+}
+
+// Handles GET /v1/dashboard/clicks[?from=&to=&tz=&granularity=&includeBots=]
+// Returns the zero-filled click time series of the caller's links.
+func DashboardClicks(d DashboardDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		p, err := parseDashboardPeriod(q)
+		if err != nil {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		gran, ok := domain.ParseGranularity(q.Get("granularity"))
+		if !ok {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", "granularity must be one of hour, day, week, month")
+			return
+		}
+		buckets, ok := gran.Buckets(p.From, p.To, p.Loc)
+		if !ok {
+			util.WriteError(w, http.StatusBadRequest, "range_too_large",
+				fmt.Sprintf("range spans more than %d %s buckets", domain.MaxSeriesBuckets, gran))
+			return
+		}
+
+		counts, err := d.DashboardRepo.Series(r.Context(), middleware.GetAPIKey(r.Context()).ID, p.From, p.To, gran, p.Loc, p.IncludeBots)
+		if errors.Is(err, domain.ErrClicksPurged) {
+			util.WriteError(w, http.StatusBadRequest, "clicks_purged", clicksPurgedMessage)
+			return
+		}
+		if err != nil {
+			d.Logger.ErrorContext(r.Context(), "dashboard series failed", "error", err)
+			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not fetch dashboard")
+			return
+		}
+		byStart := make(map[int64]int64, len(counts))
+		for _, c := range counts {
+			byStart[c.Start.Unix()] = c.Clicks
+		}
+		var total int64
+		series := make([]bucketRecord, 0, len(buckets))
+		for _, b := range buckets {
+			clicks := byStart[b.Unix()]
+			total += clicks
+			series = append(series, bucketRecord{Start: b, Clicks: clicks})
+		}
+
+		util.WriteJSON(w, http.StatusOK, dashboardSeriesResponse{
+			From:        p.From.In(p.Loc).Format(time.RFC3339),
+			To:          p.To.In(p.Loc).Format(time.RFC3339),
+			Granularity: string(gran),
+			Timezone:    p.Loc.String(),
+			IncludeBots: p.IncludeBots,
+			TotalClicks: total,
+			Series:      series,
+		})
+	})
+}
+
+// Handles GET /v1/dashboard/referrers and /v1/dashboard/countries[?from=&to=&tz=&includeBots=&limit=]
+// Referrers are reported by host ("" for direct traffic), countries by ISO code ("ZZ" if unknown).
+func DashboardTopValues(d DashboardDeps, dimension string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := parseDashboardPeriod(r.URL.Query())
+		if err != nil {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		values, err := d.DashboardRepo.TopValues(r.Context(), middleware.GetAPIKey(r.Context()).ID, dimension, p.From, p.To, p.IncludeBots, p.Limit)
+		if errors.Is(err, domain.ErrClicksPurged) {
+			util.WriteError(w, http.StatusBadRequest, "clicks_purged", clicksPurgedMessage)
+			return
+		}
+		if err != nil {
+			d.Logger.ErrorContext(r.Context(), "dashboard top values failed", "dimension", dimension, "error", err)
+			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not fetch dashboard")
+			return
+		}
+		out := make([]valueRecord, 0, len(values))
+		for _, v := range values {
+			out = append(out, valueRecord{Value: v.Value, Clicks: v.Clicks})
+		}
+		util.WriteJSON(w, http.StatusOK, dashboardValuesResponse{
+			From:      p.From.In(p.Loc).Format(time.RFC3339),
+			To:        p.To.In(p.Loc).Format(time.RFC3339),
+			Dimension: dimension,
+			Values:    out,
+		})
+	})
+}
+
+// Handles GET /v1/dashboard/new-links[?from=&to=&tz=&limit=]
+func DashboardNewLinks(d DashboardDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := parseDashboardPeriod(r.URL.Query())
+		if err != nil {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		links, err := d.LinksRepo.ListCreated(r.Context(), middleware.GetAPIKey(r.Context()).ID, p.From, p.To, p.Limit)
+		if err != nil {
+			d.Logger.ErrorContext(r.Context(), "dashboard new links failed", "error", err)
+			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not fetch dashboard")
+			return
+		}
+		out := make([]dashboardLink, 0, len(links))
+		for _, l := range links {
+			out = append(out, newDashboardLink(d.Config, l))
+		}
+		util.WriteJSON(w, http.StatusOK, dashboardLinksResponse{
+			From:  p.From.In(p.Loc).Format(time.RFC3339),
+			To:    p.To.In(p.Loc).Format(time.RFC3339),
+			Links: out,
+		})
+	})
+}
@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/health"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/util"
 )
 
 type healthResponse struct {
@@ -14,20 +16,51 @@ type healthResponse struct {
 	DB     string `json:"db"`
 }
 
+// Healthz is kept for existing monitors; prefer /livez and /readyz
 func Healthz(db *pgxpool.Pool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
 		defer cancel()
-		dbStatus := "ok"
+		resp := healthResponse{Status: "ok", DB: "ok"}
+		status := http.StatusOK
 		if err := db.Ping(ctx); err != nil {
-			dbStatus = "error"
-			w.WriteHeader(http.StatusServiceUnavailable)
+			resp = healthResponse{Status: "error", DB: "error"}
+			status = http.StatusServiceUnavailable
 		}
-		resp := healthResponse{
-			Status: "ok",
-			DB:     dbStatus,
+		util.WriteJSON(w, status, resp)
+	})
+}
+
+type livezResponse struct {
+	Status        string  `json:"status"`
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+// Livez reports that the process is up and serving. It checks no dependencies, so a
+// database outage never gets the instance restarted.
+func Livez(c *health.Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		util.WriteJSON(w, http.StatusOK, livezResponse{Status: "ok", UptimeSeconds: c.Uptime().Seconds()})
+	})
+}
+
+type readyzResponse struct {
+	Status string          `json:"status"` // ok or unavailable
+	Checks []health.Result `json:"checks"`
+}
+
+// Readyz runs the readiness checks and answers 503 with the failing ones when the
+// instance should not receive traffic
+func Readyz(c *health.Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ok, results := c.Ready(r.Context())
+		resp := readyzResponse{Status: "ok", Checks: results}
+		status := http.StatusOK
+		if !ok {
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
 		}
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(resp)
+		w.Header().Set("Cache-Control", "no-store")
+		util.WriteJSON(w, status, resp)
 	})
 }
@@ -1,38 +1,178 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/http/middleware"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/util"
 )
 
 type LinkDeps struct {
-	Config    config.Config
-	Logger    *log.Logger
-	LinksRepo storage.LinksRepo
+	Config      config.Config
+	Logger      *slog.Logger
+	LinksRepo   storage.LinksRepo
+	DomainsRepo storage.DomainsRepo
+	Rules       *links.Rules
+	Metrics     *observability.Metrics
 }
 
 type createLinkRequest struct {
-	LongURL     string     `json:"originalUrl"`
-	CustomAlias *string    `json:"customAlias,omitempty"`
-	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	LongURL             string     `json:"original_url"`
+	Domain              string     `json:"domain,omitempty"` // custom domain host; BASE_URL when empty
+	CustomAlias         *string    `json:"custom_alias,omitempty"`
+	ExpiresAt           *time.Time `json:"expires_at,omitempty"`
+	DedupeWindowSeconds *int       `json:"dedupe_window_seconds,omitempty"` // overrides the server default; 0 disables
+	KeyAlphabet         string     `json:"key_alphabet,omitempty"`          // of a generated key; KEY_ALPHABET when empty
+
+	// Deprecated camelCase names, still accepted from older clients
+	LegacyLongURL             string     `json:"originalUrl,omitempty"`
+	LegacyCustomAlias         *string    `json:"customAlias,omitempty"`
+	LegacyExpiresAt           *time.Time `json:"expiresAt,omitempty"`
+	LegacyDedupeWindowSeconds *int       `json:"dedupeWindowSeconds,omitempty"`
+}
+
+// normalize fills unset fields from their deprecated camelCase names
+func (r *createLinkRequest) normalize() {
+	if r.LongURL == "" {
+		r.LongURL = r.LegacyLongURL
+	}
+	if r.CustomAlias == nil {
+		r.CustomAlias = r.LegacyCustomAlias
+	}
+	if r.ExpiresAt == nil {
+		r.ExpiresAt = r.LegacyExpiresAt
+	}
+	if r.DedupeWindowSeconds == nil {
+		r.DedupeWindowSeconds = r.LegacyDedupeWindowSeconds
+	}
+}
+
+// linkResponse is the JSON form of a link
+type linkResponse struct {
+	Key              string     `json:"key"`
+	Domain           string     `json:"domain,omitempty"`
+	ShortURL         string     `json:"short_url"`
+	LongURLCanonical string     `json:"original_url"`
+	IsCustom         bool       `json:"is_custom"`
+	IsDisabled       bool       `json:"is_disabled"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+	DedupeWindowSecs *int       `json:"dedupe_window_seconds,omitempty"`
+}
+
+func newLinkResponse(cfg config.Config, l *domain.Link) linkResponse {
+	resp := linkResponse{
+		Key:              l.Key,
+		Domain:           l.Domain,
+		ShortURL:         links.ShortURL(cfg.BaseURL, l),
+		LongURLCanonical: l.LongURL,
+		IsCustom:         l.IsCustom,
+		IsDisabled:       l.IsDisabled,
+		CreatedAt:        l.CreatedAt,
+		ExpiresAt:        l.ExpiresAt,
+	}
+	if l.DedupeWindow != nil {
+		secs := int(l.DedupeWindow.Seconds())
+		resp.DedupeWindowSecs = &secs
+	}
+	return resp
 }
 
 type createLinkResponse struct {
-	Key              string     `json:"shortCode"`
-	ShortURL         string     `json:"shortUrl"`
-	LongURLCanonical string     `json:"originalUrl"`
-	IsCustom         bool       `json:"isCustom"`
-	CreatedAt        time.Time  `json:"createdAt"`
-	ExpiresAt        *time.Time `json:"expiresAt,omitempty"`
-	Existing         bool       `json:"existing"`
+	linkResponse
+	Existing bool `json:"existing"`
+}
+
+// legacyCreateLinkResponse repeats the fields under the camelCase names POST /v1/links
+// answered with before the API moved to snake_case. They are deprecated and will be
+// dropped in the next release.
+type legacyCreateLinkResponse struct {
+	createLinkResponse
+	LegacyKey                 string     `json:"shortCode"`
+	LegacyShortURL            string     `json:"shortUrl"`
+	LegacyLongURL             string     `json:"originalUrl"`
+	LegacyIsCustom            bool       `json:"isCustom"`
+	LegacyCreatedAt           time.Time  `json:"createdAt"`
+	LegacyExpiresAt           *time.Time `json:"expiresAt,omitempty"`
+	LegacyDedupeWindowSeconds *int       `json:"dedupeWindowSeconds,omitempty"`
+}
+
+func newLegacyCreateLinkResponse(resp createLinkResponse) legacyCreateLinkResponse {
+	return legacyCreateLinkResponse{
+		createLinkResponse:        resp,
+		LegacyKey:                 resp.Key,
+		LegacyShortURL:            resp.ShortURL,
+		LegacyLongURL:             resp.LongURLCanonical,
+		LegacyIsCustom:            resp.IsCustom,
+		LegacyCreatedAt:           resp.CreatedAt,
+		LegacyExpiresAt:           resp.ExpiresAt,
+		LegacyDedupeWindowSeconds: resp.DedupeWindowSecs,
+	}
+}
+
+// service runs the link operations shared with the gRPC API
+func (d LinkDeps) service() links.Service {
+	return links.Service{Repo: d.LinksRepo, Domains: d.DomainsRepo, Rules: d.Rules, Logger: d.Logger, Metrics: d.Metrics}
+}
+
+// linkDomain is the custom domain a keyed /v1 request addresses with ?domain=; empty
+// for BASE_URL
+func linkDomain(r *http.Request) string {
+	return domain.NormalizeHost(r.URL.Query().Get("domain"))
+}
+
+// createRequest converts the JSON body (after normalize) to a service request
+func (r createLinkRequest) createRequest() links.CreateRequest {
+	req := links.CreateRequest{URL: r.LongURL, Domain: r.Domain, KeyAlphabet: r.KeyAlphabet, ExpiresAt: r.ExpiresAt, DedupeWindowSeconds: r.DedupeWindowSeconds}
+	if r.CustomAlias != nil {
+		req.Alias = *r.CustomAlias
+	}
+	return req
+}
+
+func (d LinkDeps) create(ctx context.Context, req createLinkRequest) (*domain.Link, bool, error) {
+	req.normalize()
+	cr := req.createRequest()
+	if k := middleware.GetAPIKey(ctx); k != nil {
+		cr.KeyID = k.ID
+	}
+	return d.service().Create(ctx, cr)
+}
+
+// linkErrorStatus maps link operation failures to HTTP statuses
+var linkErrorStatus = map[links.Kind]int{
+	links.Invalid:            http.StatusBadRequest,
+	links.NotFound:           http.StatusNotFound,
+	links.Conflict:           http.StatusConflict,
+	links.FailedPrecondition: http.StatusConflict,
+	links.Gone:               http.StatusGone,
+	links.Forbidden:          http.StatusForbidden,
+	links.Internal:           http.StatusInternalServerError,
+}
+
+// linkErrorResponse returns the status and body for an error of the links service
+func linkErrorResponse(err error) (int, *util.ErrorResponse) {
+	var lerr *links.Error
+	if !errors.As(err, &lerr) {
+		return http.StatusInternalServerError, &util.ErrorResponse{Error: "server_error", Message: "internal error"}
+	}
+	return linkErrorStatus[lerr.Kind], &util.ErrorResponse{Error: lerr.Code, Message: lerr.Message}
+}
+
+func writeLinkError(w http.ResponseWriter, err error) {
+	status, resp := linkErrorResponse(err)
+	util.WriteJSON(w, status, resp)
 }
 
 func CreateLink(d LinkDeps) http.Handler {
@@ -49,78 +189,127 @@ func CreateLink(d LinkDeps) http.Handler {
 			return
 		}
 
-		if req.ExpiresAt == nil {
-			defaultExpiry := time.Now().UTC().AddDate(0, 0, 90)
-			req.ExpiresAt = &defaultExpiry
-		}
-
-		if req.LongURL == "" {
-			util.WriteError(w, http.StatusBadRequest, "invalid_url", "long_url is required")
+		link, existing, err := d.create(r.Context(), req)
+		if err != nil {
+			writeLinkError(w, err)
 			return
 		}
+		status := http.StatusCreated
+		if existing {
+			status = http.StatusOK
+		}
+		util.WriteJSON(w, status, newLegacyCreateLinkResponse(createLinkResponse{linkResponse: newLinkResponse(d.Config, link), Existing: existing}))
+	})
+}
 
-		canon, err := domain.CanonicalizeURL(req.LongURL)
-		if err != nil {
-			util.WriteError(w, http.StatusBadRequest, "invalid_url", "must be a valid http/https URL")
+type batchCreateRequest struct {
+	Links []createLinkRequest `json:"links"`
+}
+
+// batchResult is the outcome of one batch item: Link on success, Error otherwise
+type batchResult struct {
+	Status int                 `json:"status"`
+	Link   *createLinkResponse `json:"link,omitempty"`
+	Error  *util.ErrorResponse `json:"error,omitempty"`
+}
+
+type batchCreateResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// Handles POST /v1/links/batch: creates up to 100 links. Items are independent; each
+// result carries the status the item would have had as a single request.
+func BatchCreateLinks(d LinkDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req batchCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", "invalid JSON body")
 			return
 		}
-
-		if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
-			util.WriteError(w, http.StatusBadRequest, "expiry_in_past", "expires_at must be in the future")
+		if len(req.Links) == 0 || len(req.Links) > links.MaxBatch {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("links must hold between 1 and %d items", links.MaxBatch))
 			return
 		}
 
-		var link *domain.Link
-		var existing bool
-
-		if req.CustomAlias != nil && *req.CustomAlias != "" {
-			alias := *req.CustomAlias
-			if !domain.ValidateAlias(alias) {
-				util.WriteError(w, http.StatusBadRequest, "invalid_alias", "alias must match [A-Za-z0-9_-]{3,32}")
-				return
-			}
-			if domain.IsReserved(alias) {
-				util.WriteError(w, http.StatusBadRequest, "reserved_key", "alias is reserved")
-				return
-			}
-			link, err = d.LinksRepo.CreateAlias(r.Context(), alias, canon, req.ExpiresAt)
+		results := make([]batchResult, len(req.Links))
+		for i, item := range req.Links {
+			link, existing, err := d.create(r.Context(), item)
 			if err != nil {
-				if errors.Is(err, domain.ErrAliasInUse) {
-					util.WriteError(w, http.StatusConflict, "alias_in_use", "alias already taken")
-					return
-				}
-				d.Logger.Printf("create alias error: %v", err)
-				util.WriteError(w, http.StatusInternalServerError, "server_error", "could not create link")
-				return
+				status, resp := linkErrorResponse(err)
+				results[i] = batchResult{Status: status, Error: resp}
+				continue
 			}
-		} else {
-			// idempotent path
-			if l, err := d.LinksRepo.GetSystemByCanonicalURL(r.Context(), canon); err == nil {
-				link = l
-				existing = true
-			} else {
-				link, err = d.LinksRepo.CreateSystem(r.Context(), canon, req.ExpiresAt)
-				if err != nil {
-					d.Logger.Printf("create system error: %v", err)
-					util.WriteError(w, http.StatusInternalServerError, "server_error", "could not create link")
-					return
-				}
+			status := http.StatusCreated
+			if existing {
+				status = http.StatusOK
 			}
+			results[i] = batchResult{Status: status, Link: &createLinkResponse{linkResponse: newLinkResponse(d.Config, link), Existing: existing}}
 		}
+		util.WriteJSON(w, http.StatusOK, batchCreateResponse{Results: results})
+	})
+}
 
-		resp := createLinkResponse{
-			Key:              link.Key,
-			ShortURL:         d.Config.BaseURL + "/" + link.Key,
-			LongURLCanonical: link.LongURL,
-			IsCustom:         link.IsCustom,
-			CreatedAt:        link.CreatedAt,
-			ExpiresAt:        link.ExpiresAt,
-			Existing:         existing,
+// Handles GET /v1/links/{key}
+func GetLink(d LinkDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		observability.AddLogAttrs(r.Context(), slog.String("link_key", key))
+		link, err := d.service().Get(r.Context(), linkDomain(r), key)
+		if err != nil {
+			writeLinkError(w, err)
+			return
 		}
-		status := http.StatusCreated
-		if existing {
-			status = http.StatusOK
+		util.WriteJSON(w, http.StatusOK, newLinkResponse(d.Config, link))
+	})
+}
+
+// optional is a PATCH field: Set reports that it was present, Value is nil for JSON null
+type optional[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (o *optional[T]) UnmarshalJSON(b []byte) error {
+	o.Set = true
+	if string(b) == "null" {
+		o.Value = nil
+		return nil
+	}
+	o.Value = new(T)
+	return json.Unmarshal(b, o.Value)
+}
+
+type updateLinkRequest struct {
+	LongURL             *string             `json:"original_url"`
+	ExpiresAt           optional[time.Time] `json:"expires_at"`            // null removes the expiry
+	DedupeWindowSeconds optional[int]       `json:"dedupe_window_seconds"` // null restores the server default
+	Disabled            *bool               `json:"disabled"`
+}
+
+// Handles PATCH /v1/links/{key}: changes the fields present in the body (see links.Service.Update)
+func UpdateLink(d LinkDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		observability.AddLogAttrs(r.Context(), slog.String("link_key", key))
+
+		var req updateLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", "invalid JSON body")
+			return
+		}
+
+		updated, err := d.service().Update(r.Context(), linkDomain(r), key, middleware.GetAPIKey(r.Context()).ID, links.Update{
+			URL:                 req.LongURL,
+			SetExpiry:           req.ExpiresAt.Set,
+			ExpiresAt:           req.ExpiresAt.Value,
+			SetDedupeWindow:     req.DedupeWindowSeconds.Set,
+			DedupeWindowSeconds: req.DedupeWindowSeconds.Value,
+			Disabled:            req.Disabled,
+		})
+		if err != nil {
+			writeLinkError(w, err)
+			return
 		}
-		util.WriteJSON(w, status, resp)
+		util.WriteJSON(w, http.StatusOK, newLinkResponse(d.Config, updated))
 	})
 }
@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
+)
+
+// Metrics serves GET /metrics in the Prometheus text exposition format
+func Metrics(m *observability.Metrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = m.WritePrometheus(w)
+	})
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// OpenAPI serves the API document with its server URL set to the configured base URL
+func OpenAPI(spec []byte, baseURL string) http.Handler {
+	body := spec
+	var doc map[string]any
+	if err := json.Unmarshal(spec, &doc); err == nil {
+		doc["servers"] = []map[string]string{{"url": baseURL}}
+		if b, err := json.MarshalIndent(doc, "", "  "); err == nil {
+			body = b
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		_, _ = w.Write(body)
+	})
+}
@@ -0,0 +1,469 @@
+package handlers
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/http/middleware"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/qr"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/util"
+)
+
+type QRDeps struct {
+	Config    config.Config
+	Logger    *slog.Logger
+	LinksRepo storage.LinksRepo
+	LogoRepo  storage.QRLogoRepo
+	Generator *qr.Generator
+}
+
+const (
+	qrDefaultSize = 256
+	// qrMaxAge is how long clients and CDNs may cache a code; the encoded URL never changes
+	qrMaxAge = 24 * 60 * 60
+	// qrExportMaxKeys bounds the links rendered into one ZIP archive
+	qrExportMaxKeys = 500
+)
+
+var qrContentType = map[qr.Format]string{
+	qr.PNG: "image/png",
+	qr.SVG: "image/svg+xml",
+}
+
+// qrParams are the rendering options, given as query parameters of GET /v1/links/{key}/qr
+// or in the body of POST /v1/qr/export
+type qrParams struct {
+	Format string `json:"format"`
+	Size   *int   `json:"size"`
+	Level  string `json:"level"`
+	Margin *int   `json:"margin"`
+	FG     string `json:"fg"`
+	BG     string `json:"bg"`
+	Logo   bool   `json:"logo"` // overlay the API key's logo, forcing level H
+}
+
+func qrParamsFromQuery(q url.Values) (qrParams, error) {
+	p := qrParams{Format: q.Get("format"), Level: q.Get("level"), FG: q.Get("fg"), BG: q.Get("bg")}
+	if v := q.Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return p, fmt.Errorf("size must be an integer")
+		}
+		p.Size = &n
+	}
+	if v := q.Get("margin"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return p, fmt.Errorf("margin must be an integer")
+		}
+		p.Margin = &n
+	}
+	if v := q.Get("logo"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return p, fmt.Errorf("logo must be true or false")
+		}
+		p.Logo = b
+	}
+	return p, nil
+}
+
+// options validates p; the logo, if requested, is added by the caller
+func (p qrParams) options() (qr.Options, error) {
+	opts := qr.Options{Format: qr.PNG, Size: qrDefaultSize}
+
+	switch f := qr.Format(strings.ToLower(p.Format)); f {
+	case "":
+	case qr.PNG, qr.SVG:
+		opts.Format = f
+	default:
+		return opts, fmt.Errorf("format must be png or svg")
+	}
+	if p.Size != nil {
+		if *p.Size < qr.MinSize || *p.Size > qr.MaxSize {
+			return opts, fmt.Errorf("size must be between %d and %d", qr.MinSize, qr.MaxSize)
+		}
+		opts.Size = *p.Size
+	}
+	level, ok := qr.ParseLevel(p.Level)
+	if !ok {
+		return opts, fmt.Errorf("level must be one of L, M, Q, H")
+	}
+	opts.Level = level
+	if p.Margin != nil {
+		if *p.Margin < 0 || *p.Margin > qr.MaxMargin {
+			return opts, fmt.Errorf("margin must be between 0 and %d", qr.MaxMargin)
+		}
+		opts.Margin = p.Margin
+	}
+	var err error
+	if opts.Foreground, err = qrColor(p.FG, "fg"); err != nil {
+		return opts, err
+	}
+	if opts.Background, err = qrColor(p.BG, "bg"); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// etag identifies an image by the encoded data and the rendering options. logoVersion
+// changes whenever a new logo is uploaded.
+func (p qrParams) etag(data string, logoVersion time.Time) string {
+	h := sha256.New()
+	h.Write([]byte(data))
+	size, margin := "", ""
+	if p.Size != nil {
+		size = strconv.Itoa(*p.Size)
+	}
+	if p.Margin != nil {
+		margin = strconv.Itoa(*p.Margin)
+	}
+	for _, v := range []string{p.Format, size, p.Level, margin, strings.TrimPrefix(p.FG, "#"), strings.TrimPrefix(p.BG, "#")} {
+		fmt.Fprintf(h, "\x00%s", strings.ToLower(v))
+	}
+	if p.Logo {
+		fmt.Fprintf(h, "\x00logo=%d", logoVersion.UnixNano())
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+func qrColor(v, name string) (color.Color, error) {
+	if v == "" {
+		return nil, nil
+	}
+	c, err := qr.ParseColor(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return c, nil
+}
+
+// qrData is what a link's code encodes: the short URL marked so scans count as qr_clicks
+func qrData(cfg config.Config, link *domain.Link) string {
+	return links.ShortURL(cfg.BaseURL, link) + "?src=" + domain.ClickSourceQR
+}
+
+// errNoLogo is returned by withLogo when logo=true but the key has not uploaded a logo
+var errNoLogo = errors.New("logo requested but no logo has been uploaded")
+
+// withLogo adds the logo of the API key keyID to opts when p asks for it, returning its
+// version. Links created without a key have no logo.
+func (d QRDeps) withLogo(r *http.Request, keyID int64, p qrParams, opts *qr.Options) (time.Time, error) {
+	if !p.Logo {
+		return time.Time{}, nil
+	}
+	if keyID == 0 {
+		return time.Time{}, errNoLogo
+	}
+	logo, err := d.LogoRepo.Get(r.Context(), keyID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return time.Time{}, errNoLogo
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	img, _, err := qr.DecodeLogo(logo.Image)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decode stored logo: %w", err)
+	}
+	opts.Logo = img
+	return logo.UpdatedAt, nil
+}
+
+// writeLogoError answers a failed withLogo
+func (d QRDeps) writeLogoError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errNoLogo) {
+		util.WriteError(w, http.StatusConflict, "no_logo", "logo=true but no logo has been uploaded with this API key")
+		return
+	}
+	d.Logger.ErrorContext(r.Context(), "qr logo lookup failed", "error", err)
+	util.WriteError(w, http.StatusInternalServerError, "server_error", "could not load QR logo")
+}
+
+// Handles GET /v1/links/{key}/qr[?format=png|svg&size=256&level=L|M|Q|H&margin=4&fg=000000&bg=ffffff&logo=true]
+// The code encodes the short URL with ?src=qr so scans are counted as qr_clicks in stats.
+// logo=true overlays the logo of the API key that created the link.
+func QRCode(d QRDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		observability.AddLogAttrs(r.Context(), slog.String("link_key", key))
+
+		params, err := qrParamsFromQuery(r.URL.Query())
+		if err != nil {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		opts, err := params.options()
+		if err != nil {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+
+		svc := links.Service{Repo: d.LinksRepo, Logger: d.Logger}
+		link, err := svc.Get(r.Context(), linkDomain(r), key)
+		if err != nil {
+			writeLinkError(w, err)
+			return
+		}
+		logoVersion, err := d.withLogo(r, link.APIKeyID, params, &opts)
+		if err != nil {
+			d.writeLogoError(w, r, err)
+			return
+		}
+
+		data := qrData(d.Config, link)
+		etag := params.etag(data, logoVersion)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", qrMaxAge))
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		img, err := d.Generator.Render(data, opts)
+		if err != nil {
+			if errors.Is(err, qr.ErrSizeTooSmall) {
+				util.WriteError(w, http.StatusBadRequest, "bad_request", "size is too small for this link; use a larger size or margin=0")
+				return
+			}
+			d.Logger.ErrorContext(r.Context(), "qr render failed", "error", err)
+			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not render QR code")
+			return
+		}
+
+		w.Header().Set("Content-Type", qrContentType[opts.Format])
+		w.Header().Set("Content-Length", strconv.Itoa(len(img)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(img)
+	})
+}
+
+// etagMatches reports whether an If-None-Match header lists etag (weak comparison)
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+type qrExportRequest struct {
+	Keys   []string `json:"keys"`
+	Domain string   `json:"domain,omitempty"` // custom domain of the keys; BASE_URL when empty
+	qrParams
+}
+
+var qrManifestHeader = []string{"key", "file", "short_url", "encoded_url", "original_url", "error"}
+
+// Handles POST /v1/qr/export: renders the QR codes of up to 500 links into one ZIP
+// archive, with one image per link and manifest.csv listing every requested key.
+// Keys that cannot be rendered are only reported in the manifest. logo=true overlays the
+// logo of the calling API key on every code.
+func ExportQRCodes(d QRDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req qrExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", "invalid JSON body")
+			return
+		}
+		if len(req.Keys) == 0 || len(req.Keys) > qrExportMaxKeys {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", fmt.Sprintf("keys must hold between 1 and %d items", qrExportMaxKeys))
+			return
+		}
+		opts, err := req.options()
+		if err != nil {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		if _, err := d.withLogo(r, middleware.GetAPIKey(r.Context()).ID, req.qrParams, &opts); err != nil {
+			d.writeLogoError(w, r, err)
+			return
+		}
+
+		// Look every link up before streaming, so lookup failures can still change the status
+		svc := links.Service{Repo: d.LinksRepo, Logger: d.Logger}
+		found := make(map[string]*domain.Link, len(req.Keys))
+		for _, key := range req.Keys {
+			if _, seen := found[key]; seen {
+				continue
+			}
+			link, err := svc.Get(r.Context(), req.Domain, key)
+			var lerr *links.Error
+			if errors.As(err, &lerr) && lerr.Kind == links.NotFound {
+				found[key] = nil
+				continue
+			}
+			if err != nil {
+				writeLinkError(w, err)
+				return
+			}
+			found[key] = link
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="qr-codes.zip"`)
+		w.WriteHeader(http.StatusOK)
+
+		zw := zip.NewWriter(w)
+		var manifest strings.Builder
+		cw := csv.NewWriter(&manifest)
+		_ = cw.Write(qrManifestHeader)
+		written := make(map[string]bool, len(found))
+		for _, key := range req.Keys {
+			if written[key] {
+				continue
+			}
+			written[key] = true
+			link := found[key]
+			if link == nil {
+				_ = cw.Write([]string{key, "", "", "", "", "not_found"})
+				continue
+			}
+			data := qrData(d.Config, link)
+			shortURL := links.ShortURL(d.Config.BaseURL, link)
+			img, err := d.Generator.Render(data, opts)
+			if err != nil {
+				reason := "render_failed"
+				if errors.Is(err, qr.ErrSizeTooSmall) {
+					reason = "size_too_small"
+				}
+				_ = cw.Write([]string{key, "", shortURL, data, link.LongURL, reason})
+				continue
+			}
+			// Keys are limited to URL-safe characters, so they are safe file names
+			file := link.Key + "." + string(opts.Format)
+			if err := writeZipFile(zw, file, img); err != nil {
+				d.Logger.WarnContext(r.Context(), "qr export aborted", "error", err)
+				return
+			}
+			_ = cw.Write([]string{key, file, shortURL, data, link.LongURL, ""})
+		}
+		cw.Flush()
+		if err := writeZipFile(zw, "manifest.csv", []byte(manifest.String())); err != nil {
+			d.Logger.WarnContext(r.Context(), "qr export aborted", "error", err)
+			return
+		}
+		if err := zw.Close(); err != nil {
+			d.Logger.WarnContext(r.Context(), "qr export aborted", "error", err)
+		}
+	})
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	// Images are already compressed; storing them saves CPU
+	method := zip.Store
+	if strings.HasSuffix(name, ".csv") || strings.HasSuffix(name, ".svg") {
+		method = zip.Deflate
+	}
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+type qrLogoResponse struct {
+	ContentType string    `json:"content_type"`
+	Bytes       int       `json:"bytes"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Handles PUT /v1/qr/logo: stores the logo of the calling API key, sent as the raw PNG
+// or JPEG body
+func PutQRLogo(d QRDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(io.LimitReader(r.Body, qr.MaxLogoBytes+1))
+		if err != nil {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", "could not read body")
+			return
+		}
+		if len(data) > qr.MaxLogoBytes {
+			util.WriteError(w, http.StatusRequestEntityTooLarge, "payload_too_large", fmt.Sprintf("logo must be at most %d bytes", qr.MaxLogoBytes))
+			return
+		}
+		img, contentType, err := qr.DecodeLogo(data)
+		if err != nil {
+			util.WriteError(w, http.StatusBadRequest, "invalid_logo", err.Error())
+			return
+		}
+
+		keyID := middleware.GetAPIKey(r.Context()).ID
+		logo, err := d.LogoRepo.Put(r.Context(), keyID, contentType, data)
+		if err != nil {
+			d.Logger.ErrorContext(r.Context(), "store qr logo failed", "error", err)
+			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not store logo")
+			return
+		}
+		d.Logger.InfoContext(r.Context(), "qr logo updated", "api_key_id", keyID, "bytes", len(data), "content_type", contentType)
+		util.WriteJSON(w, http.StatusOK, qrLogoResponse{
+			ContentType: contentType,
+			Bytes:       len(data),
+			Width:       img.Bounds().Dx(),
+			Height:      img.Bounds().Dy(),
+			UpdatedAt:   logo.UpdatedAt,
+		})
+	})
+}
+
+// Handles GET /v1/qr/logo: returns the logo of the calling API key as uploaded
+func GetQRLogo(d QRDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logo, err := d.LogoRepo.Get(r.Context(), middleware.GetAPIKey(r.Context()).ID)
+		if errors.Is(err, domain.ErrNotFound) {
+			util.WriteError(w, http.StatusNotFound, "not_found", "no logo has been uploaded")
+			return
+		}
+		if err != nil {
+			d.Logger.ErrorContext(r.Context(), "get qr logo failed", "error", err)
+			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not load logo")
+			return
+		}
+		w.Header().Set("Content-Type", logo.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(logo.Image)))
+		w.Header().Set("Last-Modified", logo.UpdatedAt.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(logo.Image)
+	})
+}
+
+// Handles DELETE /v1/qr/logo: removes the logo of the calling API key
+func DeleteQRLogo(d QRDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyID := middleware.GetAPIKey(r.Context()).ID
+		err := d.LogoRepo.Delete(r.Context(), keyID)
+		if errors.Is(err, domain.ErrNotFound) {
+			util.WriteError(w, http.StatusNotFound, "not_found", "no logo has been uploaded")
+			return
+		}
+		if err != nil {
+			d.Logger.ErrorContext(r.Context(), "delete qr logo failed", "error", err)
+			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not delete logo")
+			return
+		}
+		d.Logger.InfoContext(r.Context(), "qr logo deleted", "api_key_id", keyID)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -1,26 +1,33 @@
 package handlers
 
 import (
-	"context"
-	"log"
-	"net"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/bot"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clicks"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clientip"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/util"
 )
 
 type RedirectDeps struct {
-	Config     config.Config
-	Logger     *log.Logger
-	LinksRepo  storage.LinksRepo
-	ClicksRepo storage.ClicksRepo
+	Config    config.Config
+	Logger    *slog.Logger
+	LinksRepo storage.LinksRepo
+	Hosts     *links.Hosts // maps the Host header to a custom domain's key namespace
+	Clicks    *clicks.Pipeline
+	Metrics   *observability.Metrics
 }
 
-// Root serves index.html on "/" and treats any other single-segment path as a key to redirect.
-func Root(webDir string, d RedirectDeps) http.Handler {
+// Root serves index.html on "/" and passes any other single-segment path to redirect.
+func Root(webDir string, redirect http.Handler) http.Handler {
 	// Prepare the file server for the frontend
 	index := Home(webDir)
 
@@ -39,19 +46,34 @@ func Root(webDir string, d RedirectDeps) http.Handler {
 			return
 		}
 
+		redirect.ServeHTTP(w, r)
+	})
+}
+
+// Redirect sends "/{key}" to the link's long URL and records the click. The key is
+// looked up in the namespace of the custom domain the request was sent to, or of
+// BASE_URL for any other host.
+func Redirect(d RedirectDeps) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 2. Extract Short Code
 		// Remove the leading slash (e.g., "/AbCd" -> "AbCd")
-		key := strings.TrimPrefix(path, "/")
+		key := strings.TrimPrefix(r.URL.Path, "/")
 		if key == "" {
 			http.NotFound(w, r)
 			return
 		}
+		observability.AddLogAttrs(r.Context(), slog.String("link_key", key))
 
 		// 3. Lookup Link in Database
 		// We use r.Context() so we don't waste resources if the user disconnects
-		link, err := d.LinksRepo.GetByKey(r.Context(), key)
+		host := d.Hosts.Domain(r.Context(), r.Host)
+		if host != "" {
+			observability.AddLogAttrs(r.Context(), slog.String("link_domain", host))
+		}
+		link, err := d.LinksRepo.GetByKey(r.Context(), host, key)
 		if err != nil {
 			// If link doesn't exist, return 404
+			d.Metrics.IncrementRedirect(observability.RedirectNotFound)
 			http.NotFound(w, r)
 			return
 		}
@@ -59,63 +81,45 @@ func Root(webDir string, d RedirectDeps) http.Handler {
 		// 4. Check Active/Expiration Status (USER STORY #5)
 		// We check if the link is manually disabled OR if the expiration date has passed
 		if link.IsDisabled {
+			d.Metrics.IncrementRedirect(observability.RedirectDisabled)
 			http.Error(w, "Link has been disabled", http.StatusGone) // 410 Gone
 			return
 		}
 		if link.ExpiresAt != nil && link.ExpiresAt.Before(time.Now().UTC()) {
+			d.Metrics.IncrementRedirect(observability.RedirectExpired)
 			http.Error(w, "Link has expired", http.StatusGone) // 410 Gone
 			return
 		}
 
-		// 5. Async Analytics (Fire and Forget)
-		// We capture request data BEFORE starting the goroutine to avoid race conditions
+		// 5. Async Analytics
+		// The pipeline flags repeat visits and stores the click off the request path
+		ip := clientip.FromContext(r.Context())
 		userAgent := r.UserAgent()
-		ip := getRealIP(r)
 		referer := r.Referer()
+		visitor := util.HashVisitor(ip, userAgent) // never store the raw IP
+		// Previewers and crawlers are still recorded, but flagged so stats can exclude them
+		isBot, _ := bot.Classify(r)
+		// QR codes from /v1/links/{key}/qr encode the short URL with ?src=qr
+		source := ""
+		if r.URL.Query().Get("src") == domain.ClickSourceQR {
+			source = domain.ClickSourceQR
+		}
 
-		go func() {
-			// CRITICAL: Create a new context.
-			// r.Context() dies when the ServeHTTP function returns.
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-
-			// Prepare the time
-			occurredAt := time.Now().UTC()
-
-			// Prepare country code (nil for now, since we don't have GeoIP yet)
-			var countryCode *string = nil
-
-			// Call Insert with exactly 7 arguments as defined in your repo
-			// We pass addresses (&ip, &userAgent, etc) because the repo expects *string
-			err := d.ClicksRepo.Insert(ctx, link.ID, occurredAt, &ip, countryCode, &userAgent, &referer)
-
-			if err != nil {
-				d.Logger.Printf("Analytics error (key=%s): %v", key, err)
-			}
-		}()
+		d.Clicks.Record(link, &domain.Click{
+			LinkID:      link.ID,
+			OccurredAt:  time.Now().UTC(),
+			VisitorHash: &visitor,
+			CountryCode: nil, // nil for now, since we don't have GeoIP yet
+			UserAgent:   &userAgent,
+			Referer:     &referer,
+			IsBot:       isBot,
+			Source:      source,
+		})
 
 		// 6. Perform Redirect
 		// 307 Temporary Redirect is preferred over 302 for API-like redirects
 		// to preserve the HTTP method, though 302 is also acceptable.
+		d.Metrics.IncrementRedirect(observability.RedirectHit)
 		http.Redirect(w, r, link.LongURL, http.StatusTemporaryRedirect)
 	})
 }
-
-// Helper: Extract the correct user IP (handles Proxies/Cloudflare)
-func getRealIP(r *http.Request) string {
-	// 1. Check X-Forwarded-For (Standard for proxies)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		parts := strings.Split(xff, ",")
-		return strings.TrimSpace(parts[0])
-	}
-	// 2. Check X-Real-IP
-	if xrip := r.Header.Get("X-Real-IP"); xrip != "" {
-		return xrip
-	}
-	// 3. Fallback to RemoteAddr (contains port, e.g. "127.0.0.1:8989")
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-	return host
-}
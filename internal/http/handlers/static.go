@@ -22,6 +22,18 @@ func Home(webDir string) http.Handler {
 	})
 }
 
+// Page serves a single HTML file from webDir (e.g. the dashboard)
+func Page(webDir, name string) http.Handler {
+	pagePath := filepath.Join(webDir, name)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := os.Stat(pagePath); err != nil {
+			http.Error(w, name+" not found", http.StatusNotFound)
+			return
+		}
+		http.ServeFile(w, r, pagePath)
+	})
+}
+
 // Optional: static files (CSS/JS/images) if you add them later under web/static
 func StaticDir(prefix, dir string) http.Handler {
 	fs := http.FileServer(http.Dir(dir))
@@ -2,38 +2,63 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/util"
 )
 
 type StatsDeps struct {
 	Config    config.Config
-	Logger    *log.Logger
+	Logger    *slog.Logger
 	LinksRepo storage.LinksRepo
 	StatsRepo storage.StatsRepo
 }
 
 type statsResponse struct {
-	Key           string        `json:"key"`
-	ShortURL      string        `json:"short_url"`
-	TotalClicks   int64         `json:"total_clicks"`
-	LastClickedAt *time.Time    `json:"last_clicked_at,omitempty"`
-	Daily         []dailyRecord `json:"daily"`
-	From          string        `json:"from"`
-	To            string        `json:"to"`
+	Key           string         `json:"key"`
+	ShortURL      string         `json:"short_url"`
+	TotalClicks   int64          `json:"total_clicks"`
+	DedupedClicks int64          `json:"deduplicated_clicks"`
+	QRClicks      int64          `json:"qr_clicks"`
+	LastClickedAt *time.Time     `json:"last_clicked_at,omitempty"`
+	IncludeBots   bool           `json:"include_bots"`
+	Granularity   string         `json:"granularity"`
+	Timezone      string         `json:"timezone"`
+	Series        []bucketRecord `json:"series"`
+	Daily         []dailyRecord  `json:"daily,omitempty"` // only for granularity=day
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+}
+type bucketRecord struct {
+	Start          time.Time `json:"start"`
+	Clicks         int64     `json:"clicks"`
+	UniqueVisitors *int64    `json:"unique_visitors,omitempty"` // only for whole UTC hours and days
 }
 type dailyRecord struct {
 	Day    string `json:"day"` // YYYY-MM-DD
 	Clicks int64  `json:"clicks"`
 }
 
-// Handles GET /v1/links/{key}/stats[?from=YYYY-MM-DD&to=YYYY-MM-DD]
+const dateLayout = "2006-01-02"
+
+// clicksPurgedMessage explains a domain.ErrClicksPurged rejection
+const clicksPurgedMessage = "the range needs raw clicks older than the retention period; " +
+	"start and end it on whole UTC hours and use a time zone with a whole-hour offset"
+
+// Handles GET /v1/links/{key}/stats[?from=&to=&granularity=hour|day|week|month&tz=Europe/Bucharest&includeBots=true]
+// from/to accept YYYY-MM-DD (local dates in tz, both inclusive) or RFC3339 (to is exclusive).
+// Bot, crawler and link previewer clicks are excluded unless includeBots is set.
 func Stats(d StatsDeps) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -54,70 +79,135 @@ func Stats(d StatsDeps) http.Handler {
 			return
 		}
 		key := parts[0]
+		observability.AddLogAttrs(r.Context(), slog.String("link_key", key))
 
-		link, err := d.LinksRepo.GetByKey(r.Context(), key)
-		if err != nil {
-			http.NotFound(w, r)
+		// Series options
+		q := r.URL.Query()
+		gran, ok := domain.ParseGranularity(q.Get("granularity"))
+		if !ok {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", "granularity must be one of hour, day, week, month")
 			return
 		}
+		includeBots := false
+		if v := q.Get("includeBots"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				util.WriteError(w, http.StatusBadRequest, "bad_request", "includeBots must be true or false")
+				return
+			}
+			includeBots = b
+		}
+		loc := time.UTC
+		if tz := q.Get("tz"); tz != "" {
+			l, err := time.LoadLocation(tz)
+			if err != nil || tz == "Local" {
+				util.WriteError(w, http.StatusBadRequest, "bad_request", "tz must be an IANA time zone name")
+				return
+			}
+			loc = l
+		}
 
 		// Date range
-		q := r.URL.Query()
-		var from, to time.Time
-		const layout = "2006-01-02"
-		today := time.Now().UTC().Truncate(24 * time.Hour)
+		today := domain.GranularityDay.Truncate(time.Now(), loc)
+		from := today.AddDate(0, 0, -30)
+		to := today.AddDate(0, 0, 1)
 		if fromStr := q.Get("from"); fromStr != "" {
-			f, err := time.ParseInLocation(layout, fromStr, time.UTC)
+			f, err := parseRangeBound(fromStr, loc, false)
 			if err != nil {
 				util.WriteError(w, http.StatusBadRequest, "bad_request", "invalid from date")
 				return
 			}
 			from = f
-		} else {
-			from = today.AddDate(0, 0, -30)
 		}
 		if toStr := q.Get("to"); toStr != "" {
-			t, err := time.ParseInLocation(layout, toStr, time.UTC)
+			t, err := parseRangeBound(toStr, loc, true)
 			if err != nil {
 				util.WriteError(w, http.StatusBadRequest, "bad_request", "invalid to date")
 				return
 			}
-			// make 'to' exclusive by adding a day at midnight
-			to = t.AddDate(0, 0, 1)
-		} else {
-			to = today.AddDate(0, 0, 1)
+			to = t
+		}
+		if !from.Before(to) {
+			util.WriteError(w, http.StatusBadRequest, "bad_request", "from must be before to")
+			return
+		}
+		buckets, ok := gran.Buckets(from, to, loc)
+		if !ok {
+			util.WriteError(w, http.StatusBadRequest, "range_too_large",
+				fmt.Sprintf("range spans more than %d %s buckets", domain.MaxSeriesBuckets, gran))
+			return
+		}
+
+		link, err := d.LinksRepo.GetByKey(r.Context(), linkDomain(r), key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
 		}
 
-		total, last, err := d.StatsRepo.Totals(r.Context(), link.ID)
+		totals, err := d.StatsRepo.Totals(r.Context(), link.ID, includeBots)
 		if err != nil {
-			d.Logger.Printf("stats totals error: %v", err)
+			d.Logger.ErrorContext(r.Context(), "stats totals failed", "error", err)
 			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not fetch stats")
 			return
 		}
-		days, err := d.StatsRepo.Daily(r.Context(), link.ID, from, to)
+		counts, err := d.StatsRepo.Series(r.Context(), link.ID, from, to, gran, loc, includeBots)
+		if errors.Is(err, domain.ErrClicksPurged) {
+			util.WriteError(w, http.StatusBadRequest, "clicks_purged", clicksPurgedMessage)
+			return
+		}
 		if err != nil {
-			d.Logger.Printf("stats daily error: %v", err)
+			d.Logger.ErrorContext(r.Context(), "stats series failed", "error", err)
 			util.WriteError(w, http.StatusInternalServerError, "server_error", "could not fetch stats")
 			return
 		}
-		out := make([]dailyRecord, 0, len(days))
-		for _, dc := range days {
-			out = append(out, dailyRecord{
-				Day:    dc.Day.Format(layout),
-				Clicks: dc.Clicks,
-			})
+
+		// Zero-fill so every bucket in the range is present
+		byStart := make(map[int64]storage.BucketCount, len(counts))
+		for _, c := range counts {
+			byStart[c.Start.Unix()] = c
+		}
+		series := make([]bucketRecord, 0, len(buckets))
+		var daily []dailyRecord
+		for _, b := range buckets {
+			c := byStart[b.Unix()]
+			rec := bucketRecord{Start: b, Clicks: c.Clicks}
+			if gran.CountsUniqueVisitors(b, from, to) {
+				rec.UniqueVisitors = &c.UniqueVisitors
+			}
+			series = append(series, rec)
+			if gran == domain.GranularityDay {
+				daily = append(daily, dailyRecord{Day: b.Format(dateLayout), Clicks: c.Clicks})
+			}
 		}
 
 		resp := statsResponse{
 			Key:           link.Key,
-			ShortURL:      d.Config.BaseURL + "/" + link.Key,
-			TotalClicks:   total,
-			LastClickedAt: last,
-			Daily:         out,
-			From:          from.Format(layout),
-			To:            to.AddDate(0, 0, -1).Format(layout), // inclusive end date
+			ShortURL:      links.ShortURL(d.Config.BaseURL, link),
+			TotalClicks:   totals.Clicks,
+			DedupedClicks: totals.DedupedClicks,
+			QRClicks:      totals.QRClicks,
+			LastClickedAt: totals.LastClickedAt,
+			IncludeBots:   includeBots,
+			Granularity:   string(gran),
+			Timezone:      loc.String(),
+			Series:        series,
+			Daily:         daily,
+			From:          from.In(loc).Format(time.RFC3339),
+			To:            to.In(loc).Format(time.RFC3339), // exclusive end
 		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(resp)
 	})
 }
+
+// parseRangeBound parses a from/to query value. A bare date is interpreted in loc;
+// for the upper bound it is inclusive, so the returned instant is the following midnight.
+func parseRangeBound(s string, loc *time.Location, upper bool) (time.Time, error) {
+	if t, err := time.ParseInLocation(dateLayout, s, loc); err == nil {
+		if upper {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/util"
+)
+
+// RequireAdminToken only lets through requests carrying "Authorization: Bearer <token>".
+// An empty token disables the wrapped endpoints entirely.
+func RequireAdminToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				util.WriteError(w, http.StatusForbidden, "admin_disabled", "ADMIN_TOKEN is not configured")
+				return
+			}
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				util.WriteError(w, http.StatusUnauthorized, "unauthorized", "admin token required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/apikey"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/util"
+)
+
+const apiKeyCtxKey ctxKey = "api_key"
+
+// APIKeyHeader is the alternative to "Authorization: Bearer sk_..."
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuth identifies the caller from an API key sent as "Authorization: Bearer sk_..."
+// or X-API-Key. Requests without a key pass through anonymously; an unknown or revoked
+// key is rejected. Bearer tokens without the key prefix (the admin token) are ignored.
+func APIKeyAuth(keys storage.APIKeysRepo, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw := r.Header.Get(APIKeyHeader)
+			if raw == "" {
+				if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && strings.HasPrefix(bearer, apikey.Prefix) {
+					raw = bearer
+				}
+			}
+			if raw == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !apikey.LooksValid(raw) {
+				unauthorized(w, "invalid API key")
+				return
+			}
+			k, err := keys.GetByHash(r.Context(), apikey.Hash(raw))
+			switch {
+			case errors.Is(err, domain.ErrNotFound):
+				unauthorized(w, "invalid API key")
+				return
+			case err != nil:
+				logger.ErrorContext(r.Context(), "api key lookup failed", "error", err)
+				util.WriteError(w, http.StatusInternalServerError, "server_error", "could not check API key")
+				return
+			case k.Revoked():
+				unauthorized(w, "API key revoked")
+				return
+			}
+
+			observability.AddLogAttrs(r.Context(), slog.Int64("auth_key_id", k.ID))
+			ctx := context.WithValue(r.Context(), apiKeyCtxKey, k)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireAPIKey rejects requests that APIKeyAuth did not identify
+func RequireAPIKey() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if GetAPIKey(r.Context()) == nil {
+				unauthorized(w, "API key required")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetAPIKey returns the key the request was authenticated with, or nil
+func GetAPIKey(ctx context.Context) *domain.APIKey {
+	k, _ := ctx.Value(apiKeyCtxKey).(*domain.APIKey)
+	return k
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	util.WriteError(w, http.StatusUnauthorized, "unauthorized", msg)
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clientip"
+)
+
+// ClientIP resolves the client address once per request and stores it in the context
+// (clientip.FromContext), for logging, rate limits and click analytics
+func ClientIP(res *clientip.Resolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := clientip.NewContext(r.Context(), res.Resolve(r.RemoteAddr, r.Header))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clientip"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
 )
 
 type statusRecorder struct {
@@ -26,17 +30,62 @@ func (sr *statusRecorder) Write(b []byte) (int, error) {
 	return n, err
 }
 
-func Logging(logger *log.Logger) func(http.Handler) http.Handler {
+// Unwrap lets http.ResponseController reach the underlying writer (flushing, deadlines)
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// Logging adds the route, client IP and link key (when routed by {key}) to the request log
+// attributes, writes one access log line per request and, when metrics is non-nil, records
+// the request count and latency under the mux route pattern (not the raw path, which
+// would give every short key its own series).
+func Logging(logger *slog.Logger, metrics *observability.Metrics) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			sr := &statusRecorder{ResponseWriter: w}
 
-			next.ServeHTTP(sr, r)
+			ctx := observability.WithLogAttrs(r.Context(),
+				slog.String("route", routeLabel(r)),
+				slog.String("client_ip", clientip.FromContext(r.Context())),
+			)
+			if key := r.PathValue("key"); key != "" {
+				observability.AddLogAttrs(ctx, slog.String("link_key", key))
+			}
+			next.ServeHTTP(sr, r.WithContext(ctx))
 
 			lat := time.Since(start)
-			logger.Printf(`req_id=%s method=%s path=%s status=%d bytes=%d latency_ms=%.3f`,
-				GetRequestID(r.Context()), r.Method, r.URL.Path, sr.status, sr.bytes, float64(lat.Microseconds())/1000.0)
+			status := sr.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(ctx, level, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int("bytes", sr.bytes),
+				slog.Float64("latency_ms", float64(lat.Microseconds())/1000.0),
+			)
+
+			if metrics != nil {
+				metrics.ObserveRequest(routeLabel(r), r.Method, status, lat)
+			}
 		})
 	}
 }
+
+// routeLabel returns the route pattern without its method prefix ("GET /v1/dashboard/clicks" -> "/v1/dashboard/clicks")
+func routeLabel(r *http.Request) string {
+	p := r.Pattern
+	if i := strings.IndexByte(p, ' '); i >= 0 {
+		p = p[i+1:]
+	}
+	if p == "" {
+		return "unmatched"
+	}
+	return p
+}
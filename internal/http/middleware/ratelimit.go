@@ -1,91 +1,46 @@
 package middleware
 
 import (
-	"net"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
-	"strings"
-	"sync"
+	"strconv"
 	"time"
-)
-
-// simple token-bucket per IP (in-memory)
-type bucket struct {
-	mu         sync.Mutex
-	tokens     float64
-	capacity   float64
-	fillRate   float64 // tokens per second
-	lastRefill time.Time
-}
-
-func newBucket(capacity int, window time.Duration) *bucket {
-	return &bucket{
-		tokens:     float64(capacity),
-		capacity:   float64(capacity),
-		fillRate:   float64(capacity) / window.Seconds(),
-		lastRefill: time.Now(),
-	}
-}
-
-func (b *bucket) allow() bool {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	now := time.Now()
-	elapsed := now.Sub(b.lastRefill).Seconds()
-	// Refill
-	b.tokens = minF(b.capacity, b.tokens+elapsed*b.fillRate)
-	b.lastRefill = now
-	if b.tokens >= 1.0 {
-		b.tokens -= 1.0
-		return true
-	}
-	return false
-}
-
-func minF(a, b float64) float64 {
-	if a < b {
-		return a
-	}
-	return b
-}
-
-type limiter struct {
-	mu      sync.Mutex
-	buckets map[string]*bucket
-	cap     int
-	win     time.Duration
-}
 
-func newLimiter(cap int, window time.Duration) *limiter {
-	return &limiter{
-		buckets: make(map[string]*bucket),
-		cap:     cap,
-		win:     window,
-	}
-}
-
-func (l *limiter) get(ip string) *bucket {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	if b, ok := l.buckets[ip]; ok {
-		return b
-	}
-	b := newBucket(l.cap, l.win)
-	l.buckets[ip] = b
-	return b
-}
-
-func RateLimitPerIP(cap int, window time.Duration) func(http.Handler) http.Handler {
-	l := newLimiter(cap, window)
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clientip"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/rate"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/util"
+)
 
+// RateLimit counts requests with l: per API key when APIKeyAuth identified one (under
+// the policy of its tier), per client IP otherwise. Limited responses carry
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers; rejections add
+// Retry-After. If the backend fails the request is let through: an unavailable counter
+// store should not take the API down with it.
+func RateLimit(l *rate.Limiter, logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := clientIP(r)
-			if ip == "" {
-				ip = "unknown"
+			client := rate.IPClient(clientip.FromContext(r.Context()))
+			if k := GetAPIKey(r.Context()); k != nil {
+				client = rate.KeyClient(k.ID, k.Tier)
 			}
-			if !l.get(ip).allow() {
-				w.Header().Set("Retry-After", window.String())
-				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			res, err := l.Allow(r.Context(), client)
+			if err != nil {
+				logger.ErrorContext(r.Context(), "rate limit check failed", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if res.Limit > 0 {
+				h := w.Header()
+				h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+				h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+				h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+				h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit, seconds(res.Window)))
+			}
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+				util.WriteError(w, http.StatusTooManyRequests, "rate_limited", "too many requests")
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -93,17 +48,7 @@ func RateLimitPerIP(cap int, window time.Duration) func(http.Handler) http.Handl
 	}
 }
 
-func clientIP(r *http.Request) string {
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		parts := strings.Split(xff, ",")
-		return strings.TrimSpace(parts[0])
-	}
-	if xrip := r.Header.Get("X-Real-IP"); xrip != "" {
-		return xrip
-	}
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-	return host
+// seconds rounds d up to whole seconds, as the headers count in seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
@@ -4,7 +4,10 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"log/slog"
 	"net/http"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
 )
 
 type ctxKey string
@@ -20,6 +23,7 @@ func RequestID() func(http.Handler) http.Handler {
 				reqID = newID()
 			}
 			ctx := context.WithValue(r.Context(), requestIDKey, reqID)
+			ctx = observability.WithLogAttrs(ctx, slog.String("request_id", reqID))
 			w.Header().Set(requestIDHeader, reqID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/tracing"
+)
+
+// Tracing starts a server span per request, continuing the caller's trace when a valid
+// W3C traceparent header is present. It must run after RequestID so the span carries
+// the request id; the trace id is echoed in the traceparent response header.
+func Tracing(t *tracing.Tracer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if t == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if sc, ok := tracing.ParseTraceparent(r.Header.Get(tracing.TraceparentHeader)); ok {
+				ctx = tracing.ContextWithRemote(ctx, sc)
+			}
+			route := routeLabel(r)
+			ctx, span := t.Start(ctx, r.Method+" "+route, tracing.KindServer,
+				tracing.Attr{Key: "http.request.method", Value: r.Method},
+				tracing.Attr{Key: "http.route", Value: route},
+				tracing.Attr{Key: "url.path", Value: r.URL.Path},
+				tracing.Attr{Key: "request.id", Value: GetRequestID(ctx)},
+			)
+			defer span.End()
+			if span != nil {
+				observability.AddLogAttrs(ctx, slog.String("trace_id", span.SpanContext().TraceID.String()))
+			}
+			w.Header().Set(tracing.TraceparentHeader, span.SpanContext().Traceparent())
+
+			sr := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(sr, r.WithContext(ctx))
+
+			status := sr.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttr("http.response.status_code", status)
+			if status >= 500 {
+				span.SetStatus(tracing.StatusError, fmt.Sprintf("HTTP %d", status))
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/openapi"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/util"
+)
+
+// ValidateRequest rejects requests whose parameters or body do not match the OpenAPI
+// document with 400 invalid_request, listing every violation in details
+func ValidateRequest(v *openapi.Validator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			violations, err := v.Validate(r)
+			switch {
+			case errors.Is(err, openapi.ErrBodyTooLarge):
+				util.WriteError(w, http.StatusRequestEntityTooLarge, "payload_too_large", err.Error())
+				return
+			case errors.Is(err, openapi.ErrUnsupportedMediaType):
+				util.WriteError(w, http.StatusUnsupportedMediaType, "unsupported_media_type", err.Error())
+				return
+			case err != nil:
+				util.WriteError(w, http.StatusBadRequest, "bad_request", "could not read request body")
+				return
+			}
+			if len(violations) > 0 {
+				details := make([]util.ErrorDetail, len(violations))
+				for i, v := range violations {
+					details[i] = util.ErrorDetail(v)
+				}
+				util.WriteJSON(w, http.StatusBadRequest, util.ErrorResponse{
+					Error:   "invalid_request",
+					Message: "request does not match the API schema",
+					Details: details,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,22 +1,42 @@
 package http
 
 import (
-	"log"
+	"log/slog"
 	stdhttp "net/http"
 	"path/filepath"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clicks"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clientip"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/health"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/http/handlers"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/http/middleware"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/openapi"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/qr"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/rate"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/postgres"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/traced"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/tracing"
 )
 
 type Deps struct {
-	Config config.Config
-	Logger *log.Logger
-	DB     *pgxpool.Pool
+	Config  config.Config
+	Logger  *slog.Logger
+	DB      *pgxpool.Pool
+	Clicks  *clicks.Pipeline
+	Metrics *observability.Metrics
+	Tracer  *tracing.Tracer // nil disables tracing
+	Health  *health.Checker
+	Limits  rate.Backend // rate limit counters; nil keeps them in memory
+
+	// Reloadable settings; nil uses those of Config
+	RateLimits *rate.Settings
+	Rules      *links.Rules
 }
 
 type Middleware func(stdhttp.Handler) stdhttp.Handler
@@ -30,37 +50,127 @@ func chain(h stdhttp.Handler, m ...Middleware) stdhttp.Handler {
 
 func NewRouter(d Deps) stdhttp.Handler {
 	mux := stdhttp.NewServeMux()
+	if d.Metrics == nil {
+		d.Metrics = observability.NewMetrics()
+	}
+	if d.Health == nil {
+		d.Health = health.NewChecker(health.Database(d.DB))
+	}
+	if d.Limits == nil {
+		d.Limits = rate.NewMemory(0)
+	}
+	if d.RateLimits == nil {
+		d.RateLimits = rate.NewSettings(d.Config.RateLimits)
+	}
+	if d.Rules == nil {
+		d.Rules = links.NewRules(d.Config.ReservedWords, d.Config.BlockedHosts)
+	}
 
 	global := []Middleware{
 		middleware.Recover(d.Logger),
+		middleware.HSTS(d.Config.HSTSMaxAge, d.Config.HSTSSubdomains, d.Config.HSTSPreload),
 		middleware.RequestID(),
-		middleware.Logging(d.Logger),
+		middleware.ClientIP(clientip.New(d.Config.TrustedProxies, d.Config.ClientIPHeader)),
+		middleware.Tracing(d.Tracer),
+		middleware.Logging(d.Logger, d.Metrics),
 	}
 
+	// /v1 requests are checked against the OpenAPI document before reaching handlers
+	doc, err := openapi.Load()
+	if err != nil {
+		panic(err) // embedded at build time
+	}
+	validate := middleware.ValidateRequest(openapi.NewValidator(doc))
+
 	// Repos
-	linksRepo := postgres.NewLinksRepo(d.DB, d.Config.KeyMinLen, d.Config.KeyMaxLen)
-	clicksRepo := postgres.NewClicksRepo(d.DB)
-	statsRepo := postgres.NewStatsRepo(d.DB)
+	linksRepo := traced.NewLinksRepo(postgres.NewLinksRepo(d.DB, d.Config.KeyOptions()), d.Tracer)
+	clicksRepo := traced.NewClicksRepo(postgres.NewClicksRepo(d.DB), d.Tracer)
+	statsRepo := traced.NewStatsRepo(postgres.NewStatsRepo(d.DB), d.Tracer)
+	dashboardRepo := traced.NewDashboardRepo(postgres.NewDashboardRepo(d.DB), d.Tracer)
+	apiKeysRepo := traced.NewAPIKeysRepo(postgres.NewAPIKeysRepo(d.DB), d.Tracer)
+	qrLogoRepo := traced.NewQRLogoRepo(postgres.NewQRLogoRepo(d.DB), d.Tracer)
+	domainsRepo := traced.NewDomainsRepo(postgres.NewDomainsRepo(d.DB), d.Tracer)
+
+	// API keys are optional on /v1 except where a key is required
+	auth := middleware.APIKeyAuth(apiKeysRepo, d.Logger)
+	api := append(global, auth, validate)
+	withKey := append(global, auth, middleware.RequireAPIKey(), validate)
+
+	// Per route limits, by API key (under its tier's policy) or by IP; after auth so the key is known
+	limit := func(route string) Middleware {
+		return middleware.RateLimit(rate.NewLimiter(d.Limits, route, d.RateLimits), d.Logger)
+	}
 
 	// Health
 	mux.Handle("/healthz", chain(handlers.Healthz(d.DB), global...))
+	mux.Handle("GET /livez", chain(handlers.Livez(d.Health), global...))
+	mux.Handle("GET /readyz", chain(handlers.Readyz(d.Health), global...))
+
+	// Prometheus scrape endpoint, admin only
+	mux.Handle("GET /metrics", chain(handlers.Metrics(d.Metrics), append(global, middleware.RequireAdminToken(d.Config.AdminToken))...))
 
 	// API
-	createDeps := handlers.LinkDeps{Config: d.Config, Logger: d.Logger, LinksRepo: linksRepo}
+	linkDeps := handlers.LinkDeps{Config: d.Config, Logger: d.Logger, LinksRepo: linksRepo, DomainsRepo: domainsRepo, Rules: d.Rules, Metrics: d.Metrics}
 	mux.Handle("/v1/links", chain(
-		handlers.CreateLink(createDeps),
-		append(global, middleware.RateLimitPerIP(d.Config.RateLimitCreate, d.Config.RateLimitWindow))...,
+		handlers.CreateLink(linkDeps),
+		append(global, auth, limit(rate.CreateLinks), validate)...,
+	))
+	mux.Handle("POST /v1/links/batch", chain(
+		handlers.BatchCreateLinks(linkDeps),
+		append(global, auth, middleware.RequireAPIKey(), limit(rate.BatchCreateLinks), validate)...,
 	))
+	mux.Handle("GET /v1/links/{key}", chain(handlers.GetLink(linkDeps), api...))
+	mux.Handle("PATCH /v1/links/{key}", chain(handlers.UpdateLink(linkDeps), withKey...))
 
 	statsDeps := handlers.StatsDeps{Config: d.Config, Logger: d.Logger, LinksRepo: linksRepo, StatsRepo: statsRepo}
-	mux.Handle("/v1/links/", chain(handlers.Stats(statsDeps), global...)) // handles /v1/links/{key}/stats
+	mux.Handle("/v1/links/", chain(handlers.Stats(statsDeps), append(global, auth, limit(rate.LinkStats), validate)...)) // handles /v1/links/{key}/stats
+
+	// QR codes of the short URL, ZIP exports of many, and the logo each key overlays on them
+	qrDeps := handlers.QRDeps{Config: d.Config, Logger: d.Logger, LinksRepo: linksRepo, LogoRepo: qrLogoRepo, Generator: qr.NewGenerator()}
+	mux.Handle("GET /v1/links/{key}/qr", chain(handlers.QRCode(qrDeps), api...))
+	mux.Handle("POST /v1/qr/export", chain(handlers.ExportQRCodes(qrDeps), withKey...))
+	mux.Handle("GET /v1/qr/logo", chain(handlers.GetQRLogo(qrDeps), withKey...))
+	mux.Handle("PUT /v1/qr/logo", chain(handlers.PutQRLogo(qrDeps), withKey...))
+	mux.Handle("DELETE /v1/qr/logo", chain(handlers.DeleteQRLogo(qrDeps), withKey...))
+
+	// Raw click export
+	clicksDeps := handlers.ClicksDeps{Config: d.Config, Logger: d.Logger, LinksRepo: linksRepo, DomainsRepo: domainsRepo, ClicksRepo: clicksRepo}
+	mux.Handle("GET /v1/links/{key}/clicks", chain(handlers.ExportClicks(clicksDeps), withKey...))
+
+	// Click erasure (per link / GDPR per visitor), admin only
+	admin := append(global, middleware.RequireAdminToken(d.Config.AdminToken), validate)
+	mux.Handle("DELETE /v1/links/{key}/clicks", chain(handlers.DeleteLinkClicks(clicksDeps), admin...))
+	mux.Handle("DELETE /v1/clicks", chain(handlers.DeleteVisitorClicks(clicksDeps), admin...))
+
+	// Dashboard: aggregates across the caller's links, and the page that renders them
+	dashDeps := handlers.DashboardDeps{Config: d.Config, Logger: d.Logger, LinksRepo: linksRepo, DashboardRepo: dashboardRepo}
+	mux.Handle("GET /v1/dashboard/top-links", chain(handlers.DashboardTopLinks(dashDeps), withKey...))
+	mux.Handle("GET /v1/dashboard/clicks", chain(handlers.DashboardClicks(dashDeps), withKey...))
+	mux.Handle("GET /v1/dashboard/referrers", chain(handlers.DashboardTopValues(dashDeps, storage.DimensionReferrer), withKey...))
+	mux.Handle("GET /v1/dashboard/countries", chain(handlers.DashboardTopValues(dashDeps, storage.DimensionCountry), withKey...))
+	mux.Handle("GET /v1/dashboard/new-links", chain(handlers.DashboardNewLinks(dashDeps), withKey...))
+	mux.Handle("GET /dashboard", chain(handlers.Page(d.Config.WebDir, "dashboard.html"), global...))
+
+	// API description and the page that renders it
+	mux.Handle("GET /v1/openapi.json", chain(handlers.OpenAPI(openapi.Spec(), d.Config.BaseURL), global...))
+	mux.Handle("GET /docs", chain(handlers.Page(d.Config.WebDir, "docs.html"), global...))
 
 	// Static assets (optional)
 	// mux.Handle("/static/", chain(handlers.StaticDir("/static/", filepath.Join(d.Config.WebDir, "static")), global...))
 
-	// Root: serve UI at "/" and redirect for "/{key}"
-	redirDeps := handlers.RedirectDeps{Config: d.Config, Logger: d.Logger, LinksRepo: linksRepo, ClicksRepo: clicksRepo}
-	mux.Handle("/", chain(handlers.Root(d.Config.WebDir, redirDeps), global...))
+	// Root: serve UI at "/" and redirect for "/{key}", in the key namespace of the Host's custom domain
+	hosts := links.NewHosts(domainsRepo, d.Logger)
+	d.Metrics.CounterFunc("domain_cache_hits_total", "Custom domain lookups answered from a fresh cache.", func() float64 {
+		hits, _ := hosts.CacheStats()
+		return float64(hits)
+	})
+	d.Metrics.CounterFunc("domain_cache_misses_total", "Custom domain lookups that found the cache empty or stale.", func() float64 {
+		_, misses := hosts.CacheStats()
+		return float64(misses)
+	})
+	redirDeps := handlers.RedirectDeps{Config: d.Config, Logger: d.Logger, LinksRepo: linksRepo, Hosts: hosts, Clicks: d.Clicks, Metrics: d.Metrics}
+	redirect := chain(handlers.Redirect(redirDeps), limit(rate.Redirect))
+	mux.Handle("/", chain(handlers.Root(d.Config.WebDir, redirect), global...))
 
 	_ = filepath.Separator // avoid unused import if StaticDir is commented
 
@@ -1,20 +1,62 @@
 package http
 
 import (
+	"crypto/tls"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
 )
 
-func NewServer(cfg config.Config, logger *log.Logger, handler http.Handler) *http.Server {
+// NewServer returns the API server on PORT. With tlsConfig set it is meant for
+// ListenAndServeTLS("", ""), the certificates coming from tlsConfig.
+func NewServer(cfg config.Config, logger *slog.Logger, handler http.Handler, tlsConfig *tls.Config) *http.Server {
 	addr := fmt.Sprintf(":%d", cfg.Port)
 	return &http.Server{
 		Addr:         addr,
 		Handler:      handler,
+		TLSConfig:    tlsConfig,
 		ReadTimeout:  cfg.ReadTimeout,
 		WriteTimeout: cfg.WriteTimeout,
 		IdleTimeout:  cfg.IdleTimeout,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
 	}
 }
+
+// NewRedirectServer returns the plain HTTP server on HTTP_REDIRECT_PORT, which sends
+// every request to the same host and path over HTTPS on PORT
+func NewRedirectServer(cfg config.Config, logger *slog.Logger) *http.Server {
+	return &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.HTTPRedirectPort),
+		Handler:      redirectToHTTPS(cfg.Port),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+}
+
+// redirectToHTTPS answers with 308, so clients repeat the method and body over HTTPS
+func redirectToHTTPS(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		if host == "" {
+			http.Error(w, "missing Host header", http.StatusBadRequest)
+			return
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6 literal
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
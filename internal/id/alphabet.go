@@ -0,0 +1,94 @@
+package id
+
+import (
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// Alphabet names (KEY_ALPHABET, key_alphabet)
+const (
+	Base62    = "base62"    // 0-9, A-Z, a-z: the shortest keys
+	Crockford = "crockford" // Crockford's base32: digits and upper case letters without I, L, O and U
+	Lower     = "lower"     // lower case letters and digits without 0/o, 1/l/i: easy to read out and type
+	Words     = "words"     // dash-separated English words, e.g. brave-otter-lamp
+)
+
+// Alphabets lists the alphabet names
+var Alphabets = []string{Base62, Crockford, Lower, Words}
+
+// Alphabet is the set of symbols keys are made of. A key of length n has n symbols;
+// for Words that is n words.
+type Alphabet struct {
+	Name    string
+	symbols []string
+	sep     string
+	minLen  int // fixed key lengths; 0 uses the configured ones
+	maxLen  int
+}
+
+var alphabets = map[string]*Alphabet{
+	Base62:    {Name: Base62, symbols: strings.Split(alphabet, "")},
+	Crockford: {Name: Crockford, symbols: strings.Split("0123456789ABCDEFGHJKMNPQRSTVWXYZ", "")},
+	Lower:     {Name: Lower, symbols: strings.Split("23456789abcdefghjkmnpqrstuvwxyz", "")},
+	// 3 to 4 words of at most 7 letters stay within the 32 characters of a key
+	Words: {Name: Words, symbols: words, sep: "-", minLen: 3, maxLen: 4},
+}
+
+// AlphabetNamed returns the alphabet called name, or nil
+func AlphabetNamed(name string) *Alphabet {
+	return alphabets[name]
+}
+
+// IsAlphabet reports whether name is one of Alphabets
+func IsAlphabet(name string) bool {
+	return slices.Contains(Alphabets, name)
+}
+
+// lengths returns the key lengths used with the configured ones
+func (a *Alphabet) lengths(minLen, maxLen int) (int, int) {
+	if a.minLen > 0 {
+		return a.minLen, a.maxLen
+	}
+	return minLen, maxLen
+}
+
+func (a *Alphabet) size() int64 {
+	return int64(len(a.symbols))
+}
+
+// join builds the key of the given symbol indexes
+func (a *Alphabet) join(digits []int64) string {
+	parts := make([]string, len(digits))
+	for i, d := range digits {
+		parts[i] = a.symbols[d]
+	}
+	return strings.Join(parts, a.sep)
+}
+
+// encode writes n in the alphabet, left-padded with its first symbol to length symbols
+func (a *Alphabet) encode(n int64, length int) string {
+	var digits []int64
+	for n > 0 {
+		digits = append(digits, n%a.size())
+		n /= a.size()
+	}
+	for len(digits) < length {
+		digits = append(digits, 0)
+	}
+	slices.Reverse(digits)
+	return a.join(digits)
+}
+
+// digits returns n symbols taken from the big-endian number b
+func (a *Alphabet) digits(b []byte, n int) string {
+	v := new(big.Int).SetBytes(b)
+	size := big.NewInt(a.size())
+	digit := new(big.Int)
+	out := make([]int64, n)
+	for i := range out {
+		v.DivMod(v, size, digit)
+		out[i] = digit.Int64()
+	}
+	return a.join(out)
+}
@@ -0,0 +1,57 @@
+package id
+
+import "strings"
+
+// DefaultBanned are substrings no generated key contains, next to the configured ones.
+// Short words that are common inside harmless ones (ass, tit) are left out.
+var DefaultBanned = []string{
+	"anal", "anus", "bitch", "boob", "cock", "coon", "cum", "cunt", "dick", "dildo", "fag",
+	"fuck", "jizz", "kike", "kkk", "nazi", "nigg", "penis", "porn", "puss", "rape", "shit",
+	"slut", "spic", "twat", "vagina", "wank", "whore",
+}
+
+// leet undoes the digit for letter substitutions that hide a word, e.g. sh1t
+var leet = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b")
+
+// Filter rejects keys that contain a banned word, ignoring case, separators and digits
+// standing in for letters. A nil *Filter allows every key.
+type Filter struct {
+	banned []string
+}
+
+// NewFilter bans DefaultBanned and extra
+func NewFilter(extra []string) *Filter {
+	f := &Filter{}
+	for _, w := range append(DefaultBanned, extra...) {
+		if w = normalize(w); w != "" {
+			f.banned = append(f.banned, w)
+		}
+	}
+	return f
+}
+
+// Allows reports whether key contains no banned word
+func (f *Filter) Allows(key string) bool {
+	if f == nil {
+		return true
+	}
+	k := normalize(key)
+	for _, w := range f.banned {
+		if strings.Contains(k, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize lower-cases s, reads digits as the letters they resemble and drops
+// everything else but letters
+func normalize(s string) string {
+	s = leet.Replace(strings.ToLower(s))
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -2,6 +2,7 @@ package id
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
@@ -59,12 +60,6 @@ func Decode(s string) (int64, error) {
 	return result, nil
 }
 
-// Generator generates unique IDs
-type Generator struct {
-	minLen int
-	maxLen int
-}
-
 func Generate(n int) string {
 	result := make([]byte, n)
 	for i := 0; i < n; i++ {
@@ -79,36 +74,66 @@ func Generate(n int) string {
 	return string(result)
 }
 
-// NewGenerator creates a new ID generator
-func NewGenerator(minLen, maxLen int) *Generator {
+// maxFilteredTries bounds the keys drawn to find one the filter allows
+const maxFilteredTries = 100
+
+var errFiltered = errors.New("no generated key passed the banned words filter")
+
+// Generator generates keys of minLen to maxLen symbols of an alphabet, skipping those
+// its filter rejects
+type Generator struct {
+	alphabet *Alphabet
+	minLen   int
+	maxLen   int
+	filter   *Filter
+}
+
+// NewGenerator creates a key generator for a (Base62 when nil), which may fix its own
+// lengths instead of minLen and maxLen; f may be nil
+func NewGenerator(a *Alphabet, minLen, maxLen int, f *Filter) *Generator {
+	if a == nil {
+		a = alphabets[Base62]
+	}
+	minLen, maxLen = a.lengths(minLen, maxLen)
 	return &Generator{
-		minLen: minLen,
-		maxLen: maxLen,
+		alphabet: a,
+		minLen:   minLen,
+		maxLen:   maxLen,
+		filter:   f,
 	}
 }
 
-// GenerateKey generates a random key of specified length
+// GenerateKey generates a random key of specified length that the filter allows
 func (g *Generator) GenerateKey(length int) (string, error) {
 	if length < g.minLen || length > g.maxLen {
 		return "", fmt.Errorf("length must be between %d and %d", g.minLen, g.maxLen)
 	}
 
-	result := make([]byte, length)
-	for i := 0; i < length; i++ {
-		num, err := rand.Int(rand.Reader, big.NewInt(base))
-		if err != nil {
-			return "", fmt.Errorf("random generation failed: %w", err)
+	digits := make([]int64, length)
+	for range maxFilteredTries {
+		for i := range digits {
+			num, err := rand.Int(rand.Reader, big.NewInt(g.alphabet.size()))
+			if err != nil {
+				return "", fmt.Errorf("random generation failed: %w", err)
+			}
+			digits[i] = num.Int64()
+		}
+		if key := g.alphabet.join(digits); g.Allows(key) {
+			return key, nil
 		}
-		result[i] = alphabet[num.Int64()]
 	}
+	return "", errFiltered
+}
 
-	return string(result), nil
+// GenerateFromID generates a deterministic key from a numeric ID, padded to length
+// symbols (at least minLen)
+func (g *Generator) GenerateFromID(id int64, length int) string {
+	return g.alphabet.encode(id, max(length, g.minLen))
 }
 
-// GenerateFromID generates a deterministic key from a numeric ID
-func (g *Generator) GenerateFromID(id int64) string {
-	encoded := Encode(id)
-	return Pad(encoded, g.minLen)
+// Allows reports whether the filter allows key
+func (g *Generator) Allows(key string) bool {
+	return g.filter.Allows(key)
 }
 
 // IsValidKey checks if a key is valid
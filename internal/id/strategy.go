@@ -0,0 +1,194 @@
+package id
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// Key strategies (KEY_STRATEGY)
+const (
+	Random  = "random"  // random keys, longer after repeated collisions
+	Counter = "counter" // a sequence number through a keyed permutation: unique, not guessable in order
+	Hash    = "hash"    // derived from the URL, so every instance picks the same key for it
+)
+
+// Strategies lists the strategy names
+var Strategies = []string{Random, Counter, Hash}
+
+const (
+	randomTriesPerLen = 3 // random keys tried at each length before a longer one
+	hashSaltedTries   = 3 // salted hashes tried at the maximum length
+	feistelRounds     = 4
+)
+
+// Options select the keys of system links
+type Options struct {
+	Strategy string // one of Strategies; Random when empty
+	Alphabet string // one of Alphabets; Base62 when empty
+	MinLen   int    // in symbols, unless the alphabet fixes its lengths
+	MaxLen   int
+	Seed     string   // keys the Counter permutation; changing it reorders future keys
+	Banned   []string // words keys may not contain, next to DefaultBanned
+}
+
+// Sequence returns a number it never returned before, starting at 1 (a database sequence)
+type Sequence func(ctx context.Context) (int64, error)
+
+// Strategy picks the keys of system links. Key is called with attempt 0, 1, ... until
+// a key is free, and returns a different key for each attempt.
+type Strategy interface {
+	Key(ctx context.Context, url string, attempt int) (string, error)
+}
+
+// NewStrategy returns the strategy of o. Random and Hash switch to Counter keys once
+// their own attempts are used up; those never repeat, so a free key is always found.
+// Keys the banned words filter rejects are never returned: they are drawn again.
+func NewStrategy(o Options, seq Sequence) Strategy {
+	g := NewGenerator(AlphabetNamed(o.Alphabet), o.MinLen, o.MaxLen, NewFilter(o.Banned))
+	counter := newCounter(g, o.Seed, seq)
+	lengths := g.maxLen - g.minLen + 1
+	switch o.Strategy {
+	case Counter:
+		return counter
+	case Hash:
+		return fallback{first: hashKeys{g: g}, tries: lengths + hashSaltedTries, then: counter}
+	default:
+		return fallback{first: randomKeys{g: g}, tries: lengths * randomTriesPerLen, then: counter}
+	}
+}
+
+type fallback struct {
+	first Strategy
+	tries int
+	then  Strategy
+}
+
+func (f fallback) Key(ctx context.Context, url string, attempt int) (string, error) {
+	if attempt < f.tries {
+		return f.first.Key(ctx, url, attempt)
+	}
+	return f.then.Key(ctx, url, attempt-f.tries)
+}
+
+// randomKeys tries randomTriesPerLen keys of each length from min to max
+type randomKeys struct {
+	g *Generator
+}
+
+func (r randomKeys) Key(_ context.Context, _ string, attempt int) (string, error) {
+	return r.g.GenerateKey(min(r.g.minLen+attempt/randomTriesPerLen, r.g.maxLen))
+}
+
+// hashKeys tries longer and longer prefixes of the URL's digest, then digests salted
+// with the attempt. A banned prefix is replaced by one of a digest salted further.
+type hashKeys struct {
+	g *Generator
+}
+
+func (h hashKeys) Key(_ context.Context, url string, attempt int) (string, error) {
+	n, s := h.g.minLen+attempt, url
+	if n > h.g.maxLen {
+		n, s = h.g.maxLen, url+"#"+strconv.Itoa(attempt)
+	}
+	for i := range maxFilteredTries {
+		if i > 0 {
+			s += "!"
+		}
+		sum := sha256.Sum256([]byte(s))
+		if key := h.g.alphabet.digits(sum[:], n); h.g.Allows(key) {
+			return key, nil
+		}
+	}
+	return "", errFiltered
+}
+
+// counter turns sequence numbers into keys: with an alphabet of s symbols, the first
+// s^min numbers into keys of min symbols, the next s^(min+1) into keys of min+1, and so
+// on. Within a length the number goes through a Feistel permutation keyed by the seed,
+// so consecutive links get unrelated keys while no two numbers share one. Numbers whose
+// key the filter rejects are skipped.
+type counter struct {
+	g    *Generator
+	keys [feistelRounds]uint64
+	seq  Sequence
+}
+
+func newCounter(g *Generator, seed string, seq Sequence) *counter {
+	c := &counter{g: g, seq: seq}
+	sum := sha256.Sum256([]byte("counter:" + seed))
+	for i := range c.keys {
+		c.keys[i] = binary.BigEndian.Uint64(sum[i*8:])
+	}
+	return c
+}
+
+func (c *counter) Key(ctx context.Context, _ string, _ int) (string, error) {
+	for range maxFilteredTries {
+		n, err := c.seq(ctx)
+		if err != nil {
+			return "", fmt.Errorf("next key number: %w", err)
+		}
+		key, err := c.key(n - 1)
+		if err != nil || c.g.Allows(key) {
+			return key, err
+		}
+	}
+	return "", errFiltered
+}
+
+func (c *counter) key(n int64) (string, error) {
+	symbols := c.g.alphabet.size()
+	size := int64(1)
+	for range c.g.minLen {
+		if size > math.MaxInt64/symbols {
+			return "", errors.New("counter keys do not fit this KEY_MIN_LEN")
+		}
+		size *= symbols
+	}
+	for l := c.g.minLen; l <= c.g.maxLen; l++ {
+		if n < size {
+			return c.g.GenerateFromID(c.permute(n, size), l), nil
+		}
+		n -= size
+		if size > math.MaxInt64/symbols {
+			break
+		}
+		size *= symbols
+	}
+	return "", errors.New("counter keys exhausted; raise KEY_MAX_LEN")
+}
+
+// permute maps n in [0, size) to [0, size) one to one, by cycle walking a Feistel
+// network over the smallest even number of bits holding size-1
+func (c *counter) permute(n, size int64) int64 {
+	width := bits.Len64(uint64(size - 1))
+	width += width % 2
+	half := uint(width / 2)
+	mask := uint64(1)<<half - 1
+	x := uint64(n)
+	for {
+		l, r := x>>half, x&mask
+		for _, k := range c.keys {
+			l, r = r, l^(mix(r^k)&mask)
+		}
+		x = l<<half | r
+		if x < uint64(size) {
+			return int64(x)
+		}
+	}
+}
+
+// mix is the splitmix64 finalizer
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
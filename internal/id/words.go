@@ -0,0 +1,33 @@
+package id
+
+// words are the symbols of the Words alphabet: 256 short, common and inoffensive
+// English words, so each word of a key carries 8 bits
+var words = []string{
+	"able", "acid", "acorn", "actor", "agent", "album", "alert", "alpine", "amber", "apple",
+	"apron", "arch", "arena", "atlas", "attic", "aunt", "autumn", "badge", "bagel", "baker",
+	"bamboo", "barn", "basil", "basket", "beach", "bean", "bear", "beaver", "bell", "bike",
+	"birch", "bison", "blanket", "blaze", "blue", "boat", "bonus", "book", "bottle", "bowl",
+	"brave", "bread", "brick", "bridge", "brook", "broom", "bucket", "bugle", "cabin",
+	"cactus", "camel", "camera", "candle", "canvas", "canyon", "carbon", "card", "carpet",
+	"carrot", "castle", "cedar", "chair", "chalk", "cherry", "chess", "cider", "circle",
+	"citrus", "clay", "cliff", "cloud", "clover", "coast", "cobalt", "comet", "copper",
+	"coral", "cotton", "cover", "coyote", "crane", "crayon", "creek", "crisp", "crown",
+	"cube", "curve", "daisy", "dance", "delta", "dial", "diary", "dingo", "dolphin", "donkey",
+	"dragon", "drum", "eagle", "easel", "elbow", "elder", "ember", "emerald", "falcon",
+	"fern", "ferry", "fiddle", "fig", "finch", "flag", "flame", "forest", "fossil", "fox",
+	"frost", "garden", "gecko", "ginger", "glacier", "glove", "goat", "gold", "goose",
+	"gravel", "green", "guitar", "hammer", "harp", "hazel", "heron", "hill", "honey", "hotel",
+	"igloo", "indigo", "iris", "ivory", "jacket", "jade", "jazz", "jewel", "jungle", "kayak",
+	"kettle", "koala", "ladder", "lagoon", "lake", "lantern", "lemon", "lilac", "lily",
+	"linen", "lion", "lizard", "llama", "locket", "lotus", "lunar", "magnet", "mango",
+	"marble", "meadow", "melon", "mint", "mocha", "moon", "mosaic", "moss", "nectar",
+	"needle", "nest", "nickel", "north", "nutmeg", "oak", "oasis", "olive", "onion", "opal",
+	"orange", "orchid", "otter", "owl", "oyster", "panda", "paper", "parrot", "pasta",
+	"pearl", "pebble", "pepper", "piano", "pickle", "pilot", "pine", "planet", "plum", "pond",
+	"poppy", "prism", "pumpkin", "quill", "rabbit", "radar", "radio", "reef", "ribbon",
+	"river", "robin", "rose", "ruby", "saddle", "saffron", "salmon", "sand", "scarf", "shell",
+	"sketch", "sky", "sleigh", "snow", "solar", "spark", "spruce", "squash", "stone", "storm",
+	"sugar", "summit", "sun", "swan", "table", "tango", "tea", "teapot", "timber", "toast",
+	"topaz", "torch", "tundra", "turtle", "velvet", "violet", "wagon", "walnut", "walrus",
+	"water", "willow", "window", "winter", "wizard", "yacht", "yarn", "yogurt", "zebra",
+}
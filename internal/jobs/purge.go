@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+)
+
+// purgePause is the delay between batches so the purge never monopolizes the database
+const purgePause = 100 * time.Millisecond
+
+// PurgeClicks returns a task that deletes raw clicks older than retention in batches of batchSize.
+// Rollups are kept forever. A zero retention disables the task.
+func PurgeClicks(repo storage.ClicksRepo, logger *slog.Logger, retention time.Duration, batchSize int, interval time.Duration) Task {
+	if retention <= 0 || batchSize <= 0 {
+		interval = 0
+	}
+	return Task{
+		Name:     "purge_clicks",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			cutoff := time.Now().UTC().Add(-retention)
+			var total int64
+			for {
+				n, err := repo.PurgeBefore(ctx, cutoff, batchSize)
+				if err != nil {
+					return err
+				}
+				total += n
+				if n < int64(batchSize) {
+					break
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(purgePause):
+				}
+			}
+			if total > 0 {
+				logger.Info("purged raw clicks", "count", total, "before", cutoff.Format(time.RFC3339))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,20 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+)
+
+// PurgeRateLimits returns a task that deletes expired shared rate limit counters
+func PurgeRateLimits(repo storage.RateLimitRepo, interval time.Duration) Task {
+	return Task{
+		Name:     "purge_rate_limits",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			_, err := repo.PurgeExpired(ctx, time.Now().UTC())
+			return err
+		},
+	}
+}
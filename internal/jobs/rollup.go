@@ -0,0 +1,19 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+)
+
+// Rollup returns a task that adds newly inserted raw clicks to the hourly and daily rollup tables
+func Rollup(repo storage.RollupsRepo, interval time.Duration) Task {
+	return Task{
+		Name:     "rollup",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			return repo.Rollup(ctx, time.Now().UTC())
+		},
+	}
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Task is a unit of background work run periodically by the Scheduler
+type Task struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs tasks on their own tickers until its context is cancelled
+type Scheduler struct {
+	logger *slog.Logger
+	tasks  []Task
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler with no tasks
+func NewScheduler(logger *slog.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Add registers a task. Tasks with a non-positive interval are ignored.
+func (s *Scheduler) Add(t Task) {
+	if t.Interval <= 0 {
+		return
+	}
+	s.tasks = append(s.tasks, t)
+}
+
+// Start launches every task in its own goroutine. Each task runs once immediately,
+// then on every tick; a run never overlaps the previous one.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, t := range s.tasks {
+		s.wg.Add(1)
+		go func(t Task) {
+			defer s.wg.Done()
+			s.loop(ctx, t)
+		}(t)
+	}
+}
+
+// Wait blocks until all tasks have stopped after the context passed to Start is cancelled
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, t Task) {
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if err := t.Run(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("job failed", "job", t.Name, "error", err)
+		} else if err == nil {
+			s.logger.Debug("job done", "job", t.Name, "duration_ms", time.Since(start).Milliseconds())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
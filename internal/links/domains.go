@@ -0,0 +1,117 @@
+package links
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+)
+
+// hostsTTL is how long Hosts keeps the registered domains before reloading them
+const hostsTTL = 30 * time.Second
+
+// ShortURL returns the public URL of a link: under baseURL (BASE_URL), or on its
+// custom domain with the scheme of baseURL
+func ShortURL(baseURL string, l *domain.Link) string {
+	if l.Domain == "" {
+		return baseURL + "/" + l.Key
+	}
+	scheme := "https"
+	if u, err := url.Parse(baseURL); err == nil && u.Scheme != "" {
+		scheme = u.Scheme
+	}
+	return scheme + "://" + l.Domain + "/" + l.Key
+}
+
+// Hosts tells which custom domain a redirect's Host header addresses. The registered
+// domains are cached, so a domain added on one instance serves redirects on all of
+// them within hostsTTL. Lookups never wait for the database once the cache is loaded:
+// a stale cache keeps answering while one request reloads it in the background.
+type Hosts struct {
+	repo   storage.DomainsRepo
+	logger *slog.Logger
+
+	cache     atomic.Pointer[hostSet]
+	reloading atomic.Bool
+	first     sync.Mutex // held by the requests waiting for the first load
+
+	hits, misses atomic.Uint64
+}
+
+type hostSet struct {
+	hosts  map[string]bool
+	loaded time.Time
+}
+
+func NewHosts(repo storage.DomainsRepo, logger *slog.Logger) *Hosts {
+	return &Hosts{repo: repo, logger: logger}
+}
+
+// Domain returns the registered domain a Host header names, or "" (BASE_URL's
+// namespace) for any other host
+func (h *Hosts) Domain(ctx context.Context, hostHeader string) string {
+	host := domain.NormalizeHost(hostHeader)
+
+	set := h.cache.Load()
+	switch {
+	case set == nil:
+		h.misses.Add(1)
+		set = h.load(ctx)
+	case time.Since(set.loaded) < hostsTTL:
+		h.hits.Add(1)
+	default:
+		h.misses.Add(1)
+		if h.reloading.CompareAndSwap(false, true) {
+			go func() {
+				defer h.reloading.Store(false)
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), hostsTTL)
+				defer cancel()
+				h.reload(ctx, set)
+			}()
+		}
+	}
+	if set.hosts[host] {
+		return host
+	}
+	return ""
+}
+
+// CacheStats returns how many lookups were answered from a fresh cache (hits) and how
+// many found it empty or older than hostsTTL (misses)
+func (h *Hosts) CacheStats() (hits, misses uint64) {
+	return h.hits.Load(), h.misses.Load()
+}
+
+// load waits for the cache to be filled, filling it once for all concurrent callers
+func (h *Hosts) load(ctx context.Context) *hostSet {
+	h.first.Lock()
+	defer h.first.Unlock()
+	if set := h.cache.Load(); set != nil {
+		return set
+	}
+	return h.reload(ctx, nil)
+}
+
+// reload replaces the cache; on failure the domains of prev are kept until the next try
+func (h *Hosts) reload(ctx context.Context, prev *hostSet) *hostSet {
+	set := &hostSet{loaded: time.Now()}
+	if prev != nil {
+		set.hosts = prev.hosts
+	}
+	ds, err := h.repo.List(ctx)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "loading custom domains failed", "error", err)
+	} else {
+		set.hosts = make(map[string]bool, len(ds))
+		for _, d := range ds {
+			set.hosts[d.Host] = true
+		}
+	}
+	h.cache.Store(set)
+	return set
+}
@@ -0,0 +1,317 @@
+// Package links holds the link operations shared by the REST and gRPC APIs: request
+// validation, defaults and the repository calls behind them. Each API maps the Kind of
+// a returned *Error to its own status codes.
+package links
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/id"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+)
+
+// MaxDedupeWindow bounds per-link de-duplication windows (state is held in memory)
+const MaxDedupeWindow = 24 * time.Hour
+
+// MaxBatch bounds the links created by one batch request
+const MaxBatch = 100
+
+type Kind int
+
+const (
+	Invalid            Kind = iota // the request itself is wrong
+	NotFound                       // no link with that key
+	Conflict                       // the alias is taken
+	FailedPrecondition             // not allowed for this link
+	Gone                           // the link is disabled or expired
+	Forbidden                      // the caller may not do this
+	Internal
+)
+
+// Error is a failed link operation as reported to API clients
+type Error struct {
+	Kind    Kind
+	Code    string // API error code, e.g. alias_in_use
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func invalid(code, msg string) *Error {
+	return &Error{Kind: Invalid, Code: code, Message: msg}
+}
+
+var (
+	errNotFound     = &Error{Kind: NotFound, Code: "not_found", Message: "link not found"}
+	errCreateFailed = &Error{Kind: Internal, Code: "server_error", Message: "could not create link"}
+	errLoadFailed   = &Error{Kind: Internal, Code: "server_error", Message: "could not load link"}
+	errDomainFailed = &Error{Kind: Internal, Code: "server_error", Message: "could not load domain"}
+	errBlockedURL   = invalid("blocked_url", "links to this host are not allowed")
+	errNotOwner     = &Error{Kind: Forbidden, Code: "not_link_owner", Message: "the link was not created with this API key"}
+)
+
+type Service struct {
+	Repo    storage.LinksRepo
+	Domains storage.DomainsRepo // needed to create links on custom domains
+	Rules   *Rules              // configured reserved words and blocked hosts; nil for none
+	Logger  *slog.Logger
+	Metrics *observability.Metrics // nil does not count created links
+}
+
+type CreateRequest struct {
+	URL                 string
+	Domain              string     // custom domain host; empty for BASE_URL
+	KeyID               int64      // the caller's API key, 0 when anonymous; it owns the link
+	Alias               string     // empty for a generated key
+	KeyAlphabet         string     // of a generated key (id.Alphabets); empty for KEY_ALPHABET
+	ExpiresAt           *time.Time // nil for domain.DefaultLifetimeDays from now
+	NoExpiry            bool       // the link never expires; ExpiresAt must be nil
+	DedupeWindowSeconds *int       // overrides the server default; 0 disables
+
+	// Operator marks links created with shortctl, which may use any registered domain
+	// without an API key grant. Every other check still applies.
+	Operator bool
+}
+
+// Create validates req and stores the link. existing reports that an equivalent
+// system link was returned instead of a new one.
+func (s Service) Create(ctx context.Context, req CreateRequest) (link *domain.Link, existing bool, err error) {
+	if req.ExpiresAt == nil && !req.NoExpiry {
+		defaultExpiry := time.Now().UTC().AddDate(0, 0, domain.DefaultLifetimeDays)
+		req.ExpiresAt = &defaultExpiry
+	}
+
+	if req.URL == "" {
+		return nil, false, invalid("invalid_url", "original_url is required")
+	}
+
+	canon, err := domain.CanonicalizeURL(req.URL)
+	if err != nil {
+		return nil, false, invalid("invalid_url", "must be a valid http/https URL")
+	}
+	if s.Rules.Blocked(canon) {
+		return nil, false, errBlockedURL
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return nil, false, invalid("expiry_in_past", "expires_at must be in the future")
+	}
+
+	window, err := dedupeWindow(req.DedupeWindowSeconds)
+	if err != nil {
+		return nil, false, err
+	}
+
+	host := domain.NormalizeHost(req.Domain)
+	if host != "" {
+		if req.Operator {
+			_, err = s.getDomain(ctx, host)
+		} else {
+			err = s.checkDomain(ctx, host, req.KeyID)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	if req.KeyAlphabet != "" {
+		if !id.IsAlphabet(req.KeyAlphabet) {
+			return nil, false, invalid("invalid_key_alphabet", "key_alphabet must be base62, crockford, lower or words")
+		}
+		if req.Alias != "" {
+			return nil, false, invalid("invalid_key_alphabet", "key_alphabet only applies to generated keys")
+		}
+	}
+
+	if req.Alias != "" {
+		if !domain.ValidateAlias(req.Alias) {
+			return nil, false, invalid("invalid_alias", "alias must match [A-Za-z0-9_-]{3,32}")
+		}
+		if s.Rules.Reserved(req.Alias) {
+			return nil, false, invalid("reserved_key", "alias is reserved")
+		}
+		link, err = s.Repo.CreateAlias(ctx, host, req.Alias, canon, req.KeyID, req.ExpiresAt, window)
+		if err != nil {
+			if errors.Is(err, domain.ErrAliasInUse) {
+				return nil, false, &Error{Kind: Conflict, Code: "alias_in_use", Message: "alias already taken"}
+			}
+			s.Logger.ErrorContext(ctx, "create alias failed", "error", err)
+			return nil, false, errCreateFailed
+		}
+	} else {
+		// idempotent path, per owner
+		if l, err := s.Repo.GetSystemByCanonicalURL(ctx, host, canon, req.KeyID); err == nil {
+			link = l
+			existing = true
+		} else {
+			link, err = s.Repo.CreateSystem(ctx, host, canon, req.KeyAlphabet, req.KeyID, req.ExpiresAt, window)
+			if err != nil {
+				s.Logger.ErrorContext(ctx, "create system link failed", "error", err)
+				return nil, false, errCreateFailed
+			}
+		}
+	}
+
+	if !existing && s.Metrics != nil {
+		s.Metrics.IncrementLinksCreated()
+	}
+	return link, existing, nil
+}
+
+// checkDomain fails unless host is a registered domain the API key may use
+func (s Service) checkDomain(ctx context.Context, host string, keyID int64) error {
+	d, err := s.getDomain(ctx, host)
+	if err != nil {
+		return err
+	}
+	if keyID == 0 || !d.CanUse(keyID) {
+		return &Error{Kind: Forbidden, Code: "domain_not_allowed", Message: "this API key may not use the domain"}
+	}
+	return nil
+}
+
+// getDomain returns the registered domain host
+func (s Service) getDomain(ctx context.Context, host string) (*domain.CustomDomain, error) {
+	d, err := s.Domains.Get(ctx, host)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, invalid("unknown_domain", "domain is not registered")
+	}
+	if err != nil {
+		s.Logger.ErrorContext(ctx, "domain lookup failed", "error", err)
+		return nil, errDomainFailed
+	}
+	return d, nil
+}
+
+// Get returns the link with key in the namespace of the custom domain host, or of
+// BASE_URL when host is empty
+func (s Service) Get(ctx context.Context, host, key string) (*domain.Link, error) {
+	link, err := s.Repo.GetByKey(ctx, domain.NormalizeHost(host), key)
+	if err != nil {
+		return nil, s.lookupError(ctx, err)
+	}
+	return link, nil
+}
+
+// Resolve returns a link that may be redirected to. Unlike the redirect handler it
+// records no click.
+func (s Service) Resolve(ctx context.Context, host, key string) (*domain.Link, error) {
+	link, err := s.Get(ctx, host, key)
+	if err != nil {
+		return nil, err
+	}
+	if link.IsDisabled {
+		return nil, &Error{Kind: Gone, Code: "disabled", Message: "link has been disabled"}
+	}
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(time.Now().UTC()) {
+		return nil, &Error{Kind: Gone, Code: "expired", Message: "link has expired"}
+	}
+	return link, nil
+}
+
+// Update lists the changes to a link; unset fields are kept
+type Update struct {
+	URL                 *string
+	SetExpiry           bool
+	ExpiresAt           *time.Time // with SetExpiry; nil removes the expiry
+	SetDedupeWindow     bool
+	DedupeWindowSeconds *int // with SetDedupeWindow; nil restores the server default
+	Disabled            *bool
+}
+
+// Update applies u to the link on behalf of the API key keyID, which must pass Authorize.
+// Links without an owner can only be changed by operators (shortctl). Only custom aliases
+// can be pointed at a new URL; a generated key stands for the URL it was created for.
+func (s Service) Update(ctx context.Context, host, key string, keyID int64, u Update) (*domain.Link, error) {
+	link, err := s.Get(ctx, host, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Authorize(ctx, link, keyID); err != nil {
+		return nil, err
+	}
+
+	if u.URL != nil {
+		canon, err := domain.CanonicalizeURL(*u.URL)
+		if err != nil {
+			return nil, invalid("invalid_url", "must be a valid http/https URL")
+		}
+		if canon != link.LongURL && s.Rules.Blocked(canon) {
+			return nil, errBlockedURL
+		}
+		if !link.IsCustom && canon != link.LongURL {
+			return nil, &Error{Kind: FailedPrecondition, Code: "immutable_url", Message: "only custom aliases can change their destination"}
+		}
+		link.LongURL = canon
+	}
+	if u.SetExpiry {
+		if u.ExpiresAt != nil && u.ExpiresAt.Before(time.Now()) {
+			return nil, invalid("expiry_in_past", "expires_at must be in the future")
+		}
+		link.ExpiresAt = u.ExpiresAt
+	}
+	if u.SetDedupeWindow {
+		window, err := dedupeWindow(u.DedupeWindowSeconds)
+		if err != nil {
+			return nil, err
+		}
+		link.DedupeWindow = window
+	}
+	if u.Disabled != nil {
+		link.IsDisabled = *u.Disabled
+	}
+
+	updated, err := s.Repo.Update(ctx, link)
+	if err != nil {
+		return nil, s.lookupError(ctx, err)
+	}
+	s.Logger.InfoContext(ctx, "link updated", "disabled", updated.IsDisabled)
+	return updated, nil
+}
+
+// Authorize fails with Forbidden unless the API key keyID created link and, for a link on
+// a custom domain, is still granted the domain. Links without an owner belong to no key.
+func (s Service) Authorize(ctx context.Context, link *domain.Link, keyID int64) error {
+	if link.APIKeyID == 0 || link.APIKeyID != keyID {
+		return errNotOwner
+	}
+	if link.Domain != "" {
+		return s.checkDomain(ctx, link.Domain, keyID)
+	}
+	return nil
+}
+
+// Delete removes a link with all its clicks and stats
+func (s Service) Delete(ctx context.Context, host, key string) error {
+	if err := s.Repo.Delete(ctx, domain.NormalizeHost(host), key); err != nil {
+		return s.lookupError(ctx, err)
+	}
+	s.Logger.InfoContext(ctx, "link deleted")
+	return nil
+}
+
+func (s Service) lookupError(ctx context.Context, err error) *Error {
+	if errors.Is(err, domain.ErrNotFound) {
+		return errNotFound
+	}
+	s.Logger.ErrorContext(ctx, "link lookup failed", "error", err)
+	return errLoadFailed
+}
+
+func dedupeWindow(secs *int) (*time.Duration, error) {
+	if secs == nil {
+		return nil, nil
+	}
+	window := time.Duration(*secs) * time.Second
+	if window < 0 || window > MaxDedupeWindow {
+		return nil, invalid("invalid_dedupe_window", "dedupe_window_seconds must be between 0 and 86400")
+	}
+	return &window, nil
+}
@@ -0,0 +1,74 @@
+package links
+
+import (
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+)
+
+// Rules are the configured restrictions on new links: aliases reserved next to the
+// built-in ones and destination hosts that may not be shortened. They can be replaced
+// while in use, e.g. on a configuration reload. A nil *Rules only applies the
+// built-in reserved words.
+type Rules struct {
+	v atomic.Pointer[ruleSet]
+}
+
+type ruleSet struct {
+	reserved map[string]bool
+	blocked  map[string]bool
+}
+
+func NewRules(reserved, blocked []string) *Rules {
+	r := &Rules{}
+	r.Set(reserved, blocked)
+	return r
+}
+
+// Set replaces the rules; words and hosts are compared case-insensitively
+func (r *Rules) Set(reserved, blocked []string) {
+	rs := &ruleSet{reserved: map[string]bool{}, blocked: map[string]bool{}}
+	for _, w := range reserved {
+		rs.reserved[strings.ToLower(strings.TrimSpace(w))] = true
+	}
+	for _, h := range blocked {
+		rs.blocked[domain.NormalizeHost(h)] = true
+	}
+	r.v.Store(rs)
+}
+
+// Reserved reports whether alias is a built-in or configured reserved word
+func (r *Rules) Reserved(alias string) bool {
+	if domain.IsReserved(alias) {
+		return true
+	}
+	return r != nil && r.v.Load().reserved[strings.ToLower(strings.TrimSpace(alias))]
+}
+
+// Blocked reports whether the host of rawURL, or a domain it belongs to, is blocked
+func (r *Rules) Blocked(rawURL string) bool {
+	if r == nil {
+		return false
+	}
+	blocked := r.v.Load().blocked
+	if len(blocked) == 0 {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := domain.NormalizeHost(u.Hostname())
+	for {
+		if blocked[host] {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			return false
+		}
+		host = parent
+	}
+}
@@ -0,0 +1,217 @@
+// Package migrate applies the numbered SQL files in db/migrations and records the
+// applied version in schema_migrations.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/postgres"
+)
+
+// lockID serialises concurrent migration runs (shared by all instances)
+const lockID = 727002
+
+// Migration is one numbered step with its up and down SQL
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads NN_name.up.sql / NN_name.down.sql pairs from the root of fsys, sorted by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		base, up := strings.CutSuffix(name, ".up.sql")
+		if !up {
+			var down bool
+			if base, down = strings.CutSuffix(name, ".down.sql"); !down {
+				continue
+			}
+		}
+		num, rest, ok := strings.Cut(base, "_")
+		v, err := strconv.Atoi(num)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: name must start with a number", name)
+		}
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[v]
+		if !ok {
+			m = &Migration{Version: v, Name: rest}
+			byVersion[v] = m
+		}
+		if up {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Migrator applies migrations to one database
+type Migrator struct {
+	DB         *pgxpool.Pool
+	Migrations []Migration
+}
+
+func New(db *pgxpool.Pool, migrations []Migration) *Migrator {
+	return &Migrator{DB: db, Migrations: migrations}
+}
+
+// ErrNoBaseline means no version is recorded. Migrations 01-04 describe an older schema
+// than the one the service runs on (the README setup script), so the runner never starts
+// from zero: create the base schema with the script, record it with Baseline, then run Up.
+var ErrNoBaseline = errors.New("no schema version recorded; set up the base schema and run baseline with the last applied version first")
+
+// Current returns the applied version (0 if none)
+func (m *Migrator) Current(ctx context.Context) (int, error) {
+	return postgres.CurrentSchemaVersion(ctx, m.DB)
+}
+
+// Pending returns the migrations above the applied version
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	cur, err := m.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, mig := range m.Migrations {
+		if mig.Version > cur {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies pending migrations up to target (0 = all), each in its own transaction,
+// calling applied after each one
+func (m *Migrator) Up(ctx context.Context, target int, applied func(Migration)) error {
+	cur, err := m.Current(ctx)
+	if err != nil {
+		return err
+	}
+	if cur == 0 {
+		return ErrNoBaseline
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	for _, mig := range pending {
+		if target > 0 && mig.Version > target {
+			break
+		}
+		err := m.inTx(ctx, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, mig.Up); err != nil {
+				return err
+			}
+			return record(ctx, tx, mig.Version)
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+		}
+		if applied != nil {
+			applied(mig)
+		}
+	}
+	return nil
+}
+
+// Down reverts the most recently applied migration and returns it
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	cur, err := m.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if cur == 0 {
+		return nil, errors.New("no migrations applied")
+	}
+	var mig *Migration
+	for i := range m.Migrations {
+		if m.Migrations[i].Version == cur {
+			mig = &m.Migrations[i]
+		}
+	}
+	if mig == nil || mig.Down == "" {
+		return nil, fmt.Errorf("no down migration for version %d", cur)
+	}
+	err = m.inTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, mig.Down); err != nil {
+			return err
+		}
+		// The down file may have dropped schema_migrations itself (version 8)
+		_, err := tx.Exec(ctx, `DO $$ BEGIN
+			IF to_regclass('schema_migrations') IS NOT NULL THEN
+				DELETE FROM schema_migrations WHERE version = `+strconv.Itoa(mig.Version)+`;
+			END IF;
+		END $$`)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return mig, nil
+}
+
+// Baseline records version as applied without running anything, for databases set up
+// by hand (e.g. the README script followed by some of db/migrations)
+func (m *Migrator) Baseline(ctx context.Context, version int) error {
+	return m.inTx(ctx, func(tx pgx.Tx) error {
+		return record(ctx, tx, version)
+	})
+}
+
+func (m *Migrator) inTx(ctx context.Context, fn func(pgx.Tx) error) error {
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, lockID); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func record(ctx context.Context, tx pgx.Tx, version int) error {
+	if _, err := tx.Exec(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version    INTEGER PRIMARY KEY,
+            applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        )`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1) ON CONFLICT (version) DO NOTHING`, version)
+	return err
+}
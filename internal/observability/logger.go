@@ -1,11 +1,124 @@
 package observability
 
 import (
-	"log"
-	"os"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
 )
 
-func NewLogger() *log.Logger {
-	// Simple stdout logger; you can swap for slog/zap later.
-	return log.New(os.Stdout, "", log.LstdFlags|log.Lmicroseconds)
+// redacted replaces the value of sensitive attributes
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are matched case-insensitively as substrings of attribute keys
+var sensitiveKeys = []string{"password", "passwd", "secret", "token", "api_key", "apikey", "authorization", "cookie"}
+
+// NewLogger returns a structured logger writing format ("json" or "text") at level and above;
+// pass a *slog.LevelVar to change the level while the logger is in use.
+// Attributes added to a request context with WithLogAttrs/AddLogAttrs are included in every
+// record logged with that context, and sensitive values are redacted.
+func NewLogger(w io.Writer, format string, level slog.Leveler) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+	var h slog.Handler
+	switch format {
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	return slog.New(contextHandler{Handler: h}), nil
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, k := range sensitiveKeys {
+		if strings.Contains(key, k) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	// Connection strings carry credentials in their userinfo
+	if a.Value.Kind() == slog.KindString {
+		if v := a.Value.String(); strings.Contains(v, "://") {
+			if u, err := url.Parse(v); err == nil && u.User != nil {
+				if _, ok := u.User.Password(); ok {
+					return slog.String(a.Key, u.Redacted())
+				}
+			}
+		}
+	}
+	return a
+}
+
+// logAttrs collects request-scoped attributes; middleware and handlers append to the
+// same bag so the access log line sees attributes added deeper in the chain.
+type logAttrs struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+type logAttrsKey struct{}
+
+// WithLogAttrs returns ctx carrying attrs for every record logged with it
+func WithLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if b, ok := ctx.Value(logAttrsKey{}).(*logAttrs); ok {
+		b.add(attrs)
+		return ctx
+	}
+	return context.WithValue(ctx, logAttrsKey{}, &logAttrs{attrs: attrs})
+}
+
+// AddLogAttrs adds attrs to the request-scoped attributes already in ctx; without them it does nothing
+func AddLogAttrs(ctx context.Context, attrs ...slog.Attr) {
+	if b, ok := ctx.Value(logAttrsKey{}).(*logAttrs); ok {
+		b.add(attrs)
+	}
+}
+
+func (b *logAttrs) add(attrs []slog.Attr) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, a := range attrs {
+		replaced := false
+		for i := range b.attrs {
+			if b.attrs[i].Key == a.Key {
+				b.attrs[i] = a
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			b.attrs = append(b.attrs, a)
+		}
+	}
+}
+
+func (b *logAttrs) snapshot() []slog.Attr {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]slog.Attr(nil), b.attrs...)
+}
+
+// contextHandler adds the request-scoped attributes of the record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if b, ok := ctx.Value(logAttrsKey{}).(*logAttrs); ok {
+		r.AddAttrs(b.snapshot()...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name)}
 }
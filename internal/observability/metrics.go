@@ -5,6 +5,42 @@ import (
 	"time"
 )
 
+// Redirect outcomes recorded by IncrementRedirect
+const (
+	RedirectHit      = "hit"
+	RedirectNotFound = "not_found"
+	RedirectExpired  = "expired"
+	RedirectDisabled = "disabled"
+)
+
+// latencyBuckets are the upper bounds (seconds) of the request latency histogram
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	route  string
+	method string
+	status int
+}
+
+type latencyKey struct {
+	route  string
+	status int
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, non-cumulative; last slot is +Inf
+	sum    float64
+	count  uint64
+}
+
+// funcMetric is sampled at scrape time (queue depth, pool stats, ...)
+type funcMetric struct {
+	name  string
+	help  string
+	kind  string // gauge or counter
+	value func() float64
+}
+
 // Metrics tracks simple application metrics
 type Metrics struct {
 	mu             sync.RWMutex
@@ -14,6 +50,11 @@ type Metrics struct {
 	ErrorsCount    int64
 	StartTime      time.Time
 	LastResetTime  time.Time
+
+	requests  map[requestKey]uint64
+	latencies map[latencyKey]*histogram
+	redirects map[string]uint64
+	funcs     []funcMetric
 }
 
 // NewMetrics creates a new metrics instance
@@ -22,6 +63,9 @@ func NewMetrics() *Metrics {
 	return &Metrics{
 		StartTime:     now,
 		LastResetTime: now,
+		requests:      make(map[requestKey]uint64),
+		latencies:     make(map[latencyKey]*histogram),
+		redirects:     make(map[string]uint64),
 	}
 }
 
@@ -53,6 +97,57 @@ func (m *Metrics) IncrementErrors() {
 	m.ErrorsCount++
 }
 
+// ObserveRequest records a served request by route pattern, method and status
+func (m *Metrics) ObserveRequest(route, method string, status int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.RequestsServed++
+	if status >= 500 {
+		m.ErrorsCount++
+	}
+	m.requests[requestKey{route: route, method: method, status: status}]++
+
+	k := latencyKey{route: route, status: status}
+	h, ok := m.latencies[k]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets)+1)}
+		m.latencies[k] = h
+	}
+	secs := latency.Seconds()
+	i := 0
+	for i < len(latencyBuckets) && secs > latencyBuckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.sum += secs
+	h.count++
+}
+
+// IncrementRedirect counts a redirect attempt by outcome (RedirectHit, RedirectNotFound, ...)
+func (m *Metrics) IncrementRedirect(outcome string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.redirects[outcome]++
+	if outcome == RedirectHit {
+		m.LinksClicked++
+	}
+}
+
+// GaugeFunc registers a gauge sampled from fn on every scrape
+func (m *Metrics) GaugeFunc(name, help string, fn func() float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.funcs = append(m.funcs, funcMetric{name: name, help: help, kind: "gauge", value: fn})
+}
+
+// CounterFunc registers a monotonically increasing value sampled from fn on every scrape
+func (m *Metrics) CounterFunc(name, help string, fn func() float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.funcs = append(m.funcs, funcMetric{name: name, help: help, kind: "counter", value: fn})
+}
+
 // GetStats returns a copy of current stats
 func (m *Metrics) GetStats() map[string]interface{} {
 	m.mu.RLock()
@@ -79,5 +174,8 @@ func (m *Metrics) Reset() {
 	m.LinksClicked = 0
 	m.RequestsServed = 0
 	m.ErrorsCount = 0
+	m.requests = make(map[requestKey]uint64)
+	m.latencies = make(map[latencyKey]*histogram)
+	m.redirects = make(map[string]uint64)
 	m.LastResetTime = time.Now()
 }
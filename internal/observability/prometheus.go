@@ -0,0 +1,98 @@
+package observability
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metricPrefix namespaces every exported metric
+const metricPrefix = "shortener_"
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format (version 0.0.4)
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.RLock()
+	funcs := append([]funcMetric(nil), m.funcs...)
+	bw := bufio.NewWriter(w)
+
+	writeHeader(bw, "http_requests_total", "counter", "HTTP requests by route pattern, method and status.")
+	reqKeys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		reqKeys = append(reqKeys, k)
+	}
+	sort.Slice(reqKeys, func(i, j int) bool {
+		a, b := reqKeys[i], reqKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	for _, k := range reqKeys {
+		fmt.Fprintf(bw, "%shttp_requests_total{route=%s,method=%s,status=\"%d\"} %d\n",
+			metricPrefix, quote(k.route), quote(k.method), k.status, m.requests[k])
+	}
+
+	writeHeader(bw, "http_request_duration_seconds", "histogram", "HTTP request latency by route pattern and status.")
+	latKeys := make([]latencyKey, 0, len(m.latencies))
+	for k := range m.latencies {
+		latKeys = append(latKeys, k)
+	}
+	sort.Slice(latKeys, func(i, j int) bool {
+		if latKeys[i].route != latKeys[j].route {
+			return latKeys[i].route < latKeys[j].route
+		}
+		return latKeys[i].status < latKeys[j].status
+	})
+	for _, k := range latKeys {
+		h := m.latencies[k]
+		labels := fmt.Sprintf("route=%s,status=\"%d\"", quote(k.route), k.status)
+		var cumulative uint64
+		for i, le := range latencyBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(bw, "%shttp_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", metricPrefix, labels, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(bw, "%shttp_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", metricPrefix, labels, h.count)
+		fmt.Fprintf(bw, "%shttp_request_duration_seconds_sum{%s} %s\n", metricPrefix, labels, formatFloat(h.sum))
+		fmt.Fprintf(bw, "%shttp_request_duration_seconds_count{%s} %d\n", metricPrefix, labels, h.count)
+	}
+
+	writeHeader(bw, "redirects_total", "counter", "Redirect attempts by outcome.")
+	for _, outcome := range []string{RedirectHit, RedirectNotFound, RedirectExpired, RedirectDisabled} {
+		fmt.Fprintf(bw, "%sredirects_total{outcome=%s} %d\n", metricPrefix, quote(outcome), m.redirects[outcome])
+	}
+
+	writeHeader(bw, "links_created_total", "counter", "Short links created.")
+	fmt.Fprintf(bw, "%slinks_created_total %d\n", metricPrefix, m.LinksCreated)
+
+	writeHeader(bw, "uptime_seconds", "gauge", "Seconds since the process started.")
+	fmt.Fprintf(bw, "%suptime_seconds %s\n", metricPrefix, formatFloat(time.Since(m.StartTime).Seconds()))
+	m.mu.RUnlock()
+
+	// Sampled outside the lock: callbacks may be slow or take their own locks
+	for _, f := range funcs {
+		writeHeader(bw, f.name, f.kind, f.help)
+		fmt.Fprintf(bw, "%s%s %s\n", metricPrefix, f.name, formatFloat(f.value()))
+	}
+	return bw.Flush()
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricPrefix, name, help, metricPrefix, name, kind)
+}
+
+// quote renders a label value with the escaping required by the text format
+func quote(v string) string {
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+	return `"` + v + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,162 @@
+// Package openapi holds the OpenAPI 3 document of the /v1 API and validates incoming
+// requests against it. Only the parts of the specification the document uses are
+// understood: path and query parameters, JSON request bodies, $ref to components, and
+// the schema keywords type, format, nullable, enum, pattern, min/max(Length), required,
+// properties, additionalProperties, items, min/maxItems and anyOf.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the raw document
+func Spec() []byte {
+	return spec
+}
+
+// Document is the subset of an OpenAPI document used for validation
+type Document struct {
+	Paths      map[string]PathItem `json:"paths"`
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Parameters map[string]*Parameter `json:"parameters"`
+	} `json:"components"`
+}
+
+// PathItem maps lower-case HTTP methods to operations
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []*Parameter `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // path or query
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool `json:"required"`
+	Content  map[string]struct {
+		Schema *Schema `json:"schema"`
+	} `json:"content"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	AnyOf                []*Schema          `json:"anyOf"`
+
+	pattern *regexp.Regexp
+}
+
+// Load parses the embedded document and resolves its references
+func Load() (*Document, error) {
+	return Parse(spec)
+}
+
+// Parse parses an OpenAPI document and resolves its references
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	r := resolver{doc: &doc}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if op == nil {
+				continue
+			}
+			for i, p := range op.Parameters {
+				op.Parameters[i] = r.parameter(p)
+			}
+			if op.RequestBody != nil {
+				for ct, c := range op.RequestBody.Content {
+					c.Schema = r.schema(c.Schema)
+					op.RequestBody.Content[ct] = c
+				}
+			}
+			if r.err != nil {
+				return nil, fmt.Errorf("openapi: %s %s: %w", strings.ToUpper(method), path, r.err)
+			}
+		}
+	}
+	return &doc, nil
+}
+
+// resolver replaces $ref pointers with the components they name and compiles patterns.
+// The first error is kept in err.
+type resolver struct {
+	doc *Document
+	err error
+}
+
+func (r *resolver) parameter(p *Parameter) *Parameter {
+	if p.Ref != "" {
+		name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+		target := r.doc.Components.Parameters[name]
+		if !ok || target == nil {
+			if r.err == nil {
+				r.err = fmt.Errorf("unresolved reference %s", p.Ref)
+			}
+			return p
+		}
+		p = target
+	}
+	p.Schema = r.schema(p.Schema)
+	return p
+}
+
+func (r *resolver) schema(s *Schema) *Schema {
+	if s == nil || r.err != nil {
+		return s
+	}
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		target := r.doc.Components.Schemas[name]
+		if !ok || target == nil {
+			r.err = fmt.Errorf("unresolved reference %s", s.Ref)
+			return s
+		}
+		return r.schema(target)
+	}
+	if s.Pattern != "" && s.pattern == nil {
+		if s.pattern, r.err = regexp.Compile(s.Pattern); r.err != nil {
+			return s
+		}
+	}
+	for name, p := range s.Properties {
+		s.Properties[name] = r.schema(p)
+	}
+	s.Items = r.schema(s.Items)
+	for i, alt := range s.AnyOf {
+		s.AnyOf[i] = r.schema(alt)
+	}
+	return s
+}
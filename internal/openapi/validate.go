@@ -0,0 +1,408 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxBodyBytes bounds the request bodies read for validation
+const MaxBodyBytes = 1 << 20
+
+// Violation is one way a request differs from the document
+type Violation struct {
+	In      string `json:"in"`    // path, query or body
+	Field   string `json:"field"` // parameter name or dotted body path ("" for the whole body)
+	Message string `json:"message"`
+}
+
+var (
+	// ErrBodyTooLarge is returned when the body exceeds MaxBodyBytes
+	ErrBodyTooLarge = errors.New("request body too large")
+	// ErrUnsupportedMediaType is returned for bodies that are not JSON
+	ErrUnsupportedMediaType = errors.New("request body must be application/json")
+)
+
+// Validator matches requests to the operations of a document
+type Validator struct {
+	routes []route
+}
+
+type route struct {
+	segments []string // literal segments, or "{name}" placeholders
+	item     PathItem
+}
+
+func NewValidator(doc *Document) *Validator {
+	v := &Validator{}
+	for path, item := range doc.Paths {
+		v.routes = append(v.routes, route{segments: strings.Split(strings.Trim(path, "/"), "/"), item: item})
+	}
+	// Prefer literal segments over placeholders when two templates match
+	sort.Slice(v.routes, func(i, j int) bool {
+		return placeholders(v.routes[i].segments) < placeholders(v.routes[j].segments)
+	})
+	return v
+}
+
+func placeholders(segments []string) int {
+	n := 0
+	for _, s := range segments {
+		if strings.HasPrefix(s, "{") {
+			n++
+		}
+	}
+	return n
+}
+
+// Validate checks the parameters and body of r against its operation. Requests for
+// paths or methods the document does not describe are not checked. The body is read
+// and replaced, so handlers can still decode it. The error is ErrBodyTooLarge,
+// ErrUnsupportedMediaType or a read error; schema violations are only reported in the slice.
+func (v *Validator) Validate(r *http.Request) ([]Violation, error) {
+	op, pathParams := v.find(r.Method, r.URL.Path)
+	if op == nil {
+		return nil, nil
+	}
+
+	var out []Violation
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw, present = pathParams[p.Name]
+		case "query":
+			if vals, ok := query[p.Name]; ok {
+				raw, present = vals[0], true
+			}
+		default:
+			continue
+		}
+		if !present || raw == "" {
+			if p.Required {
+				out = append(out, Violation{In: p.In, Field: p.Name, Message: "is required"})
+			}
+			continue
+		}
+		if msg := checkParam(p.Schema, raw); msg != "" {
+			out = append(out, Violation{In: p.In, Field: p.Name, Message: msg})
+		}
+	}
+
+	if op.RequestBody != nil {
+		body, err := v.checkBody(r, op.RequestBody)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, body...)
+	}
+	return out, nil
+}
+
+func (v *Validator) find(method, path string) (*Operation, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, rt := range v.routes {
+		if len(rt.segments) != len(segments) {
+			continue
+		}
+		params := map[string]string{}
+		matched := true
+		for i, s := range rt.segments {
+			if name, ok := strings.CutPrefix(s, "{"); ok {
+				val, err := url.PathUnescape(segments[i])
+				if err != nil {
+					val = segments[i]
+				}
+				params[strings.TrimSuffix(name, "}")] = val
+			} else if s != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rt.item[strings.ToLower(method)], params
+		}
+	}
+	return nil, nil
+}
+
+// checkParam converts a path or query string to the schema's type and validates it
+func checkParam(s *Schema, raw string) string {
+	if s == nil {
+		return ""
+	}
+	var val any = raw
+	switch s.Type {
+	case "integer":
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return "must be an integer"
+		}
+		val = json.Number(strconv.FormatInt(n, 10))
+	case "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return "must be a number"
+		}
+		val = json.Number(raw)
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "must be true or false"
+		}
+		val = b
+	}
+	var out []Violation
+	checkValue(s, val, "", &out)
+	if len(out) > 0 {
+		return out[0].Message
+	}
+	return ""
+}
+
+func (v *Validator) checkBody(r *http.Request, rb *RequestBody) ([]Violation, error) {
+	ct := r.Header.Get("Content-Type")
+	if ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return nil, ErrUnsupportedMediaType
+		}
+		// Other media types the operation declares (e.g. image uploads) are left to the handler
+		if _, declared := rb.Content[mt]; declared && mt != "application/json" {
+			return nil, nil
+		}
+		if mt != "application/json" && !strings.HasSuffix(mt, "+json") {
+			return nil, ErrUnsupportedMediaType
+		}
+	}
+	content, ok := rb.Content["application/json"]
+	if !ok {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxBodyBytes {
+		return nil, ErrBodyTooLarge
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if rb.Required {
+			return []Violation{{In: "body", Message: "request body is required"}}, nil
+		}
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var body any
+	if err := dec.Decode(&body); err != nil {
+		return []Violation{{In: "body", Message: "invalid JSON: " + err.Error()}}, nil
+	}
+	var out []Violation
+	checkValue(content.Schema, body, "", &out)
+	for i := range out {
+		out[i].In = "body"
+	}
+	return out, nil
+}
+
+// checkValue validates a decoded JSON value (numbers as json.Number) against s,
+// appending a violation for every failed keyword
+func checkValue(s *Schema, val any, field string, out *[]Violation) {
+	if s == nil || (val == nil && s.Nullable) {
+		return
+	}
+	add := func(format string, args ...any) {
+		*out = append(*out, Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.AnyOf) > 0 && !matchesAny(s.AnyOf, val) {
+		add("must be %s", describeAnyOf(s.AnyOf))
+	}
+
+	switch s.Type {
+	case "string":
+		str, ok := val.(string)
+		if !ok {
+			add("must be a string")
+			return
+		}
+		n := utf8.RuneCountInString(str)
+		if s.MinLength != nil && n < *s.MinLength {
+			if *s.MinLength == 1 {
+				add("must not be empty")
+			} else {
+				add("must be at least %d characters", *s.MinLength)
+			}
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			add("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			add("must match %s", s.Pattern)
+		}
+		if msg := checkFormat(s.Format, str); msg != "" {
+			add("%s", msg)
+		}
+	case "integer", "number":
+		typeMsg := "must be a number"
+		if s.Type == "integer" {
+			typeMsg = "must be an integer"
+		}
+		num, ok := val.(json.Number)
+		if !ok {
+			add("%s", typeMsg)
+			return
+		}
+		f, err := num.Float64()
+		if err != nil || (s.Type == "integer" && strings.ContainsAny(num.String(), ".eE")) {
+			add("%s", typeMsg)
+			return
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			add("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			add("must be at most %v", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := val.(bool); !ok {
+			add("must be true or false")
+			return
+		}
+	case "array":
+		arr, ok := val.([]any)
+		if !ok {
+			add("must be an array")
+			return
+		}
+		if s.MinItems != nil && len(arr) < *s.MinItems {
+			add("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(arr) > *s.MaxItems {
+			add("must have at most %d items", *s.MaxItems)
+		}
+		for i, item := range arr {
+			checkValue(s.Items, item, fmt.Sprintf("%s[%d]", field, i), out)
+		}
+	case "object":
+		obj, ok := val.(map[string]any)
+		if !ok {
+			add("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				*out = append(*out, Violation{Field: join(field, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names) // stable output
+		for _, name := range names {
+			prop, known := s.Properties[name]
+			if !known {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*out = append(*out, Violation{Field: join(field, name), Message: "is not a known field"})
+				}
+				continue
+			}
+			checkValue(prop, obj[name], join(field, name), out)
+		}
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, val) {
+		add("must be one of %s", describeEnum(s.Enum))
+	}
+}
+
+func checkFormat(format, s string) string {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return "must be an RFC3339 timestamp"
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return "must be a date (YYYY-MM-DD)"
+		}
+	case "uri":
+		if u, err := url.Parse(s); err != nil || !u.IsAbs() {
+			return "must be an absolute URL"
+		}
+	}
+	return ""
+}
+
+func inEnum(enum []any, val any) bool {
+	if n, ok := val.(json.Number); ok {
+		f, _ := n.Float64()
+		val = f
+	}
+	for _, e := range enum {
+		if reflect.DeepEqual(e, val) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeEnum(enum []any) string {
+	parts := make([]string, len(enum))
+	for i, e := range enum {
+		parts[i] = fmt.Sprint(e)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func matchesAny(alts []*Schema, val any) bool {
+	for _, alt := range alts {
+		var out []Violation
+		checkValue(alt, val, "", &out)
+		if len(out) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func describeAnyOf(alts []*Schema) string {
+	parts := make([]string, 0, len(alts))
+	for _, a := range alts {
+		switch {
+		case len(a.Required) > 0:
+			parts = append(parts, "an object with "+strings.Join(a.Required, " and "))
+		case a.Format == "date":
+			parts = append(parts, "a date (YYYY-MM-DD)")
+		case a.Format == "date-time":
+			parts = append(parts, "an RFC3339 timestamp")
+		default:
+			parts = append(parts, "a "+a.Type)
+		}
+	}
+	return strings.Join(parts, " or ")
+}
+
+func join(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
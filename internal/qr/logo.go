@@ -0,0 +1,96 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // register the decoder for DecodeLogo
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// Logo upload limits
+const (
+	MaxLogoBytes     = 512 << 10
+	MaxLogoDimension = 1024 // pixels per side
+)
+
+// logoFraction is the largest share of the symbol width the logo area may take. With
+// error correction level H a code survives about 30% of damaged codewords; a centred
+// area of 25% x 25% covers about 6% of the modules and keeps well clear of that.
+const logoFraction = 0.25
+
+// minLogoVersion is the smallest symbol used under a logo. Short URLs fit in 25x25
+// modules, where the logo area would be only a few modules wide; version 5 has 37x37.
+const minLogoVersion = 5
+
+// ErrInvalidLogo is returned by DecodeLogo for data that is not a usable PNG or JPEG image
+var ErrInvalidLogo = errors.New("logo must be a PNG or JPEG image")
+
+var logoContentType = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+}
+
+// DecodeLogo checks and decodes an uploaded logo, returning the image and its content type
+func DecodeLogo(data []byte) (image.Image, string, error) {
+	if len(data) > MaxLogoBytes {
+		return nil, "", fmt.Errorf("logo must be at most %d bytes", MaxLogoBytes)
+	}
+	// Check the dimensions before decoding, so a small file cannot claim a huge image
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || logoContentType[format] == "" {
+		return nil, "", ErrInvalidLogo
+	}
+	if cfg.Width < 1 || cfg.Height < 1 || cfg.Width > MaxLogoDimension || cfg.Height > MaxLogoDimension {
+		return nil, "", fmt.Errorf("logo must be at most %dx%d pixels", MaxLogoDimension, MaxLogoDimension)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrInvalidLogo
+	}
+	return img, logoContentType[format], nil
+}
+
+// logoArea returns the first module and the width in modules of the square left clear
+// for the logo in a symbol of n modules. It has the parity of n so it sits exactly in
+// the centre of the module grid.
+func logoArea(n int) (start, width int) {
+	width = int(float64(n) * logoFraction)
+	if (n-width)%2 != 0 {
+		width--
+	}
+	return (n - width) / 2, width
+}
+
+// clearLogoArea turns the modules under the logo light, so no partial modules show
+// around it
+func clearLogoArea(modules [][]bool) {
+	start, width := logoArea(len(modules))
+	for y := start; y < start+width; y++ {
+		for x := start; x < start+width; x++ {
+			modules[y][x] = false
+		}
+	}
+}
+
+// fit returns the largest rectangle with the aspect ratio of src that fits in box,
+// centred in it
+func fit(src, box image.Rectangle) image.Rectangle {
+	sw, sh := src.Dx(), src.Dy()
+	w, h := box.Dx(), box.Dy()
+	if sw*h > sh*w {
+		h = sh * w / sw
+	} else {
+		w = sw * h / sh
+	}
+	min := box.Min.Add(image.Pt((box.Dx()-w)/2, (box.Dy()-h)/2))
+	return image.Rectangle{Min: min, Max: min.Add(image.Pt(w, h))}
+}
+
+// drawLogo scales logo into box, keeping its aspect ratio
+func drawLogo(dst draw.Image, logo image.Image, box image.Rectangle) {
+	draw.CatmullRom.Scale(dst, fit(logo.Bounds(), box), logo, logo.Bounds(), draw.Over, nil)
+}
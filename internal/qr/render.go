@@ -0,0 +1,221 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/draw"
+)
+
+// Format is the image format of a rendered code
+type Format string
+
+const (
+	PNG Format = "png"
+	SVG Format = "svg"
+)
+
+// Size and margin bounds for Render
+const (
+	MinSize       = 64
+	MaxSize       = 1024
+	MaxMargin     = 16
+	DefaultMargin = 4 // modules; the quiet zone the QR specification asks for
+)
+
+// Options controls how a code is rendered. Zero values take the defaults: PNG, the
+// generator's size, level M, a 4 module margin, black on white, no logo.
+type Options struct {
+	Format     Format
+	Size       int                  // width and height in pixels (SVG: nominal size)
+	Level      qrcode.RecoveryLevel // error correction; always H with a logo
+	Margin     *int                 // quiet zone in modules
+	Foreground color.Color
+	Background color.Color
+	Logo       image.Image // centred over the code, see DecodeLogo
+}
+
+// ErrSizeTooSmall is returned when the code has more modules than Options.Size has pixels
+var ErrSizeTooSmall = errors.New("size too small for the encoded data")
+
+// ParseLevel accepts the error correction levels L (7%), M (15%), Q (25%) and H (30%)
+func ParseLevel(s string) (qrcode.RecoveryLevel, bool) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return qrcode.Low, true
+	case "", "M":
+		return qrcode.Medium, true
+	case "Q":
+		return qrcode.High, true
+	case "H":
+		return qrcode.Highest, true
+	}
+	return 0, false
+}
+
+// ParseColor accepts RRGGBB or RRGGBBAA hex, with or without a leading #
+func ParseColor(s string) (color.Color, error) {
+	s = strings.TrimPrefix(s, "#")
+	b, err := hex.DecodeString(s)
+	if err != nil || (len(b) != 3 && len(b) != 4) {
+		return nil, fmt.Errorf("color must be RRGGBB or RRGGBBAA hex")
+	}
+	c := color.NRGBA{R: b[0], G: b[1], B: b[2], A: 0xff}
+	if len(b) == 4 {
+		c.A = b[3]
+	}
+	return c, nil
+}
+
+// Render encodes data as a QR code image
+func (g *Generator) Render(data string, o Options) ([]byte, error) {
+	if o.Format == "" {
+		o.Format = PNG
+	}
+	if o.Size == 0 {
+		o.Size = g.size
+	}
+	if o.Size < MinSize || o.Size > MaxSize {
+		return nil, fmt.Errorf("size must be between %d and %d", MinSize, MaxSize)
+	}
+	if o.Level == 0 {
+		o.Level = qrcode.Medium
+	}
+	if o.Logo != nil {
+		o.Level = qrcode.Highest // the logo hides modules, so keep the most redundancy
+	}
+	margin := DefaultMargin
+	if o.Margin != nil {
+		margin = *o.Margin
+	}
+	if margin < 0 || margin > MaxMargin {
+		return nil, fmt.Errorf("margin must be between 0 and %d", MaxMargin)
+	}
+	if o.Foreground == nil {
+		o.Foreground = color.Black
+	}
+	if o.Background == nil {
+		o.Background = color.White
+	}
+
+	code, err := qrcode.New(data, o.Level)
+	if err != nil {
+		return nil, fmt.Errorf("create qr code: %w", err)
+	}
+	if o.Logo != nil && code.VersionNumber < minLogoVersion {
+		if code, err = qrcode.NewWithForcedVersion(data, minLogoVersion, o.Level); err != nil {
+			return nil, fmt.Errorf("create qr code: %w", err)
+		}
+	}
+	code.DisableBorder = true // the margin is drawn here
+	modules := code.Bitmap()
+	if o.Logo != nil {
+		clearLogoArea(modules)
+	}
+
+	switch o.Format {
+	case PNG:
+		return renderPNG(modules, margin, o)
+	case SVG:
+		return renderSVG(modules, margin, o)
+	}
+	return nil, fmt.Errorf("unknown format %q", o.Format)
+}
+
+// renderPNG scales modules by a whole number of pixels, centring the code when the
+// size is not an exact multiple
+func renderPNG(modules [][]bool, margin int, o Options) ([]byte, error) {
+	total := len(modules) + 2*margin
+	scale := o.Size / total
+	if scale < 1 {
+		return nil, ErrSizeTooSmall
+	}
+	offset := (o.Size-scale*total)/2 + margin*scale
+
+	code := image.NewPaletted(image.Rect(0, 0, o.Size, o.Size), color.Palette{o.Background, o.Foreground})
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					code.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var img image.Image = code
+	if o.Logo != nil {
+		// The logo needs full color; keep a one module border of background around it
+		rgba := image.NewNRGBA(code.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), code, image.Point{}, draw.Src)
+		start, width := logoArea(len(modules))
+		box := image.Rect(start+1, start+1, start+width-1, start+width-1)
+		drawLogo(rgba, o.Logo, image.Rectangle{Min: box.Min.Mul(scale), Max: box.Max.Mul(scale)}.Add(image.Pt(offset, offset)))
+		img = rgba
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("encode png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderSVG draws one unit per module and lets the viewer scale it
+func renderSVG(modules [][]bool, margin int, o Options) ([]byte, error) {
+	total := len(modules) + 2*margin
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		o.Size, o.Size, total, total)
+	fill, opacity := svgColor(o.Background)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"%s/>`, total, total, fill, opacity)
+	fill, opacity = svgColor(o.Foreground)
+	fmt.Fprintf(&buf, `<path fill="%s"%s d="`, fill, opacity)
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// One horizontal run per path segment keeps the output small
+			run := 1
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", x+margin, y+margin, run, run)
+			x += run - 1
+		}
+	}
+	buf.WriteString(`"/>`)
+	if o.Logo != nil {
+		// Embedded as PNG whatever was uploaded, so the SVG is self-contained
+		var logo bytes.Buffer
+		if err := png.Encode(&logo, o.Logo); err != nil {
+			return nil, fmt.Errorf("encode logo: %w", err)
+		}
+		start, width := logoArea(len(modules))
+		fmt.Fprintf(&buf, `<image x="%d" y="%d" width="%d" height="%d" preserveAspectRatio="xMidYMid meet" href="data:image/png;base64,%s"/>`,
+			start+margin+1, start+margin+1, width-2, width-2, base64.StdEncoding.EncodeToString(logo.Bytes()))
+	}
+	buf.WriteString(`</svg>`)
+	return buf.Bytes(), nil
+}
+
+func svgColor(c color.Color) (fill, opacity string) {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	fill = fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+	if n.A != 0xff {
+		opacity = fmt.Sprintf(` fill-opacity="%.3g"`, float64(n.A)/255)
+	}
+	return fill, opacity
+}
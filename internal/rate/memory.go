@@ -0,0 +1,98 @@
+package rate
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory defaults
+const (
+	DefaultMaxKeys = 100_000
+	sweepInterval  = time.Minute
+)
+
+// Memory keeps counters in the process. Limits apply per instance, so with N replicas
+// behind a load balancer a client may get up to N times the limit; use Shared there.
+type Memory struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	maxKeys   int
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type counter struct {
+	start     time.Time // of the current window
+	window    time.Duration
+	prev, cur int64
+}
+
+// NewMemory tracks at most maxKeys keys (DefaultMaxKeys if maxKeys <= 0). Keys idle for
+// two windows are dropped; when the map is full an arbitrary key is evicted, which only
+// forgives that client's recent requests.
+func NewMemory(maxKeys int) *Memory {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+	return &Memory{counters: make(map[string]*counter), maxKeys: maxKeys, now: time.Now}
+}
+
+func (m *Memory) Take(_ context.Context, key string, limit int, window time.Duration) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+
+	start := windowStart(now, window)
+	c, ok := m.counters[key]
+	if !ok {
+		if len(m.counters) >= m.maxKeys {
+			m.evictOne()
+		}
+		c = &counter{start: start, window: window}
+		m.counters[key] = c
+	}
+	switch {
+	case c.start.Equal(start):
+	case c.start.Add(window).Equal(start):
+		c.prev, c.cur = c.cur, 0
+	default:
+		c.prev, c.cur = 0, 0
+	}
+	c.start, c.window = start, window
+
+	elapsed := now.Sub(start)
+	allowed := float64(c.prev)*weight(elapsed, window)+float64(c.cur) < float64(limit)
+	if allowed {
+		c.cur++
+	}
+	return decide(allowed, c.prev, c.cur, elapsed, limit, window), nil
+}
+
+// Len returns the number of tracked keys
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.counters)
+}
+
+// sweep drops counters whose windows have both slid out
+func (m *Memory) sweep(now time.Time) {
+	for key, c := range m.counters {
+		if !now.Before(c.start.Add(2 * c.window)) {
+			delete(m.counters, key)
+		}
+	}
+	m.lastSweep = now
+}
+
+func (m *Memory) evictOne() {
+	for key := range m.counters {
+		delete(m.counters, key)
+		return
+	}
+}
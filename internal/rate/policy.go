@@ -0,0 +1,109 @@
+package rate
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy allows Limit requests per Window. A zero Limit means no limit.
+type Policy struct {
+	Limit  int
+	Window time.Duration
+}
+
+func (p Policy) Unlimited() bool {
+	return p.Limit <= 0
+}
+
+func (p Policy) String() string {
+	if p.Unlimited() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", p.Limit, p.Window)
+}
+
+// Policies maps route names to their policy; routes without one are not limited
+type Policies map[string]Policy
+
+// Tiers holds the policies for anonymous clients and for each API key tier. A tier
+// without a policy for a route falls back to Default, as do keys of unknown tiers.
+type Tiers struct {
+	Default Policies
+	ByTier  map[string]Policies
+}
+
+// Policy returns the policy of route for a client of tier ("" for anonymous clients)
+func (t Tiers) Policy(route, tier string) Policy {
+	if p, ok := t.ByTier[tier][route]; ok && tier != "" {
+		return p
+	}
+	return t.Default[route]
+}
+
+// ParsePolicies reads a comma separated list of route=limit/window entries, e.g.
+// "create=10/1m,stats=120/1m", where "route=off" lifts the limit
+func ParsePolicies(s string) (Policies, error) {
+	out := Policies{}
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		route = strings.TrimSpace(route)
+		if !ok {
+			return nil, fmt.Errorf("%q: want route=limit/window", entry)
+		}
+		if !slices.Contains(Routes, route) {
+			return nil, fmt.Errorf("%q: unknown route %q (want one of %s)", entry, route, strings.Join(Routes, ", "))
+		}
+		p, err := parsePolicy(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", entry, err)
+		}
+		out[route] = p
+	}
+	return out, nil
+}
+
+// ParseTiers reads semicolon separated tier:policies entries, e.g.
+// "pro:create=600/1m,batch=100/1m;internal:create=off"
+func ParseTiers(s string) (map[string]Policies, error) {
+	out := map[string]Policies{}
+	for entry := range strings.SplitSeq(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tier, list, ok := strings.Cut(entry, ":")
+		tier = strings.TrimSpace(tier)
+		if !ok || tier == "" {
+			return nil, fmt.Errorf("%q: want tier:route=limit/window,...", entry)
+		}
+		p, err := ParsePolicies(list)
+		if err != nil {
+			return nil, fmt.Errorf("tier %s: %w", tier, err)
+		}
+		out[tier] = p
+	}
+	return out, nil
+}
+
+func parsePolicy(s string) (Policy, error) {
+	if s == "off" {
+		return Policy{}, nil
+	}
+	limit, window, ok := strings.Cut(s, "/")
+	n, err := strconv.Atoi(limit)
+	if !ok || err != nil || n < 1 {
+		return Policy{}, fmt.Errorf("want a positive limit/window like 10/1m, or off")
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d < time.Second {
+		return Policy{}, fmt.Errorf("window must be a duration of at least 1s")
+	}
+	return Policy{Limit: n, Window: d}, nil
+}
@@ -0,0 +1,150 @@
+// Package rate limits requests per client with a sliding window counter. Counters live
+// in a Backend: Memory keeps them in the process, Shared keeps them in Postgres so every
+// instance enforces the same limit.
+//
+// The sliding window counter keeps one count per fixed window and estimates the number
+// of requests in the last window length as
+//
+//	previous * (1 - elapsed/window) + current
+//
+// where elapsed is the time since the current window started. A request is allowed
+// while the estimate is below the limit. Denied requests are not counted.
+package rate
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Backend counts requests per key
+type Backend interface {
+	// Take counts one request for key if it fits in limit per window
+	Take(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// Result is the outcome of Backend.Take
+type Result struct {
+	Allowed    bool
+	Limit      int           // 0 when the client is not limited
+	Window     time.Duration // of the policy applied
+	Remaining  int           // requests left in the window after this one
+	Reset      time.Duration // until the current window ends, or RetryAfter when denied
+	RetryAfter time.Duration // when denied, how long until a request would be allowed
+}
+
+// Route names. Limiters with the same route count into the same counters, so the REST
+// and gRPC APIs enforce one limit per route.
+const (
+	CreateLinks      = "create"
+	BatchCreateLinks = "batch"
+	LinkStats        = "stats"
+	Redirect         = "redirect"
+)
+
+// Routes lists the route names policies can be set for
+var Routes = []string{CreateLinks, BatchCreateLinks, LinkStats, Redirect}
+
+// Client is who a request is counted against
+type Client struct {
+	ID   string // "key:<id>" for API keys, "ip:<addr>" otherwise
+	Tier string // API key tier; empty for anonymous clients
+}
+
+// IPClient is an anonymous client, counted by address
+func IPClient(ip string) Client {
+	if ip == "" {
+		ip = "unknown"
+	}
+	return Client{ID: "ip:" + ip}
+}
+
+// KeyClient is an API key, counted by key whatever address it is used from
+func KeyClient(id int64, tier string) Client {
+	return Client{ID: "key:" + strconv.FormatInt(id, 10), Tier: tier}
+}
+
+// Settings holds the policies in force; they can be replaced while limiters use them,
+// e.g. on a configuration reload. Counters are kept.
+type Settings struct {
+	tiers atomic.Pointer[Tiers]
+}
+
+func NewSettings(t Tiers) *Settings {
+	s := &Settings{}
+	s.Set(t)
+	return s
+}
+
+func (s *Settings) Tiers() Tiers {
+	return *s.tiers.Load()
+}
+
+func (s *Settings) Set(t Tiers) {
+	s.tiers.Store(&t)
+}
+
+// Limiter applies the policies of one route, keeping its counters apart from other
+// routes on the same backend
+type Limiter struct {
+	backend  Backend
+	route    string
+	settings *Settings
+}
+
+func NewLimiter(b Backend, route string, s *Settings) *Limiter {
+	return &Limiter{backend: b, route: route, settings: s}
+}
+
+// Allow counts a request for c under the policy of its tier. Unlimited clients are
+// allowed without touching the backend and get a zero Limit.
+func (l *Limiter) Allow(ctx context.Context, c Client) (Result, error) {
+	p := l.settings.Tiers().Policy(l.route, c.Tier)
+	if p.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+	return l.backend.Take(ctx, l.route+":"+c.ID, p.Limit, p.Window)
+}
+
+// windowStart returns the start of the fixed window containing t
+func windowStart(t time.Time, window time.Duration) time.Time {
+	return t.Truncate(window)
+}
+
+// weight is the share of the previous window still inside the sliding window
+func weight(elapsed, window time.Duration) float64 {
+	return 1 - float64(elapsed)/float64(window)
+}
+
+// decide builds the result from the window counts at elapsed into the current window;
+// cur already includes the request when it was allowed
+func decide(allowed bool, prev, cur int64, elapsed time.Duration, limit int, window time.Duration) Result {
+	estimate := float64(prev)*weight(elapsed, window) + float64(cur)
+	res := Result{Allowed: allowed, Limit: limit, Window: window, Remaining: max(0, int(math.Ceil(float64(limit)-estimate))), Reset: window - elapsed}
+	if !allowed {
+		res.RetryAfter = retryAfter(prev, cur, elapsed, limit, window)
+		res.Reset = res.RetryAfter
+	}
+	return res
+}
+
+// retryAfter returns how long until the estimate drops below limit, assuming no
+// further requests are counted
+func retryAfter(prev, cur int64, elapsed time.Duration, limit int, window time.Duration) time.Duration {
+	w := float64(window)
+	if cur < int64(limit) && prev > 0 {
+		// Later in this window, once enough of the previous one has slid out
+		at := w * (1 - float64(int64(limit)-cur)/float64(prev))
+		if t := time.Duration(at) - elapsed; t < window-elapsed {
+			return max(t, 0) + time.Millisecond
+		}
+	}
+	// In the next window, where this window's count is the previous one
+	next := window - elapsed
+	if cur >= int64(limit) && cur > 0 {
+		next += time.Duration(w * (1 - float64(limit)/float64(cur)))
+	}
+	return next + time.Millisecond
+}
@@ -0,0 +1,30 @@
+package rate
+
+import (
+	"context"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+)
+
+// Shared keeps counters in the database, so all instances enforce one limit. Every
+// request costs a round trip; Take fails when the database does.
+type Shared struct {
+	repo storage.RateLimitRepo
+	now  func() time.Time
+}
+
+func NewShared(repo storage.RateLimitRepo) *Shared {
+	return &Shared{repo: repo, now: time.Now}
+}
+
+func (s *Shared) Take(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	now := s.now()
+	start := windowStart(now, window)
+	elapsed := now.Sub(start)
+	prev, cur, allowed, err := s.repo.Take(ctx, key, start, window, weight(elapsed, window), limit)
+	if err != nil {
+		return Result{}, err
+	}
+	return decide(allowed, prev, cur, elapsed, limit, window), nil
+}
@@ -0,0 +1,261 @@
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/apikey"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clientip"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/rate"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/tracing"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/pkg/shortenerpb"
+)
+
+// Metadata keys (lower case, as gRPC delivers them)
+const (
+	requestIDKey     = "x-request-id"
+	apiKeyKey        = "x-api-key"
+	authorizationKey = "authorization"
+	traceparentKey   = "traceparent"
+)
+
+// apiKeyCtxKey holds the *domain.APIKey authenticate identified
+type apiKeyCtxKey struct{}
+
+type access int
+
+const (
+	anyone access = iota
+	keyHolders
+	admins
+)
+
+// methodAccess lists the calls that need more than an anonymous caller, matching the
+// REST routes they mirror
+var methodAccess = map[string]access{
+	shortenerpb.LinkService_BatchCreateLinks_FullMethodName: keyHolders,
+	shortenerpb.LinkService_UpdateLink_FullMethodName:       keyHolders,
+	shortenerpb.LinkService_DeleteLink_FullMethodName:       admins,
+}
+
+func recoverPanics(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				logger.ErrorContext(ctx, "panic", "panic", rec, "stack", string(debug.Stack()))
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// logRequests sets the request id (from x-request-id metadata or new), method and client
+// IP as log attributes and writes one access log line per call. The client IP is
+// resolved from the peer address and forwarding metadata like the HTTP headers.
+func logRequests(logger *slog.Logger, ips *clientip.Resolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		reqID := firstMetadata(ctx, requestIDKey)
+		if reqID == "" {
+			reqID = newID()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, reqID))
+		ip := clientIP(ctx, ips)
+		ctx = clientip.NewContext(ctx, ip)
+		ctx = observability.WithLogAttrs(ctx,
+			slog.String("request_id", reqID),
+			slog.String("rpc", info.FullMethod),
+			slog.String("client_ip", ip),
+		)
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		level := slog.LevelInfo
+		if isServerError(code) {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(ctx, level, "rpc request",
+			slog.String("code", code.String()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000.0),
+		)
+		return resp, err
+	}
+}
+
+// traceRequests starts a server span per call, continuing a traceparent sent as metadata
+func traceRequests(t *tracing.Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if t == nil {
+			return handler(ctx, req)
+		}
+		if sc, ok := tracing.ParseTraceparent(firstMetadata(ctx, traceparentKey)); ok {
+			ctx = tracing.ContextWithRemote(ctx, sc)
+		}
+		service, method, _ := strings.Cut(strings.TrimPrefix(info.FullMethod, "/"), "/")
+		ctx, span := t.Start(ctx, info.FullMethod, tracing.KindServer,
+			tracing.Attr{Key: "rpc.system", Value: "grpc"},
+			tracing.Attr{Key: "rpc.service", Value: service},
+			tracing.Attr{Key: "rpc.method", Value: method},
+		)
+		defer span.End()
+		if span != nil {
+			observability.AddLogAttrs(ctx, slog.String("trace_id", span.SpanContext().TraceID.String()))
+		}
+
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		span.SetAttr("rpc.grpc.status_code", int(code))
+		if isServerError(code) {
+			span.SetStatus(tracing.StatusError, code.String())
+		}
+		return resp, err
+	}
+}
+
+// authenticate identifies API keys sent as "authorization: Bearer sk_..." or x-api-key
+// like the REST API: anonymous calls pass unless methodAccess says otherwise, and an
+// unknown or revoked key is always rejected
+func authenticate(keys storage.APIKeysRepo, adminToken string, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		bearer, _ := strings.CutPrefix(firstMetadata(ctx, authorizationKey), "Bearer ")
+
+		if methodAccess[info.FullMethod] == admins {
+			if adminToken == "" {
+				return nil, statusError(codes.PermissionDenied, "admin_disabled", "ADMIN_TOKEN is not configured")
+			}
+			if subtle.ConstantTimeCompare([]byte(bearer), []byte(adminToken)) != 1 {
+				return nil, statusError(codes.Unauthenticated, "unauthorized", "admin token required")
+			}
+			return handler(ctx, req)
+		}
+
+		raw := firstMetadata(ctx, apiKeyKey)
+		if raw == "" && strings.HasPrefix(bearer, apikey.Prefix) {
+			raw = bearer
+		}
+		if raw == "" {
+			if methodAccess[info.FullMethod] == keyHolders {
+				return nil, statusError(codes.Unauthenticated, "unauthorized", "API key required")
+			}
+			return handler(ctx, req)
+		}
+
+		if !apikey.LooksValid(raw) {
+			return nil, statusError(codes.Unauthenticated, "unauthorized", "invalid API key")
+		}
+		k, err := keys.GetByHash(ctx, apikey.Hash(raw))
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			return nil, statusError(codes.Unauthenticated, "unauthorized", "invalid API key")
+		case err != nil:
+			logger.ErrorContext(ctx, "api key lookup failed", "error", err)
+			return nil, statusError(codes.Internal, "server_error", "could not check API key")
+		case k.Revoked():
+			return nil, statusError(codes.Unauthenticated, "unauthorized", "API key revoked")
+		}
+
+		observability.AddLogAttrs(ctx, slog.Int64("auth_key_id", k.ID))
+		return handler(context.WithValue(ctx, apiKeyCtxKey{}, k), req)
+	}
+}
+
+// limitCalls applies the route limits of the REST API to the calls that mirror those
+// routes, per API key or per peer IP. Responses carry ratelimit-* header metadata and
+// rejections a RetryInfo detail with the time until a call would be allowed. Like the
+// REST middleware it lets calls through when the limiters' backend fails.
+func limitCalls(limiters map[string]*rate.Limiter, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		l, ok := limiters[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+		client := rate.IPClient(clientip.FromContext(ctx))
+		if k, _ := ctx.Value(apiKeyCtxKey{}).(*domain.APIKey); k != nil {
+			client = rate.KeyClient(k.ID, k.Tier)
+		}
+		res, err := l.Allow(ctx, client)
+		if err != nil {
+			logger.ErrorContext(ctx, "rate limit check failed", "error", err)
+			return handler(ctx, req)
+		}
+		if res.Limit > 0 {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(
+				"ratelimit-limit", strconv.Itoa(res.Limit),
+				"ratelimit-remaining", strconv.Itoa(res.Remaining),
+				"ratelimit-reset", strconv.Itoa(int(math.Ceil(res.Reset.Seconds()))),
+			))
+		}
+		if !res.Allowed {
+			st := status.New(codes.ResourceExhausted, "too many requests")
+			if detailed, err := st.WithDetails(
+				&errdetails.ErrorInfo{Reason: "rate_limited", Domain: errorDomain},
+				&errdetails.RetryInfo{RetryDelay: durationpb.New(res.RetryAfter)},
+			); err == nil {
+				st = detailed
+			}
+			return nil, st.Err()
+		}
+		return handler(ctx, req)
+	}
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if vals := md.Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+// clientIP resolves the caller's address from the peer and forwarding metadata (which
+// gRPC delivers with lower case keys)
+func clientIP(ctx context.Context, ips *clientip.Resolver) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	h := make(http.Header, len(md))
+	for k, v := range md {
+		h[http.CanonicalHeaderKey(k)] = v
+	}
+	return ips.Resolve(p.Addr.String(), h)
+}
+
+func isServerError(c codes.Code) bool {
+	switch c {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		return true
+	}
+	return false
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
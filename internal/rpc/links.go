@@ -0,0 +1,184 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/pkg/shortenerpb"
+)
+
+// errorDomain is the ErrorInfo domain of all errors returned by this API
+const errorDomain = "shortener"
+
+// linkErrorCode maps link operation failures to gRPC codes
+var linkErrorCode = map[links.Kind]codes.Code{
+	links.Invalid:            codes.InvalidArgument,
+	links.NotFound:           codes.NotFound,
+	links.Conflict:           codes.AlreadyExists,
+	links.FailedPrecondition: codes.FailedPrecondition,
+	links.Gone:               codes.FailedPrecondition,
+	links.Forbidden:          codes.PermissionDenied,
+	links.Internal:           codes.Internal,
+}
+
+// statusError returns a status carrying reason (the REST error code) as ErrorInfo
+func statusError(code codes.Code, reason, msg string) error {
+	st := status.New(code, msg)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// describeLinkError returns the code, reason and message for an error of the links service
+func describeLinkError(err error) (codes.Code, string, string) {
+	var lerr *links.Error
+	if !errors.As(err, &lerr) {
+		return codes.Internal, "server_error", "internal error"
+	}
+	return linkErrorCode[lerr.Kind], lerr.Code, lerr.Message
+}
+
+func linkError(err error) error {
+	return statusError(describeLinkError(err))
+}
+
+type linkServer struct {
+	shortenerpb.UnimplementedLinkServiceServer
+	cfg   config.Config
+	links links.Service
+}
+
+func (s *linkServer) CreateLink(ctx context.Context, req *shortenerpb.CreateLinkRequest) (*shortenerpb.CreateLinkResponse, error) {
+	resp, err := s.create(ctx, req)
+	if err != nil {
+		return nil, linkError(err)
+	}
+	return resp, nil
+}
+
+func (s *linkServer) create(ctx context.Context, req *shortenerpb.CreateLinkRequest) (*shortenerpb.CreateLinkResponse, error) {
+	in := links.CreateRequest{URL: req.GetOriginalUrl(), Alias: req.GetCustomAlias(), Domain: req.GetDomain(), KeyAlphabet: req.GetKeyAlphabet()}
+	if k, _ := ctx.Value(apiKeyCtxKey{}).(*domain.APIKey); k != nil {
+		in.KeyID = k.ID
+	}
+	if req.ExpiresAt != nil {
+		t := req.ExpiresAt.AsTime()
+		in.ExpiresAt = &t
+	}
+	if req.DedupeWindow != nil {
+		secs := int(req.DedupeWindow.AsDuration() / time.Second)
+		in.DedupeWindowSeconds = &secs
+	}
+	link, existing, err := s.links.Create(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return &shortenerpb.CreateLinkResponse{Link: toLink(s.cfg, link), Existing: existing}, nil
+}
+
+func (s *linkServer) BatchCreateLinks(ctx context.Context, req *shortenerpb.BatchCreateLinksRequest) (*shortenerpb.BatchCreateLinksResponse, error) {
+	if len(req.Links) == 0 || len(req.Links) > links.MaxBatch {
+		return nil, statusError(codes.InvalidArgument, "bad_request", fmt.Sprintf("links must hold between 1 and %d items", links.MaxBatch))
+	}
+	results := make([]*shortenerpb.BatchCreateResult, len(req.Links))
+	for i, item := range req.Links {
+		created, err := s.create(ctx, item)
+		if err != nil {
+			code, reason, msg := describeLinkError(err)
+			results[i] = &shortenerpb.BatchCreateResult{Result: &shortenerpb.BatchCreateResult_Error{
+				Error: &shortenerpb.Error{Code: int32(code), Reason: reason, Message: msg},
+			}}
+			continue
+		}
+		results[i] = &shortenerpb.BatchCreateResult{Result: &shortenerpb.BatchCreateResult_Created{Created: created}}
+	}
+	return &shortenerpb.BatchCreateLinksResponse{Results: results}, nil
+}
+
+func (s *linkServer) GetLink(ctx context.Context, req *shortenerpb.GetLinkRequest) (*shortenerpb.Link, error) {
+	link, err := s.links.Get(ctx, req.GetDomain(), req.GetKey())
+	if err != nil {
+		return nil, linkError(err)
+	}
+	return toLink(s.cfg, link), nil
+}
+
+func (s *linkServer) UpdateLink(ctx context.Context, req *shortenerpb.UpdateLinkRequest) (*shortenerpb.Link, error) {
+	u := links.Update{URL: req.OriginalUrl, Disabled: req.Disabled}
+	switch e := req.Expiry.(type) {
+	case *shortenerpb.UpdateLinkRequest_ExpiresAt:
+		t := e.ExpiresAt.AsTime()
+		u.SetExpiry, u.ExpiresAt = true, &t
+	case *shortenerpb.UpdateLinkRequest_NeverExpires:
+		u.SetExpiry = e.NeverExpires
+	}
+	switch d := req.Dedupe.(type) {
+	case *shortenerpb.UpdateLinkRequest_DedupeWindow:
+		secs := int(d.DedupeWindow.AsDuration() / time.Second)
+		u.SetDedupeWindow, u.DedupeWindowSeconds = true, &secs
+	case *shortenerpb.UpdateLinkRequest_DefaultDedupeWindow:
+		u.SetDedupeWindow = d.DefaultDedupeWindow
+	}
+
+	var keyID int64
+	if k, _ := ctx.Value(apiKeyCtxKey{}).(*domain.APIKey); k != nil {
+		keyID = k.ID
+	}
+	link, err := s.links.Update(ctx, req.GetDomain(), req.GetKey(), keyID, u)
+	if err != nil {
+		return nil, linkError(err)
+	}
+	return toLink(s.cfg, link), nil
+}
+
+func (s *linkServer) DeleteLink(ctx context.Context, req *shortenerpb.DeleteLinkRequest) (*shortenerpb.DeleteLinkResponse, error) {
+	if err := s.links.Delete(ctx, req.GetDomain(), req.GetKey()); err != nil {
+		return nil, linkError(err)
+	}
+	return &shortenerpb.DeleteLinkResponse{}, nil
+}
+
+func (s *linkServer) ResolveLink(ctx context.Context, req *shortenerpb.ResolveLinkRequest) (*shortenerpb.ResolveLinkResponse, error) {
+	link, err := s.links.Resolve(ctx, req.GetDomain(), req.GetKey())
+	if err != nil {
+		return nil, linkError(err)
+	}
+	return &shortenerpb.ResolveLinkResponse{OriginalUrl: link.LongURL, ExpiresAt: timestamp(link.ExpiresAt)}, nil
+}
+
+func toLink(cfg config.Config, l *domain.Link) *shortenerpb.Link {
+	pb := &shortenerpb.Link{
+		Key:         l.Key,
+		ShortUrl:    links.ShortURL(cfg.BaseURL, l),
+		OriginalUrl: l.LongURL,
+		IsCustom:    l.IsCustom,
+		IsDisabled:  l.IsDisabled,
+		CreatedAt:   timestamppb.New(l.CreatedAt),
+		ExpiresAt:   timestamp(l.ExpiresAt),
+		Domain:      l.Domain,
+	}
+	if l.DedupeWindow != nil {
+		pb.DedupeWindow = durationpb.New(*l.DedupeWindow)
+	}
+	return pb
+}
+
+// timestamp converts an optional time; nil stays unset
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,88 @@
+// Package rpc serves the gRPC API defined in proto/shortener/v1 on its own port. It
+// runs the same link operations (internal/links), repositories, API key checks and
+// rate limits as the REST API.
+package rpc
+
+import (
+	"crypto/tls"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/clientip"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/rate"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/postgres"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage/traced"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/tracing"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/pkg/shortenerpb"
+)
+
+type Deps struct {
+	Config  config.Config
+	Logger  *slog.Logger
+	DB      *pgxpool.Pool
+	Metrics *observability.Metrics
+	Tracer  *tracing.Tracer // nil disables tracing
+	Limits  rate.Backend    // rate limit counters; nil keeps them in memory
+	TLS     *tls.Config     // certificates of the HTTPS server; nil serves plaintext
+
+	// Reloadable settings; nil uses those of Config
+	RateLimits *rate.Settings
+	Rules      *links.Rules
+}
+
+// NewServer registers the link and stats services, the standard health service and
+// server reflection (for grpcurl and similar tools)
+func NewServer(d Deps) *grpc.Server {
+	if d.Metrics == nil {
+		d.Metrics = observability.NewMetrics()
+	}
+	if d.Limits == nil {
+		d.Limits = rate.NewMemory(0)
+	}
+	if d.RateLimits == nil {
+		d.RateLimits = rate.NewSettings(d.Config.RateLimits)
+	}
+	if d.Rules == nil {
+		d.Rules = links.NewRules(d.Config.ReservedWords, d.Config.BlockedHosts)
+	}
+
+	linksRepo := traced.NewLinksRepo(postgres.NewLinksRepo(d.DB, d.Config.KeyOptions()), d.Tracer)
+	statsRepo := traced.NewStatsRepo(postgres.NewStatsRepo(d.DB), d.Tracer)
+	apiKeysRepo := traced.NewAPIKeysRepo(postgres.NewAPIKeysRepo(d.DB), d.Tracer)
+
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(
+		recoverPanics(d.Logger),
+		logRequests(d.Logger, clientip.New(d.Config.TrustedProxies, d.Config.ClientIPHeader)),
+		traceRequests(d.Tracer),
+		authenticate(apiKeysRepo, d.Config.AdminToken, d.Logger),
+		// Same limits as the REST routes, counted together when both servers share Limits
+		limitCalls(map[string]*rate.Limiter{
+			shortenerpb.LinkService_CreateLink_FullMethodName:       rate.NewLimiter(d.Limits, rate.CreateLinks, d.RateLimits),
+			shortenerpb.LinkService_BatchCreateLinks_FullMethodName: rate.NewLimiter(d.Limits, rate.BatchCreateLinks, d.RateLimits),
+			shortenerpb.LinkService_ResolveLink_FullMethodName:      rate.NewLimiter(d.Limits, rate.Redirect, d.RateLimits),
+			shortenerpb.StatsService_GetStats_FullMethodName:        rate.NewLimiter(d.Limits, rate.LinkStats, d.RateLimits),
+		}, d.Logger),
+	)}
+	if d.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(d.TLS)))
+	}
+	srv := grpc.NewServer(opts...)
+
+	domainsRepo := traced.NewDomainsRepo(postgres.NewDomainsRepo(d.DB), d.Tracer)
+	svc := links.Service{Repo: linksRepo, Domains: domainsRepo, Rules: d.Rules, Logger: d.Logger, Metrics: d.Metrics}
+	shortenerpb.RegisterLinkServiceServer(srv, &linkServer{cfg: d.Config, links: svc})
+	shortenerpb.RegisterStatsServiceServer(srv, &statsServer{cfg: d.Config, logger: d.Logger, links: svc, stats: statsRepo})
+
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	reflection.Register(srv)
+	return srv
+}
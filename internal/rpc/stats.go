@@ -0,0 +1,112 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/config"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/links"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/observability"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/pkg/shortenerpb"
+)
+
+type statsServer struct {
+	shortenerpb.UnimplementedStatsServiceServer
+	cfg    config.Config
+	logger *slog.Logger
+	links  links.Service
+	stats  storage.StatsRepo
+}
+
+// GetStats mirrors GET /v1/links/{key}/stats, with the range given as timestamps
+func (s *statsServer) GetStats(ctx context.Context, req *shortenerpb.GetStatsRequest) (*shortenerpb.Stats, error) {
+	observability.AddLogAttrs(ctx, slog.String("link_key", req.GetKey()))
+
+	gran, ok := domain.ParseGranularity(req.GetGranularity())
+	if !ok {
+		return nil, statusError(codes.InvalidArgument, "bad_request", "granularity must be one of hour, day, week, month")
+	}
+	loc := time.UTC
+	if tz := req.GetTz(); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil || tz == "Local" {
+			return nil, statusError(codes.InvalidArgument, "bad_request", "tz must be an IANA time zone name")
+		}
+		loc = l
+	}
+
+	today := domain.GranularityDay.Truncate(time.Now(), loc)
+	from := today.AddDate(0, 0, -30)
+	to := today.AddDate(0, 0, 1)
+	if req.From != nil {
+		from = req.From.AsTime()
+	}
+	if req.To != nil {
+		to = req.To.AsTime()
+	}
+	if !from.Before(to) {
+		return nil, statusError(codes.InvalidArgument, "bad_request", "from must be before to")
+	}
+	buckets, ok := gran.Buckets(from, to, loc)
+	if !ok {
+		return nil, statusError(codes.InvalidArgument, "range_too_large",
+			fmt.Sprintf("range spans more than %d %s buckets", domain.MaxSeriesBuckets, gran))
+	}
+
+	link, err := s.links.Get(ctx, req.GetDomain(), req.GetKey())
+	if err != nil {
+		return nil, linkError(err)
+	}
+	totals, err := s.stats.Totals(ctx, link.ID, req.GetIncludeBots())
+	if err != nil {
+		s.logger.ErrorContext(ctx, "stats totals failed", "error", err)
+		return nil, statusError(codes.Internal, "server_error", "could not fetch stats")
+	}
+	counts, err := s.stats.Series(ctx, link.ID, from, to, gran, loc, req.GetIncludeBots())
+	if errors.Is(err, domain.ErrClicksPurged) {
+		return nil, statusError(codes.InvalidArgument, "clicks_purged",
+			"the range needs raw clicks older than the retention period; start and end it on whole UTC hours and use a time zone with a whole-hour offset")
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "stats series failed", "error", err)
+		return nil, statusError(codes.Internal, "server_error", "could not fetch stats")
+	}
+
+	// Zero-fill so every bucket in the range is present
+	byStart := make(map[int64]storage.BucketCount, len(counts))
+	for _, c := range counts {
+		byStart[c.Start.Unix()] = c
+	}
+	series := make([]*shortenerpb.Bucket, 0, len(buckets))
+	for _, b := range buckets {
+		c := byStart[b.Unix()]
+		bucket := &shortenerpb.Bucket{Start: timestamppb.New(b), Clicks: c.Clicks}
+		if gran.CountsUniqueVisitors(b, from, to) {
+			bucket.UniqueVisitors = &c.UniqueVisitors
+		}
+		series = append(series, bucket)
+	}
+
+	return &shortenerpb.Stats{
+		Key:                link.Key,
+		ShortUrl:           links.ShortURL(s.cfg.BaseURL, link),
+		TotalClicks:        totals.Clicks,
+		DeduplicatedClicks: totals.DedupedClicks,
+		QrClicks:           totals.QRClicks,
+		LastClickedAt:      timestamp(totals.LastClickedAt),
+		IncludeBots:        req.GetIncludeBots(),
+		Granularity:        string(gran),
+		Timezone:           loc.String(),
+		From:               timestamppb.New(from),
+		To:                 timestamppb.New(to),
+		Series:             series,
+	}, nil
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+)
+
+const apiKeyColumns = `id, name, prefix, tier, created_at, last_used_at, revoked_at`
+
+// lastUsedResolution is how stale last_used_at may get; it bounds the writes made by
+// authenticated requests to one per key per interval
+const lastUsedResolution = time.Minute
+
+type APIKeysRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewAPIKeysRepo(db *pgxpool.Pool) *APIKeysRepo {
+	return &APIKeysRepo{DB: db}
+}
+
+func scanAPIKey(row pgx.Row) (*domain.APIKey, error) {
+	var k domain.APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Tier, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *APIKeysRepo) Create(ctx context.Context, name, tier, prefix, hash string) (*domain.APIKey, error) {
+	return scanAPIKey(r.DB.QueryRow(ctx, `
+        INSERT INTO api_keys (name, tier, prefix, key_hash)
+        VALUES ($1, $2, $3, $4)
+        RETURNING `+apiKeyColumns, name, tier, prefix, hash))
+}
+
+// GetByHash returns the key with that hash, revoked or not, and records its use when
+// last_used_at is older than lastUsedResolution
+func (r *APIKeysRepo) GetByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	k, err := scanAPIKey(r.DB.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`, hash))
+	if err != nil {
+		return nil, err
+	}
+	if k.LastUsedAt == nil || time.Since(*k.LastUsedAt) >= lastUsedResolution {
+		// Best effort: a failed write must not fail the request. The condition keeps
+		// concurrent requests and replicas from writing the same interval twice.
+		_, _ = r.DB.Exec(ctx, `
+            UPDATE api_keys SET last_used_at = NOW()
+            WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - make_interval(secs => $2))`,
+			k.ID, lastUsedResolution.Seconds())
+	}
+	return k, nil
+}
+
+func (r *APIKeysRepo) Revoke(ctx context.Context, id int64) error {
+	ct, err := r.DB.Exec(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *APIKeysRepo) List(ctx context.Context) ([]*domain.APIKey, error) {
+	rows, err := r.DB.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*domain.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
@@ -2,9 +2,14 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
 )
 
 type ClicksRepo struct {
@@ -15,11 +20,132 @@ func NewClicksRepo(db *pgxpool.Pool) *ClicksRepo {
 	return &ClicksRepo{DB: db}
 }
 
-func (r *ClicksRepo) Insert(ctx context.Context, linkID int64, occurredAt time.Time, visitorHash, countryCode, userAgent, referer *string) error {
+func (r *ClicksRepo) Insert(ctx context.Context, c *domain.Click) error {
 	query := `
-        INSERT INTO clicks (link_id, created_at, visitor_hash, country_code, user_agent, referer)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        INSERT INTO clicks (link_id, created_at, visitor_hash, country_code, user_agent, referer, is_bot, is_duplicate, source)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''))
     `
-	_, err := r.DB.Exec(ctx, query, linkID, occurredAt, visitorHash, countryCode, userAgent, referer)
+	_, err := r.DB.Exec(ctx, query, c.LinkID, c.OccurredAt, c.VisitorHash, c.CountryCode, c.UserAgent, c.Referer, c.IsBot, c.IsDuplicate, c.Source)
 	return err
 }
+
+// PurgeBefore deletes one batch of raw clicks older than before and records before as the
+// purge horizon first, so stats know raw clicks may be missing from then on.
+// Clicks inserted after either rollup watermark are kept so no data is lost before it is aggregated.
+func (r *ClicksRepo) PurgeBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		WITH horizon AS (
+			INSERT INTO click_purge_horizon (purged_before) VALUES ($1)
+			ON CONFLICT (id) DO UPDATE
+			SET purged_before = GREATEST(click_purge_horizon.purged_before, EXCLUDED.purged_before)
+		)
+		DELETE FROM clicks
+		WHERE id IN (
+			SELECT id FROM clicks
+			WHERE created_at < $1 AND inserted_at < LEAST(
+				COALESCE((SELECT rolled_up_to FROM rollup_watermarks WHERE granularity = 'hour'), '-infinity'::timestamptz),
+				COALESCE((SELECT rolled_up_to FROM rollup_watermarks WHERE granularity = 'day'), '-infinity'::timestamptz))
+			LIMIT $2
+		)
+	`
+	ct, err := r.DB.Exec(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// DeleteByLink erases every raw click and rollup row of a link
+func (r *ClicksRepo) DeleteByLink(ctx context.Context, linkID int64) (int64, error) {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `DELETE FROM clicks WHERE link_id = $1`, linkID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM click_rollups WHERE link_id = $1`, linkID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM click_rollup_dimensions WHERE link_id = $1`, linkID); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// DeleteByVisitor erases every raw click of a visitor. Rollups only hold counts and are kept.
+func (r *ClicksRepo) DeleteByVisitor(ctx context.Context, visitorHash string) (int64, error) {
+	ct, err := r.DB.Exec(ctx, `DELETE FROM clicks WHERE visitor_hash = $1`, visitorHash)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// clickRangeSQL filters clicks by ClickRange; $4/$5 are the After cursor (NULL for the first page).
+// Clicks are ordered by (created_at, id), so clicks sharing a timestamp are never skipped.
+const clickRangeSQL = `
+	link_id = $1 AND created_at >= $2 AND created_at < $3
+	AND ($4::timestamptz IS NULL OR (created_at, id) > ($4, $5::bigint))`
+
+func cursorArgs(c *storage.ClickCursor) (*time.Time, int64) {
+	if c == nil {
+		return nil, 0
+	}
+	return &c.OccurredAt, c.ID
+}
+
+// PageEnd returns the position of the limit-th click of cr, or nil if there are fewer
+func (r *ClicksRepo) PageEnd(ctx context.Context, cr storage.ClickRange, limit int) (*storage.ClickCursor, error) {
+	afterAt, afterID := cursorArgs(cr.After)
+	query := `
+		SELECT created_at, id FROM clicks
+		WHERE ` + clickRangeSQL + `
+		ORDER BY created_at, id
+		OFFSET $6 LIMIT 1
+	`
+	var c storage.ClickCursor
+	err := r.DB.QueryRow(ctx, query, cr.LinkID, cr.From, cr.To, afterAt, afterID, limit-1).Scan(&c.OccurredAt, &c.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Each streams the clicks of cr in keyset order up to and including end (or all of cr if end is nil)
+func (r *ClicksRepo) Each(ctx context.Context, cr storage.ClickRange, end *storage.ClickCursor, fn func(*domain.Click) error) error {
+	afterAt, afterID := cursorArgs(cr.After)
+	endAt, endID := cursorArgs(end)
+	query := `
+		SELECT created_at, visitor_hash, country_code, user_agent, referer, is_bot, is_duplicate, COALESCE(source, '')
+		FROM clicks
+		WHERE ` + clickRangeSQL + `
+		  AND ($6::timestamptz IS NULL OR (created_at, id) <= ($6, $7::bigint))
+		ORDER BY created_at, id
+	`
+	rows, err := r.DB.Query(ctx, query, cr.LinkID, cr.From, cr.To, afterAt, afterID, endAt, endID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c := domain.Click{LinkID: cr.LinkID}
+		if err := rows.Scan(&c.OccurredAt, &c.VisitorHash, &c.CountryCode, &c.UserAgent, &c.Referer, &c.IsBot, &c.IsDuplicate, &c.Source); err != nil {
+			return err
+		}
+		if err := fn(&c); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+)
+
+// DashboardRepo aggregates analytics across the links of one API key, combining hourly
+// rollups with raw clicks inserted since the last rollup like StatsRepo does for a single link.
+// Like StatsRepo.Series, reads fail with domain.ErrClicksPurged if they need purged raw clicks.
+type DashboardRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewDashboardRepo(db *pgxpool.Pool) *DashboardRepo {
+	return &DashboardRepo{DB: db}
+}
+
+// ownedSQL is a CTE "owned" with the ids of the links created with an API key
+func ownedSQL(keyID string) string {
+	return `
+	owned AS (
+		SELECT id FROM links WHERE api_key_id = ` + keyID + `
+	)`
+}
+
+// TopLinks ranks the links of keyID by clicks in [from, to)
+func (r *DashboardRepo) TopLinks(ctx context.Context, keyID int64, from, to time.Time, includeBots bool, limit int) ([]storage.LinkClicks, error) {
+	if err := checkRawClicks(ctx, r.DB, from, to, time.UTC); err != nil {
+		return nil, err
+	}
+	query := `
+		WITH` + hourWatermarkSQL + `,` + wholeHoursSQL("$1", "$2") + `,` + ownedSQL("$5") + `,
+		src AS (
+			SELECT r.link_id, r.clicks
+			FROM click_rollups r, whole
+			WHERE r.link_id IN (SELECT id FROM owned)
+			  AND r.granularity = 'hour' AND r.bucket_start >= whole.lo AND r.bucket_start < whole.hi
+			  AND ($3 OR NOT r.is_bot)
+			UNION ALL
+			SELECT c.link_id, 1
+			FROM clicks c, wm, whole
+			WHERE c.link_id IN (SELECT id FROM owned) AND c.created_at >= $1 AND c.created_at < $2
+			  AND (c.inserted_at >= wm.t OR c.created_at < whole.lo OR c.created_at >= whole.hi)
+			  AND ($3 OR NOT c.is_bot)
+		),
+		top AS (
+			SELECT link_id, SUM(clicks)::bigint AS clicks
+			FROM src
+			GROUP BY link_id
+			ORDER BY clicks DESC, link_id
+			LIMIT $4
+		)
+		SELECT ` + prefixed("l.", linkColumns) + `, top.clicks
+		FROM top JOIN links l ON l.id = top.link_id
+		ORDER BY top.clicks DESC, l.id
+	`
+	rows, err := r.DB.Query(ctx, query, from, to, includeBots, limit, keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []storage.LinkClicks
+	for rows.Next() {
+		var lc storage.LinkClicks
+		l, err := scanLink(&rowWithTail{row: rows, tail: []any{&lc.Clicks}})
+		if err != nil {
+			return nil, err
+		}
+		lc.Link = l
+		results = append(results, lc)
+	}
+	return results, rows.Err()
+}
+
+// Series returns clicks of the links of keyID grouped into buckets aligned to the wall
+// clock of loc. Only non-empty buckets are returned.
+func (r *DashboardRepo) Series(ctx context.Context, keyID int64, from, to time.Time, granularity domain.Granularity, loc *time.Location, includeBots bool) ([]storage.BucketCount, error) {
+	if err := checkRawClicks(ctx, r.DB, from, to, loc); err != nil {
+		return nil, err
+	}
+	_, fromOffset := from.In(loc).Zone()
+	_, toOffset := to.In(loc).Zone()
+	rolled := fromOffset%3600 == 0 && toOffset%3600 == 0
+
+	// Without whole-hour offsets the rollups cannot be re-bucketed, so everything comes from raw clicks
+	query := `
+		WITH` + hourWatermarkSQL + `,` + wholeHoursSQL("$1", "$2") + `,` + ownedSQL("$7") + `,
+		src AS (
+			SELECT r.bucket_start AS at, r.clicks
+			FROM click_rollups r, whole
+			WHERE $6 AND r.link_id IN (SELECT id FROM owned)
+			  AND r.granularity = 'hour' AND r.bucket_start >= whole.lo AND r.bucket_start < whole.hi
+			  AND ($5 OR NOT r.is_bot)
+			UNION ALL
+			SELECT c.created_at, 1
+			FROM clicks c, wm, whole
+			WHERE c.link_id IN (SELECT id FROM owned) AND c.created_at >= $1 AND c.created_at < $2
+			  AND (NOT $6 OR c.inserted_at >= wm.t OR c.created_at < whole.lo OR c.created_at >= whole.hi)
+			  AND ($5 OR NOT c.is_bot)
+		)
+		SELECT ` + bucketSQL("at", "$3", "$4") + ` AS bucket, SUM(clicks)::bigint AS count
+		FROM src
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`
+	rows, err := r.DB.Query(ctx, query, from, to, string(granularity), loc.String(), includeBots, rolled, keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []storage.BucketCount
+	for rows.Next() {
+		var b storage.BucketCount
+		if err := rows.Scan(&b.Start, &b.Clicks); err != nil {
+			return nil, err
+		}
+		results = append(results, b)
+	}
+	return results, rows.Err()
+}
+
+// TopValues ranks the values of a rollup dimension by clicks on the links of keyID in [from, to)
+func (r *DashboardRepo) TopValues(ctx context.Context, keyID int64, dimension string, from, to time.Time, includeBots bool, limit int) ([]storage.ValueCount, error) {
+	var rawValue string
+	switch dimension {
+	case storage.DimensionCountry:
+		rawValue = `COALESCE(c.country_code, 'ZZ')`
+	case storage.DimensionReferrer:
+		rawValue = referrerHostSQL
+	default:
+		return nil, fmt.Errorf("unknown dimension %q", dimension)
+	}
+	if err := checkRawClicks(ctx, r.DB, from, to, time.UTC); err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH` + hourWatermarkSQL + `,` + wholeHoursSQL("$2", "$3") + `,` + ownedSQL("$6") + `,
+		src AS (
+			SELECT d.value, d.clicks
+			FROM click_rollup_dimensions d, whole
+			WHERE d.link_id IN (SELECT id FROM owned) AND d.granularity = 'hour' AND d.dimension = $1
+			  AND d.bucket_start >= whole.lo AND d.bucket_start < whole.hi
+			  AND ($4 OR NOT d.is_bot)
+			UNION ALL
+			SELECT ` + rawValue + `, 1
+			FROM clicks c, wm, whole
+			WHERE c.link_id IN (SELECT id FROM owned) AND c.created_at >= $2 AND c.created_at < $3
+			  AND (c.inserted_at >= wm.t OR c.created_at < whole.lo OR c.created_at >= whole.hi)
+			  AND ($4 OR NOT c.is_bot)
+		)
+		SELECT value, SUM(clicks)::bigint AS clicks
+		FROM src
+		GROUP BY value
+		ORDER BY clicks DESC, value
+		LIMIT $5
+	`
+	rows, err := r.DB.Query(ctx, query, dimension, from, to, includeBots, limit, keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []storage.ValueCount
+	for rows.Next() {
+		var v storage.ValueCount
+		if err := rows.Scan(&v.Value, &v.Clicks); err != nil {
+			return nil, err
+		}
+		results = append(results, v)
+	}
+	return results, rows.Err()
+}
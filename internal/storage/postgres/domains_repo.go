@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+)
+
+// domainColumns is the column list scanned by scanDomain; key_ids collects the granted API keys
+const domainColumns = `d.id, d.host, d.created_at,
+        COALESCE((SELECT array_agg(api_key_id ORDER BY api_key_id) FROM domain_keys WHERE domain_id = d.id), '{}')`
+
+// PostgreSQL error codes
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+type DomainsRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewDomainsRepo(db *pgxpool.Pool) *DomainsRepo {
+	return &DomainsRepo{DB: db}
+}
+
+func scanDomain(row pgx.Row) (*domain.CustomDomain, error) {
+	var d domain.CustomDomain
+	err := row.Scan(&d.ID, &d.Host, &d.CreatedAt, &d.KeyIDs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+func (r *DomainsRepo) Create(ctx context.Context, host string) (*domain.CustomDomain, error) {
+	d, err := scanDomain(r.DB.QueryRow(ctx, `
+        INSERT INTO domains AS d (host) VALUES ($1)
+        RETURNING `+domainColumns, host))
+	if pgErrorCode(err) == uniqueViolation {
+		return nil, domain.ErrDomainExists
+	}
+	return d, err
+}
+
+func (r *DomainsRepo) Get(ctx context.Context, host string) (*domain.CustomDomain, error) {
+	return scanDomain(r.DB.QueryRow(ctx, `SELECT `+domainColumns+` FROM domains d WHERE d.host = $1`, host))
+}
+
+func (r *DomainsRepo) List(ctx context.Context) ([]*domain.CustomDomain, error) {
+	rows, err := r.DB.Query(ctx, `SELECT `+domainColumns+` FROM domains d ORDER BY d.host`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*domain.CustomDomain
+	for rows.Next() {
+		d, err := scanDomain(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// Delete removes a domain and its grants; links.domain keeps it while links use it
+func (r *DomainsRepo) Delete(ctx context.Context, host string) error {
+	ct, err := r.DB.Exec(ctx, `DELETE FROM domains WHERE host = $1`, host)
+	if pgErrorCode(err) == foreignKeyViolation {
+		return domain.ErrDomainInUse
+	}
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// Grant returns domain.ErrNotFound for an unknown domain or key
+func (r *DomainsRepo) Grant(ctx context.Context, host string, keyID int64) error {
+	ct, err := r.DB.Exec(ctx, `
+        INSERT INTO domain_keys (domain_id, api_key_id)
+        SELECT id, $2 FROM domains WHERE host = $1
+        ON CONFLICT DO NOTHING`, host, keyID)
+	if pgErrorCode(err) == foreignKeyViolation {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		// Already granted, or no such domain
+		if _, err := r.Get(ctx, host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *DomainsRepo) Revoke(ctx context.Context, host string, keyID int64) error {
+	ct, err := r.DB.Exec(ctx, `
+        DELETE FROM domain_keys
+        WHERE api_key_id = $2 AND domain_id = (SELECT id FROM domains WHERE host = $1)`, host, keyID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
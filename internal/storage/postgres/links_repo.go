@@ -3,9 +3,10 @@ package postgres
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
-	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
@@ -13,119 +14,204 @@ import (
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/id"
 )
 
+// linkColumns is the column list scanned by scanLink
+const linkColumns = `id, domain, short_code, original_url, is_custom, created_at, expires_at, is_disabled, dedupe_window_seconds, api_key_id`
+
+// maxKeyAttempts bounds the keys CreateSystem tries; strategies switch to collision-free
+// counter keys well before
+const maxKeyAttempts = 50
+
 type LinksRepo struct {
-	pool   *pgxpool.Pool
-	minLen int
-	maxLen int
+	pool     *pgxpool.Pool
+	keys     map[string]id.Strategy // by alphabet
+	alphabet string                 // of keys when CreateSystem is given none
 }
 
-func NewLinksRepo(pool *pgxpool.Pool, minLen, maxLen int) *LinksRepo {
-	return &LinksRepo{pool: pool, minLen: minLen, maxLen: maxLen}
+// NewLinksRepo generates system keys as keys selects (KEY_STRATEGY, KEY_ALPHABET), in
+// any alphabet a request asks for
+func NewLinksRepo(pool *pgxpool.Pool, keys id.Options) *LinksRepo {
+	r := &LinksRepo{pool: pool, keys: map[string]id.Strategy{}, alphabet: keys.Alphabet}
+	if r.alphabet == "" {
+		r.alphabet = id.Base62
+	}
+	for _, a := range id.Alphabets {
+		o := keys
+		o.Alphabet = a
+		r.keys[a] = id.NewStrategy(o, r.nextKeyNumber)
+	}
+	return r
 }
 
-func (r *LinksRepo) GetByKey(ctx context.Context, key string) (*domain.Link, error) {
-	// FIXED: 'key' -> 'short_code'
-	row := r.pool.QueryRow(ctx, `
-        SELECT id, short_code, original_url, is_custom, created_at, expires_at, is_disabled
-        FROM links WHERE short_code = $1`, key)
+// nextKeyNumber draws from the sequence behind counter keys
+func (r *LinksRepo) nextKeyNumber(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.pool.QueryRow(ctx, `SELECT nextval('link_key_numbers')`).Scan(&n)
+	return n, err
+}
 
+func scanLink(row pgx.Row) (*domain.Link, error) {
 	var l domain.Link
-	// FIXED: Scan into correct fields
-	if err := row.Scan(&l.ID, &l.Key, &l.LongURL, &l.IsCustom, &l.CreatedAt, &l.ExpiresAt, &l.IsDisabled); err != nil {
+	var host *string
+	var dedupeSeconds *int32
+	var keyID *int64
+	if err := row.Scan(&l.ID, &host, &l.Key, &l.LongURL, &l.IsCustom, &l.CreatedAt, &l.ExpiresAt, &l.IsDisabled, &dedupeSeconds, &keyID); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrNotFound
 		}
 		return nil, err
 	}
+	if host != nil {
+		l.Domain = *host
+	}
+	if dedupeSeconds != nil {
+		w := time.Duration(*dedupeSeconds) * time.Second
+		l.DedupeWindow = &w
+	}
+	if keyID != nil {
+		l.APIKeyID = *keyID
+	}
 	return &l, nil
 }
 
-func (r *LinksRepo) GetSystemByCanonicalURL(ctx context.Context, canonicalURL string) (*domain.Link, error) {
-	// FIXED: 'key' -> 'short_code'
-	row := r.pool.QueryRow(ctx, `
-        SELECT id, short_code, original_url, is_custom, created_at, expires_at, is_disabled
-        FROM links WHERE original_url = $1 AND is_custom = FALSE`, canonicalURL)
+// rowWithTail lets scanLink read a link followed by extra columns
+type rowWithTail struct {
+	row  pgx.Row
+	tail []any
+}
 
-	var l domain.Link
-	if err := row.Scan(&l.ID, &l.Key, &l.LongURL, &l.IsCustom, &l.CreatedAt, &l.ExpiresAt, &l.IsDisabled); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, domain.ErrNotFound
-		}
-		return nil, err
+func (r *rowWithTail) Scan(dest ...any) error {
+	return r.row.Scan(append(dest, r.tail...)...)
+}
+
+// prefixed qualifies every column of a comma-separated list with a table alias
+func prefixed(alias, columns string) string {
+	cols := strings.Split(columns, ",")
+	for i, c := range cols {
+		cols[i] = alias + strings.TrimSpace(c)
 	}
-	return &l, nil
+	return strings.Join(cols, ", ")
 }
 
-func (r *LinksRepo) CreateAlias(ctx context.Context, alias string, canonicalURL string, expiresAt *time.Time) (*domain.Link, error) {
-	var l domain.Link
+// dedupeSeconds converts an optional window to the dedupe_window_seconds column value
+func dedupeSeconds(w *time.Duration) *int32 {
+	if w == nil {
+		return nil
+	}
+	s := int32(w.Seconds())
+	return &s
+}
+
+// ownerID converts an API key id to the api_key_id column value; 0 is no owner
+func ownerID(keyID int64) *int64 {
+	if keyID == 0 {
+		return nil
+	}
+	return &keyID
+}
+
+// inDomain matches the links of one namespace; it is the expression of the unique
+// (domain, short_code) index, with the host as $1
+const inDomain = `COALESCE(domain, '') = $1`
+
+func (r *LinksRepo) GetByKey(ctx context.Context, host, key string) (*domain.Link, error) {
 	// FIXED: 'key' -> 'short_code'
-	err := r.pool.QueryRow(ctx, `
-        INSERT INTO links (short_code, original_url, is_custom, expires_at)
-        VALUES ($1, $2, TRUE, $3)
-        RETURNING id, short_code, original_url, is_custom, created_at, expires_at, is_disabled
-    `, alias, canonicalURL, expiresAt).Scan(&l.ID, &l.Key, &l.LongURL, &l.IsCustom, &l.CreatedAt, &l.ExpiresAt, &l.IsDisabled)
+	return scanLink(r.pool.QueryRow(ctx, `
+        SELECT `+linkColumns+`
+        FROM links WHERE `+inDomain+` AND short_code = $2`, host, key))
+}
 
-	if err != nil {
-		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
-			return nil, domain.ErrAliasInUse
-		}
-		return nil, err
+// GetSystemByCanonicalURL returns the system link keyID (0 for none) owns for canonicalURL
+func (r *LinksRepo) GetSystemByCanonicalURL(ctx context.Context, host, canonicalURL string, keyID int64) (*domain.Link, error) {
+	// FIXED: 'key' -> 'short_code'
+	return scanLink(r.pool.QueryRow(ctx, `
+        SELECT `+linkColumns+`
+        FROM links WHERE `+inDomain+` AND original_url = $2 AND is_custom = FALSE AND COALESCE(api_key_id, 0) = $3`, host, canonicalURL, keyID))
+}
+
+func (r *LinksRepo) CreateAlias(ctx context.Context, host, alias, canonicalURL string, keyID int64, expiresAt *time.Time, dedupeWindow *time.Duration) (*domain.Link, error) {
+	// FIXED: 'key' -> 'short_code'
+	l, err := scanLink(r.pool.QueryRow(ctx, `
+        INSERT INTO links (domain, short_code, original_url, is_custom, expires_at, dedupe_window_seconds, api_key_id)
+        VALUES (NULLIF($1, ''), $2, $3, TRUE, $4, $5, $6)
+        RETURNING `+linkColumns+`
+    `, host, alias, canonicalURL, expiresAt, dedupeSeconds(dedupeWindow), ownerID(keyID)))
+
+	if pgErrorCode(err) == uniqueViolation {
+		return nil, domain.ErrAliasInUse
 	}
-	return &l, nil
+	return l, err
 }
 
-func (r *LinksRepo) CreateSystem(ctx context.Context, canonicalURL string, expiresAt *time.Time) (*domain.Link, error) {
-	// 1. Check if it already exists
-	if l, err := r.GetSystemByCanonicalURL(ctx, canonicalURL); err == nil {
+// CreateSystem returns the system link keyID owns for canonicalURL, creating it with a
+// generated key. Keys taken by other links are skipped: the strategy supplies another
+// one until one is free.
+func (r *LinksRepo) CreateSystem(ctx context.Context, host, canonicalURL, alphabet string, keyID int64, expiresAt *time.Time, dedupeWindow *time.Duration) (*domain.Link, error) {
+	if l, err := r.GetSystemByCanonicalURL(ctx, host, canonicalURL, keyID); err == nil {
 		return l, nil
 	}
+	if alphabet == "" {
+		alphabet = r.alphabet
+	}
+	keys, ok := r.keys[alphabet]
+	if !ok {
+		return nil, fmt.Errorf("unknown key alphabet %q", alphabet)
+	}
 
-	// 2. Generate a random short code (Simple & Robust)
-	// We use your internal 'id' package to generate a random string
-	// If id.Generate() is not available, we can use a simple helper.
-	// Assuming id.Generate(length) exists based on your architecture.
-	code := id.Generate(r.minLen)
-
-	// 3. Insert into DB
-	// FIXED: 'key' -> 'short_code'
 	query := `
-        INSERT INTO links (original_url, short_code, is_custom, expires_at)
-        VALUES ($1, $2, FALSE, $3)
-        RETURNING id, created_at, is_disabled
-    `
+        INSERT INTO links (domain, original_url, short_code, is_custom, expires_at, dedupe_window_seconds, api_key_id)
+        VALUES (NULLIF($1, ''), $2, $3, FALSE, $4, $5, $6)
+        RETURNING ` + linkColumns
+	for attempt := range maxKeyAttempts {
+		code, err := keys.Key(ctx, canonicalURL, attempt)
+		if err != nil {
+			return nil, err
+		}
+		l, err := scanLink(r.pool.QueryRow(ctx, query, host, canonicalURL, code, expiresAt, dedupeSeconds(dedupeWindow), ownerID(keyID)))
+		if pgErrorCode(err) != uniqueViolation {
+			return l, err
+		}
+		// Either the URL was shortened concurrently, or the key is taken
+		if l, err := r.GetSystemByCanonicalURL(ctx, host, canonicalURL, keyID); err == nil {
+			return l, nil
+		}
+	}
+	return nil, fmt.Errorf("no free key after %d attempts", maxKeyAttempts)
+}
 
-	var idVal int64
-	var createdAt time.Time
-	var isDisabled bool
+func (r *LinksRepo) Disable(ctx context.Context, host, key string) error {
+	// FIXED: 'key' -> 'short_code'
+	ct, err := r.pool.Exec(ctx, `UPDATE links SET is_disabled = TRUE WHERE `+inDomain+` AND short_code = $2`, host, key)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
 
-	err := r.pool.QueryRow(ctx, query, canonicalURL, code, expiresAt).Scan(&idVal, &createdAt, &isDisabled)
+func (r *LinksRepo) Enable(ctx context.Context, host, key string) error {
+	ct, err := r.pool.Exec(ctx, `UPDATE links SET is_disabled = FALSE WHERE `+inDomain+` AND short_code = $2`, host, key)
 	if err != nil {
-		// If collision (rare), just return error or retry (for MVP, error is fine)
-		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
-			// If we hit a collision, we can check if it was due to the URL already existing
-			// (race condition) or the random code colliding.
-			if l, selErr := r.GetSystemByCanonicalURL(ctx, canonicalURL); selErr == nil {
-				return l, nil
-			}
-			return nil, domain.ErrAliasInUse
-		}
-		return nil, err
+		return err
 	}
+	if ct.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
 
-	return &domain.Link{
-		ID:         idVal,
-		Key:        code,
-		LongURL:    canonicalURL,
-		IsCustom:   false,
-		CreatedAt:  createdAt,
-		ExpiresAt:  expiresAt,
-		IsDisabled: isDisabled,
-	}, nil
+func (r *LinksRepo) Update(ctx context.Context, l *domain.Link) (*domain.Link, error) {
+	return scanLink(r.pool.QueryRow(ctx, `
+        UPDATE links
+        SET original_url = $2, expires_at = $3, is_disabled = $4, dedupe_window_seconds = $5
+        WHERE id = $1
+        RETURNING `+linkColumns, l.ID, l.LongURL, l.ExpiresAt, l.IsDisabled, dedupeSeconds(l.DedupeWindow)))
 }
 
-func (r *LinksRepo) Disable(ctx context.Context, key string) error {
-	// FIXED: 'key' -> 'short_code'
-	ct, err := r.pool.Exec(ctx, `UPDATE links SET is_disabled = TRUE WHERE short_code = $1`, key)
+// Delete removes a link; its clicks and rollups go with it (ON DELETE CASCADE)
+func (r *LinksRepo) Delete(ctx context.Context, host, key string) error {
+	ct, err := r.pool.Exec(ctx, `DELETE FROM links WHERE `+inDomain+` AND short_code = $2`, host, key)
 	if err != nil {
 		return err
 	}
@@ -134,3 +220,48 @@ func (r *LinksRepo) Disable(ctx context.Context, key string) error {
 	}
 	return nil
 }
+
+// ListCreated returns the links keyID created in [from, to), newest first
+func (r *LinksRepo) ListCreated(ctx context.Context, keyID int64, from, to time.Time, limit int) ([]*domain.Link, error) {
+	return r.list(ctx, `
+        SELECT `+linkColumns+`
+        FROM links WHERE api_key_id = $1 AND created_at >= $2 AND created_at < $3
+        ORDER BY created_at DESC
+        LIMIT $4`, keyID, from, to, limit)
+}
+
+// ListExpired returns links that expired before now, most recently expired first
+func (r *LinksRepo) ListExpired(ctx context.Context, now time.Time, limit int) ([]*domain.Link, error) {
+	return r.list(ctx, `
+        SELECT `+linkColumns+`
+        FROM links WHERE expires_at IS NOT NULL AND expires_at <= $1
+        ORDER BY expires_at DESC
+        LIMIT $2`, now, limit)
+}
+
+// ListAfter pages through all links in id order, starting after afterID
+func (r *LinksRepo) ListAfter(ctx context.Context, afterID int64, limit int) ([]*domain.Link, error) {
+	return r.list(ctx, `
+        SELECT `+linkColumns+`
+        FROM links WHERE id > $1
+        ORDER BY id
+        LIMIT $2`, afterID, limit)
+}
+
+func (r *LinksRepo) list(ctx context.Context, query string, args ...any) ([]*domain.Link, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*domain.Link
+	for rows.Next() {
+		l, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+)
+
+type QRLogoRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewQRLogoRepo(db *pgxpool.Pool) *QRLogoRepo {
+	return &QRLogoRepo{DB: db}
+}
+
+func (r *QRLogoRepo) Get(ctx context.Context, keyID int64) (*domain.QRLogo, error) {
+	var l domain.QRLogo
+	err := r.DB.QueryRow(ctx, `SELECT content_type, image, updated_at FROM qr_logos WHERE api_key_id = $1`, keyID).Scan(&l.ContentType, &l.Image, &l.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Put stores the logo of the key, replacing any previous one
+func (r *QRLogoRepo) Put(ctx context.Context, keyID int64, contentType string, image []byte) (*domain.QRLogo, error) {
+	l := domain.QRLogo{ContentType: contentType, Image: image}
+	err := r.DB.QueryRow(ctx, `
+        INSERT INTO qr_logos (api_key_id, content_type, image) VALUES ($1, $2, $3)
+        ON CONFLICT (api_key_id) DO UPDATE SET content_type = EXCLUDED.content_type, image = EXCLUDED.image, updated_at = NOW()
+        RETURNING updated_at`, keyID, contentType, image).Scan(&l.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *QRLogoRepo) Delete(ctx context.Context, keyID int64) error {
+	ct, err := r.DB.Exec(ctx, `DELETE FROM qr_logos WHERE api_key_id = $1`, keyID)
+	if err != nil {
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
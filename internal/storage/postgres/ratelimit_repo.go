@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RateLimitRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewRateLimitRepo(db *pgxpool.Pool) *RateLimitRepo {
+	return &RateLimitRepo{DB: db}
+}
+
+// Take counts a request in one statement: the current window's row is only incremented
+// while the estimate stays below limit. The conditional upsert re-checks the latest row
+// under its lock, so concurrent instances never overshoot the limit.
+func (r *RateLimitRepo) Take(ctx context.Context, key string, start time.Time, window time.Duration, prevWeight float64, limit int) (prev, cur int64, allowed bool, err error) {
+	query := `
+		WITH prev AS (
+			SELECT COALESCE((SELECT count FROM rate_limit_counters WHERE key = $1 AND window_start = $3), 0) AS n
+		),
+		hit AS (
+			INSERT INTO rate_limit_counters AS c (key, window_start, count, expires_at)
+			SELECT $1, $2, 1, $4 FROM prev WHERE prev.n * $5::float8 < $6
+			ON CONFLICT (key, window_start) DO UPDATE SET count = c.count + 1
+			WHERE (SELECT n FROM prev) * $5::float8 + c.count < $6
+			RETURNING c.count
+		)
+		SELECT prev.n::bigint,
+		       COALESCE((SELECT count FROM hit), (SELECT count FROM rate_limit_counters WHERE key = $1 AND window_start = $2), 0)::bigint,
+		       EXISTS (SELECT 1 FROM hit)
+		FROM prev
+	`
+	err = r.DB.QueryRow(ctx, query, key, start, start.Add(-window), start.Add(2*window), prevWeight, limit).Scan(&prev, &cur, &allowed)
+	return prev, cur, allowed, err
+}
+
+// PurgeExpired deletes counters whose windows no longer affect any decision
+func (r *RateLimitRepo) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	ct, err := r.DB.Exec(ctx, `DELETE FROM rate_limit_counters WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
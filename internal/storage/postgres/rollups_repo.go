@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+)
+
+// rollupLockID is the advisory lock key that keeps replicas from rolling up concurrently
+const rollupLockID = 727001
+
+// rollupGrace keeps the watermark behind click inserts whose transaction may not have committed yet
+const rollupGrace = time.Minute
+
+type RollupsRepo struct {
+	DB *pgxpool.Pool
+}
+
+func NewRollupsRepo(db *pgxpool.Pool) *RollupsRepo {
+	return &RollupsRepo{DB: db}
+}
+
+// rollupGranularities are the UTC bucket sizes kept in click_rollups, each with its own watermark
+var rollupGranularities = []domain.Granularity{domain.GranularityHour, domain.GranularityDay}
+
+// Rollup adds the raw clicks inserted since the last run to the hourly and daily rollup
+// tables and advances the watermarks. Clicks are counted in the bucket they occurred in,
+// so clicks the async pipeline writes late still reach buckets that were rolled up before.
+// It is a no-op if another instance holds the lock.
+func (r *RollupsRepo) Rollup(ctx context.Context, now time.Time) error {
+	tx, err := r.DB.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, rollupLockID).Scan(&locked); err != nil {
+		return err
+	}
+	if !locked {
+		return nil
+	}
+
+	until := now.Add(-rollupGrace)
+	for _, g := range rollupGranularities {
+		if err := rollupGranularity(ctx, tx, g, until); err != nil {
+			return fmt.Errorf("rollup %s: %w", g, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func rollupGranularity(ctx context.Context, tx pgx.Tx, g domain.Granularity, until time.Time) error {
+	from := time.Time{}
+	err := tx.QueryRow(ctx, `SELECT rolled_up_to FROM rollup_watermarks WHERE granularity = $1`, string(g)).Scan(&from)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if !from.IsZero() && !from.Before(until) {
+		return nil
+	}
+	lower := pgLowerBound(from)
+
+	// Every click is added exactly once: the inserted_at ranges of consecutive runs do not overlap.
+	// A visitor adds to unique_visitors only with their first click in the bucket.
+	if _, err := tx.Exec(ctx, `
+		WITH batch AS (
+			SELECT c.link_id, `+rollupBucketSQL("c.created_at", "$1")+` AS bucket, c.is_bot, c.created_at,
+			       c.is_duplicate, c.source, c.visitor_hash,
+			       `+firstVisitSQL("c", rollupBucketSQL("c.created_at", "$1"), "$1", "$2")+` AS first_visit
+			FROM clicks c
+			WHERE c.inserted_at >= $2 AND c.inserted_at < $3
+		)
+		INSERT INTO click_rollups (link_id, granularity, bucket_start, is_bot, clicks, duplicate_clicks, qr_clicks, unique_visitors, last_clicked_at)
+		SELECT link_id, $1, bucket, is_bot,
+		       COUNT(*), COUNT(*) FILTER (WHERE is_duplicate), COUNT(*) FILTER (WHERE source = 'qr'),
+		       COUNT(DISTINCT visitor_hash) FILTER (WHERE first_visit), MAX(created_at)
+		FROM batch
+		GROUP BY link_id, bucket, is_bot
+		ON CONFLICT (link_id, granularity, bucket_start, is_bot) DO UPDATE
+		SET clicks = click_rollups.clicks + EXCLUDED.clicks,
+		    duplicate_clicks = click_rollups.duplicate_clicks + EXCLUDED.duplicate_clicks,
+		    qr_clicks = click_rollups.qr_clicks + EXCLUDED.qr_clicks,
+		    unique_visitors = click_rollups.unique_visitors + EXCLUDED.unique_visitors,
+		    last_clicked_at = GREATEST(click_rollups.last_clicked_at, EXCLUDED.last_clicked_at)
+	`, string(g), lower, until); err != nil {
+		return fmt.Errorf("clicks: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO click_rollup_dimensions (link_id, granularity, bucket_start, dimension, value, is_bot, clicks)
+		SELECT link_id, $1, `+rollupBucketSQL("created_at", "$1")+` AS bucket,
+		       'country', COALESCE(country_code, 'ZZ') AS value, is_bot, COUNT(*)
+		FROM clicks
+		WHERE inserted_at >= $2 AND inserted_at < $3
+		GROUP BY link_id, bucket, value, is_bot
+		UNION ALL
+		SELECT link_id, $1, `+rollupBucketSQL("created_at", "$1")+` AS bucket,
+		       'referrer', `+referrerHostSQL+` AS value, is_bot, COUNT(*)
+		FROM clicks
+		WHERE inserted_at >= $2 AND inserted_at < $3
+		GROUP BY link_id, bucket, value, is_bot
+		ON CONFLICT (link_id, granularity, bucket_start, dimension, value, is_bot) DO UPDATE
+		SET clicks = click_rollup_dimensions.clicks + EXCLUDED.clicks
+	`, string(g), lower, until); err != nil {
+		return fmt.Errorf("dimensions: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO rollup_watermarks (granularity, rolled_up_to) VALUES ($1, $2)
+		ON CONFLICT (granularity) DO UPDATE SET rolled_up_to = EXCLUDED.rolled_up_to
+	`, string(g), until)
+	return err
+}
+
+// rollupBucketSQL returns the start of the UTC rollup bucket of the given granularity
+// containing the timestamptz col; granularity is an SQL expression
+func rollupBucketSQL(col, granularity string) string {
+	return `date_trunc(` + granularity + `, ` + col + ` AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'`
+}
+
+// firstVisitSQL is true when no click inserted before the timestamptz expression before
+// has the visitor of click c in the same rollup bucket, i.e. c makes its visitor unique
+// there. Clicks arriving later than the raw click retention may count a visitor twice.
+func firstVisitSQL(c, bucket, granularity, before string) string {
+	return `NOT EXISTS (
+				SELECT 1 FROM clicks p
+				WHERE p.link_id = ` + c + `.link_id AND p.visitor_hash = ` + c + `.visitor_hash AND p.is_bot = ` + c + `.is_bot
+				  AND p.created_at >= ` + bucket + ` AND p.created_at < ` + bucket + ` + ('1 ' || ` + granularity + `)::interval
+				  AND p.inserted_at < ` + before + `)`
+}
+
+// referrerHostSQL extracts the lower-cased host of clicks.referer, or an empty string for direct traffic
+const referrerHostSQL = `COALESCE(lower(substring(referer from '^[A-Za-z][A-Za-z0-9+.-]*://([^/:?#]+)')), '')`
+
+// pgLowerBound maps a missing watermark to -infinity so the first run covers all history
+func pgLowerBound(t time.Time) pgtype.Timestamptz {
+	if t.IsZero() {
+		return pgtype.Timestamptz{InfinityModifier: pgtype.NegativeInfinity, Valid: true}
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
@@ -2,9 +2,12 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -16,49 +19,265 @@ func NewStatsRepo(db *pgxpool.Pool) *StatsRepo {
 	return &StatsRepo{DB: db}
 }
 
-// Totals returns the total clicks and the timestamp of the last click
-func (r *StatsRepo) Totals(ctx context.Context, linkID int64) (int64, *time.Time, error) {
-	// Query the 'clicks' table directly
+// watermarkSQL is a CTE "wm" with the insertion time up to which raw clicks have been added
+// to the rollups of a granularity (-infinity before the first rollup); granularity is an SQL
+// expression. Reads combine the rollups with raw clicks inserted since.
+func watermarkSQL(granularity string) string {
+	return `
+	wm AS (
+		SELECT COALESCE(
+			(SELECT rolled_up_to FROM rollup_watermarks WHERE granularity = ` + granularity + `),
+			'-infinity'::timestamptz) AS t
+	)`
+}
+
+// hourWatermarkSQL is the watermark of the hourly rollups
+var hourWatermarkSQL = watermarkSQL("'hour'")
+
+// wholeBucketsSQL is a CTE "whole" holding the range [lo, hi) of whole UTC rollup buckets
+// inside [from, to); from, to and granularity are SQL expressions.
+func wholeBucketsSQL(from, to, granularity string) string {
+	return `
+	whole AS (
+		SELECT date_trunc(` + granularity + `, (` + from + `::timestamptz AT TIME ZONE 'UTC') + ('1 ' || ` + granularity + `)::interval - interval '1 microsecond') AT TIME ZONE 'UTC' AS lo,
+		       date_trunc(` + granularity + `, ` + to + `::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS hi
+	)`
+}
+
+// wholeHoursSQL is wholeBucketsSQL for hours. Hourly rollups are only read for those hours,
+// the partial hours at either edge of the range come from raw clicks.
+func wholeHoursSQL(from, to string) string {
+	return wholeBucketsSQL(from, to, "'hour'")
+}
+
+// bucketSQL returns the start of the bucket containing the timestamptz col, aligned to
+// the wall clock of time zone tz; granularity and tz are SQL expressions. Hours are cut
+// in absolute time like domain.Granularity.Truncate, so the repeated hour of a DST
+// fall-back is not merged into one bucket.
+func bucketSQL(col, granularity, tz string) string {
+	local := col + ` AT TIME ZONE ` + tz
+	return `CASE WHEN ` + granularity + ` = 'hour'
+			THEN ` + col + ` - ((` + local + `) - date_trunc('hour', ` + local + `))
+			ELSE date_trunc(` + granularity + `, ` + local + `) AT TIME ZONE ` + tz + ` END`
+}
+
+// rawClicksSince returns the earliest click time a read of [from, to) bucketed in loc takes
+// from raw clicks rather than from the hourly rollups. Clicks inserted since the last rollup
+// are left out: the purge never deletes them.
+func rawClicksSince(from, to time.Time, loc *time.Location) (time.Time, bool) {
+	_, fromOffset := from.In(loc).Zone()
+	_, toOffset := to.In(loc).Zone()
+	switch {
+	case fromOffset%3600 != 0 || toOffset%3600 != 0, !from.Truncate(time.Hour).Equal(from):
+		return from, true
+	case !to.Truncate(time.Hour).Equal(to):
+		return to.Truncate(time.Hour), true
+	}
+	return time.Time{}, false
+}
+
+// checkRawClicks fails with domain.ErrClicksPurged if a read of [from, to) in loc needs raw
+// clicks from before the purge horizon, which would undercount compared to the rollups.
+func checkRawClicks(ctx context.Context, db *pgxpool.Pool, from, to time.Time, loc *time.Location) error {
+	since, ok := rawClicksSince(from, to, loc)
+	if !ok {
+		return nil
+	}
+	var horizon time.Time
+	err := db.QueryRow(ctx, `SELECT purged_before FROM click_purge_horizon`).Scan(&horizon)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if since.Before(horizon) {
+		return domain.ErrClicksPurged
+	}
+	return nil
+}
+
+// Totals returns the raw, deduplicated and QR click totals and the timestamp of the last click
+func (r *StatsRepo) Totals(ctx context.Context, linkID int64, includeBots bool) (storage.Totals, error) {
+	// Rolled up clicks come from rollups, the rest from raw clicks
 	query := `
-		SELECT COUNT(*), MAX(created_at)
-		FROM clicks
-		WHERE link_id = $1
+		WITH` + hourWatermarkSQL + `,
+		rolled AS (
+			SELECT COALESCE(SUM(r.clicks), 0) AS clicks, COALESCE(SUM(r.duplicate_clicks), 0) AS duplicates,
+			       COALESCE(SUM(r.qr_clicks), 0) AS qr, MAX(r.last_clicked_at) AS last
+			FROM click_rollups r
+			WHERE r.link_id = $1 AND r.granularity = 'hour'
+			  AND ($2 OR NOT r.is_bot)
+		),
+		recent AS (
+			SELECT COUNT(*) AS clicks, COUNT(*) FILTER (WHERE c.is_duplicate) AS duplicates,
+			       COUNT(*) FILTER (WHERE c.source = 'qr') AS qr, MAX(c.created_at) AS last
+			FROM clicks c, wm
+			WHERE c.link_id = $1 AND c.inserted_at >= wm.t
+			  AND ($2 OR NOT c.is_bot)
+		)
+		SELECT (rolled.clicks + recent.clicks)::bigint,
+		       (rolled.duplicates + recent.duplicates)::bigint,
+		       (rolled.qr + recent.qr)::bigint,
+		       GREATEST(rolled.last, recent.last)
+		FROM rolled, recent
 	`
-	var total int64
-	var lastClick *time.Time
+	var t storage.Totals
+	var duplicates int64
+
+	err := r.DB.QueryRow(ctx, query, linkID, includeBots).Scan(&t.Clicks, &duplicates, &t.QRClicks, &t.LastClickedAt)
+	if err != nil {
+		return storage.Totals{}, err
+	}
+	t.DedupedClicks = t.Clicks - duplicates
+	return t, nil
+}
 
-	err := r.DB.QueryRow(ctx, query, linkID).Scan(&total, &lastClick)
+// Series returns clicks grouped into buckets of the given granularity, with the unique
+// visitors of the buckets that match a rollup bucket (see Granularity.CountsUniqueVisitors).
+// Buckets are aligned to the wall clock of loc and only non-empty buckets are returned.
+// It fails with domain.ErrClicksPurged if the range needs raw clicks that were purged.
+func (r *StatsRepo) Series(ctx context.Context, linkID int64, from, to time.Time, granularity domain.Granularity, loc *time.Location, includeBots bool) ([]storage.BucketCount, error) {
+	if err := checkRawClicks(ctx, r.DB, from, to, loc); err != nil {
+		return nil, err
+	}
+	buckets, err := r.clickSeries(ctx, linkID, from, to, granularity, loc, includeBots)
 	if err != nil {
-		return 0, nil, err
+		return nil, err
+	}
+	if err := r.addUniqueVisitors(ctx, linkID, from, to, granularity, loc, includeBots, buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+func (r *StatsRepo) clickSeries(ctx context.Context, linkID int64, from, to time.Time, granularity domain.Granularity, loc *time.Location, includeBots bool) ([]storage.BucketCount, error) {
+	// Hourly rollups can only be re-bucketed exactly in zones with whole-hour offsets
+	_, fromOffset := from.In(loc).Zone()
+	_, toOffset := to.In(loc).Zone()
+	if fromOffset%3600 != 0 || toOffset%3600 != 0 {
+		return r.rawSeries(ctx, linkID, from, to, granularity, loc, includeBots)
 	}
-	return total, lastClick, nil
+
+	// Rolled up clicks come from rollups, the rest from raw clicks.
+	// Truncating the local timestamp and converting it back yields the bucket start as timestamptz.
+	query := `
+		WITH` + hourWatermarkSQL + `,` + wholeHoursSQL("$2", "$3") + `,
+		src AS (
+			SELECT r.bucket_start AS at, r.clicks
+			FROM click_rollups r, whole
+			WHERE r.link_id = $1 AND r.granularity = 'hour'
+			  AND r.bucket_start >= whole.lo AND r.bucket_start < whole.hi
+			  AND ($6 OR NOT r.is_bot)
+			UNION ALL
+			SELECT c.created_at, 1
+			FROM clicks c, wm, whole
+			WHERE c.link_id = $1 AND c.created_at >= $2 AND c.created_at < $3
+			  AND (c.inserted_at >= wm.t OR c.created_at < whole.lo OR c.created_at >= whole.hi)
+			  AND ($6 OR NOT c.is_bot)
+		)
+		SELECT ` + bucketSQL("at", "$4", "$5") + ` AS bucket, SUM(clicks)::bigint AS count
+		FROM src
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`
+	return r.querySeries(ctx, query, linkID, from, to, granularity, loc, includeBots)
 }
 
-// Daily returns a list of clicks grouped by day (YYYY-MM-DD)
-func (r *StatsRepo) Daily(ctx context.Context, linkID int64, fromUTC, toUTC time.Time) ([]storage.DayCount, error) {
-	// We use date_trunc to group by day.
-	// Note: We use 'created_at' because that's what we defined in the clicks table.
+// rawSeries buckets raw clicks directly, for zones whose boundaries do not align with hourly rollups
+func (r *StatsRepo) rawSeries(ctx context.Context, linkID int64, from, to time.Time, granularity domain.Granularity, loc *time.Location, includeBots bool) ([]storage.BucketCount, error) {
 	query := `
-		SELECT date_trunc('day', created_at) as day, COUNT(*) as count
+		SELECT ` + bucketSQL("created_at", "$4", "$5") + ` AS bucket, COUNT(*) AS count
 		FROM clicks
-		WHERE link_id = $1 AND created_at >= $2 AND created_at <= $3
-		GROUP BY day
-		ORDER BY day ASC
+		WHERE link_id = $1 AND created_at >= $2 AND created_at < $3
+		  AND ($6 OR NOT is_bot)
+		GROUP BY bucket
+		ORDER BY bucket ASC
+	`
+	return r.querySeries(ctx, query, linkID, from, to, granularity, loc, includeBots)
+}
+
+// addUniqueVisitors sets the unique visitors of the buckets that match a UTC rollup bucket.
+// Like the rollup job, a recent click only adds its visitor if the rolled up clicks of the
+// bucket do not already have them.
+func (r *StatsRepo) addUniqueVisitors(ctx context.Context, linkID int64, from, to time.Time, granularity domain.Granularity, loc *time.Location, includeBots bool, buckets []storage.BucketCount) error {
+	counted := false
+	for _, b := range buckets {
+		if granularity.CountsUniqueVisitors(b.Start.In(loc), from, to) {
+			counted = true
+			break
+		}
+	}
+	if !counted {
+		return nil
+	}
+
+	query := `
+		WITH` + watermarkSQL("$4") + `,` + wholeBucketsSQL("$2", "$3", "$4") + `,
+		recent AS (
+			SELECT c.link_id, c.is_bot, c.visitor_hash, ` + rollupBucketSQL("c.created_at", "$4") + ` AS bucket
+			FROM clicks c, wm, whole
+			WHERE c.link_id = $1 AND c.inserted_at >= wm.t
+			  AND c.created_at >= whole.lo AND c.created_at < whole.hi
+			  AND ($5 OR NOT c.is_bot)
+		),
+		src AS (
+			SELECT r.bucket_start AS bucket, r.unique_visitors AS visitors
+			FROM click_rollups r, whole
+			WHERE r.link_id = $1 AND r.granularity = $4
+			  AND r.bucket_start >= whole.lo AND r.bucket_start < whole.hi
+			  AND ($5 OR NOT r.is_bot)
+			UNION ALL
+			SELECT recent.bucket, COUNT(DISTINCT recent.visitor_hash)
+			FROM recent, wm
+			WHERE ` + firstVisitSQL("recent", "recent.bucket", "$4", "wm.t") + `
+			GROUP BY recent.bucket, recent.is_bot
+		)
+		SELECT bucket, SUM(visitors)::bigint
+		FROM src
+		GROUP BY bucket
 	`
+	rows, err := r.DB.Query(ctx, query, linkID, from, to, string(granularity), includeBots)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	visitors := make(map[int64]int64)
+	for rows.Next() {
+		var start time.Time
+		var n int64
+		if err := rows.Scan(&start, &n); err != nil {
+			return err
+		}
+		visitors[start.Unix()] = n
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, b := range buckets {
+		if granularity.CountsUniqueVisitors(b.Start.In(loc), from, to) {
+			buckets[i].UniqueVisitors = visitors[b.Start.Unix()]
+		}
+	}
+	return nil
+}
 
-	rows, err := r.DB.Query(ctx, query, linkID, fromUTC, toUTC)
+func (r *StatsRepo) querySeries(ctx context.Context, query string, linkID int64, from, to time.Time, granularity domain.Granularity, loc *time.Location, includeBots bool) ([]storage.BucketCount, error) {
+	rows, err := r.DB.Query(ctx, query, linkID, from, to, string(granularity), loc.String(), includeBots)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var results []storage.DayCount
+	var results []storage.BucketCount
 	for rows.Next() {
-		var d storage.DayCount
-		if err := rows.Scan(&d.Day, &d.Clicks); err != nil {
+		var b storage.BucketCount
+		if err := rows.Scan(&b.Start, &b.Clicks); err != nil {
 			return nil, err
 		}
-		results = append(results, d)
+		results = append(results, b)
 	}
 
 	if err := rows.Err(); err != nil {
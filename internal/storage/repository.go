@@ -7,24 +7,147 @@ import (
 	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
 )
 
+// LinksRepo stores links. Keys are unique per domain: methods taking a host work in
+// that custom domain's namespace, or in BASE_URL's when host is empty. keyID is the
+// API key owning a link, 0 for none; each owner has its own system link per URL.
 type LinksRepo interface {
-	GetByKey(ctx context.Context, key string) (*domain.Link, error)
-	GetSystemByCanonicalURL(ctx context.Context, canonicalURL string) (*domain.Link, error)
-	CreateSystem(ctx context.Context, canonicalURL string, expiresAt *time.Time) (*domain.Link, error)
-	CreateAlias(ctx context.Context, alias string, canonicalURL string, expiresAt *time.Time) (*domain.Link, error)
-	Disable(ctx context.Context, key string) error
+	GetByKey(ctx context.Context, host, key string) (*domain.Link, error)
+	GetSystemByCanonicalURL(ctx context.Context, host, canonicalURL string, keyID int64) (*domain.Link, error)
+	// CreateSystem generates the key in alphabet (an id.Alphabets name), or in the
+	// configured one when empty
+	CreateSystem(ctx context.Context, host, canonicalURL, alphabet string, keyID int64, expiresAt *time.Time, dedupeWindow *time.Duration) (*domain.Link, error)
+	CreateAlias(ctx context.Context, host, alias, canonicalURL string, keyID int64, expiresAt *time.Time, dedupeWindow *time.Duration) (*domain.Link, error)
+	Disable(ctx context.Context, host, key string) error
+	Enable(ctx context.Context, host, key string) error
+	// Update stores the destination, expiry, disabled flag and dedupe window of l
+	Update(ctx context.Context, l *domain.Link) (*domain.Link, error)
+	// Delete removes a link with its clicks and rollups
+	Delete(ctx context.Context, host, key string) error
+	// ListCreated returns the links keyID created in [from, to), newest first
+	ListCreated(ctx context.Context, keyID int64, from, to time.Time, limit int) ([]*domain.Link, error)
+	// ListExpired returns links that expired before now, most recently expired first
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]*domain.Link, error)
+	// ListAfter pages through all links in id order, starting after afterID
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]*domain.Link, error)
+}
+
+// APIKeysRepo stores API keys by hash; plaintext keys are never persisted
+type APIKeysRepo interface {
+	Create(ctx context.Context, name, tier, prefix, hash string) (*domain.APIKey, error)
+	// GetByHash returns the key with that hash, revoked or not, and records its use
+	// (last_used_at is updated at most once a minute)
+	GetByHash(ctx context.Context, hash string) (*domain.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+	List(ctx context.Context) ([]*domain.APIKey, error)
+}
+
+// DomainsRepo stores the custom domains and the API keys allowed to use each
+type DomainsRepo interface {
+	// Create returns domain.ErrDomainExists for a registered host
+	Create(ctx context.Context, host string) (*domain.CustomDomain, error)
+	Get(ctx context.Context, host string) (*domain.CustomDomain, error)
+	List(ctx context.Context) ([]*domain.CustomDomain, error)
+	// Delete returns domain.ErrDomainInUse while links use the domain
+	Delete(ctx context.Context, host string) error
+	// Grant lets an API key create links on the domain; Revoke takes that back
+	Grant(ctx context.Context, host string, keyID int64) error
+	Revoke(ctx context.Context, host string, keyID int64) error
+}
+
+// QRLogoRepo stores the logo each API key overlays on QR codes
+type QRLogoRepo interface {
+	// Get returns domain.ErrNotFound when the key has not uploaded a logo
+	Get(ctx context.Context, keyID int64) (*domain.QRLogo, error)
+	Put(ctx context.Context, keyID int64, contentType string, image []byte) (*domain.QRLogo, error)
+	Delete(ctx context.Context, keyID int64) error
+}
+
+// RateLimitRepo keeps the fixed window counters behind rate.Shared
+type RateLimitRepo interface {
+	// Take counts one request for key in the window starting at start unless
+	// prev*prevWeight + current already reaches limit, and returns both counts afterwards
+	Take(ctx context.Context, key string, start time.Time, window time.Duration, prevWeight float64, limit int) (prev, cur int64, allowed bool, err error)
+	// PurgeExpired deletes counters of windows that ended more than a window before now
+	PurgeExpired(ctx context.Context, now time.Time) (int64, error)
 }
 
 type ClicksRepo interface {
-	Insert(ctx context.Context, linkID int64, occurredAt time.Time, visitorHash *string, countryCode *string, userAgent *string, referer *string) error
+	Insert(ctx context.Context, c *domain.Click) error
+	// PurgeBefore deletes up to limit raw clicks older than before that have already been rolled up
+	PurgeBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+	// DeleteByLink erases all raw clicks and rollups of a link
+	DeleteByLink(ctx context.Context, linkID int64) (int64, error)
+	// DeleteByVisitor erases all raw clicks recorded for a visitor hash
+	DeleteByVisitor(ctx context.Context, visitorHash string) (int64, error)
+	// PageEnd returns the position of the limit-th click of cr, or nil if cr holds fewer clicks
+	PageEnd(ctx context.Context, cr ClickRange, limit int) (*ClickCursor, error)
+	// Each streams the clicks of cr in time order, stopping after end when it is set
+	Each(ctx context.Context, cr ClickRange, end *ClickCursor, fn func(*domain.Click) error) error
+}
+
+// ClickCursor is the keyset position of a raw click
+type ClickCursor struct {
+	OccurredAt time.Time
+	ID         int64
 }
 
+// ClickRange selects the raw clicks of a link in [From, To) positioned after After
+type ClickRange struct {
+	LinkID int64
+	From   time.Time
+	To     time.Time
+	After  *ClickCursor
+}
+
+// StatsRepo reads click analytics. Bot clicks are excluded unless includeBots is set.
 type StatsRepo interface {
-	Totals(ctx context.Context, linkID int64) (clicksTotal int64, lastClickedAt *time.Time, err error)
-	Daily(ctx context.Context, linkID int64, fromUTC, toUTC time.Time) ([]DayCount, error)
+	Totals(ctx context.Context, linkID int64, includeBots bool) (Totals, error)
+	// Series fails with domain.ErrClicksPurged when the range needs raw clicks the retention purge deleted
+	Series(ctx context.Context, linkID int64, from, to time.Time, granularity domain.Granularity, loc *time.Location, includeBots bool) ([]BucketCount, error)
 }
 
-type DayCount struct {
-	Day    time.Time
+// RollupsRepo maintains the pre-aggregated analytics read by StatsRepo
+type RollupsRepo interface {
+	Rollup(ctx context.Context, now time.Time) error
+}
+
+// DashboardRepo aggregates click analytics across the links created with API key keyID
+type DashboardRepo interface {
+	TopLinks(ctx context.Context, keyID int64, from, to time.Time, includeBots bool, limit int) ([]LinkClicks, error)
+	Series(ctx context.Context, keyID int64, from, to time.Time, granularity domain.Granularity, loc *time.Location, includeBots bool) ([]BucketCount, error)
+	// TopValues ranks the values of a rollup dimension ("country" or "referrer") by clicks
+	TopValues(ctx context.Context, keyID int64, dimension string, from, to time.Time, includeBots bool, limit int) ([]ValueCount, error)
+}
+
+// LinkClicks is a link with its click count over a period
+type LinkClicks struct {
+	Link   *domain.Link
+	Clicks int64
+}
+
+// ValueCount is the click count of one dimension value
+type ValueCount struct {
+	Value  string
 	Clicks int64
 }
+
+// Rollup dimensions
+const (
+	DimensionCountry  = "country"
+	DimensionReferrer = "referrer"
+)
+
+// Totals are all-time click counts of a link
+type Totals struct {
+	Clicks        int64 // every recorded click
+	DedupedClicks int64 // clicks minus repeats of a visitor inside the link's dedupe window
+	QRClicks      int64 // clicks through the ?src=qr URL encoded in QR codes
+	LastClickedAt *time.Time
+}
+
+// BucketCount is the number of clicks in the series bucket starting at Start
+type BucketCount struct {
+	Start          time.Time
+	Clicks         int64
+	UniqueVisitors int64 // only set where Granularity.CountsUniqueVisitors holds for the bucket
+}
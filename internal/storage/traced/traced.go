@@ -0,0 +1,375 @@
+// Package traced wraps storage repositories so every call runs in a child span of the
+// request span, showing how much of a request went to the database.
+package traced
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/domain"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/storage"
+	"github.com/Kristiii101/GO_URL_Shortener_ATAD/internal/tracing"
+)
+
+func start(ctx context.Context, t *tracing.Tracer, op string, attrs ...tracing.Attr) (context.Context, *tracing.Span) {
+	attrs = append(attrs, tracing.Attr{Key: "db.system", Value: "postgresql"})
+	return t.Start(ctx, op, tracing.KindClient, attrs...)
+}
+
+func end(s *tracing.Span, err error) {
+	// A missing link is an expected outcome, not a failed query
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		s.RecordError(err)
+	}
+	s.End()
+}
+
+type LinksRepo struct {
+	Next   storage.LinksRepo
+	Tracer *tracing.Tracer
+}
+
+func NewLinksRepo(next storage.LinksRepo, t *tracing.Tracer) *LinksRepo {
+	return &LinksRepo{Next: next, Tracer: t}
+}
+
+// linkAttrs identify a link in spans
+func linkAttrs(host, key string) []tracing.Attr {
+	attrs := []tracing.Attr{{Key: "link.key", Value: key}}
+	if host != "" {
+		attrs = append(attrs, tracing.Attr{Key: "link.domain", Value: host})
+	}
+	return attrs
+}
+
+func (r *LinksRepo) GetByKey(ctx context.Context, host, key string) (*domain.Link, error) {
+	ctx, s := start(ctx, r.Tracer, "LinksRepo.GetByKey", linkAttrs(host, key)...)
+	l, err := r.Next.GetByKey(ctx, host, key)
+	end(s, err)
+	return l, err
+}
+
+func (r *LinksRepo) GetSystemByCanonicalURL(ctx context.Context, host, canonicalURL string, keyID int64) (*domain.Link, error) {
+	ctx, s := start(ctx, r.Tracer, "LinksRepo.GetSystemByCanonicalURL")
+	l, err := r.Next.GetSystemByCanonicalURL(ctx, host, canonicalURL, keyID)
+	end(s, err)
+	return l, err
+}
+
+func (r *LinksRepo) CreateSystem(ctx context.Context, host, canonicalURL, alphabet string, keyID int64, expiresAt *time.Time, dedupeWindow *time.Duration) (*domain.Link, error) {
+	ctx, s := start(ctx, r.Tracer, "LinksRepo.CreateSystem")
+	l, err := r.Next.CreateSystem(ctx, host, canonicalURL, alphabet, keyID, expiresAt, dedupeWindow)
+	end(s, err)
+	return l, err
+}
+
+func (r *LinksRepo) CreateAlias(ctx context.Context, host, alias, canonicalURL string, keyID int64, expiresAt *time.Time, dedupeWindow *time.Duration) (*domain.Link, error) {
+	ctx, s := start(ctx, r.Tracer, "LinksRepo.CreateAlias", linkAttrs(host, alias)...)
+	l, err := r.Next.CreateAlias(ctx, host, alias, canonicalURL, keyID, expiresAt, dedupeWindow)
+	end(s, err)
+	return l, err
+}
+
+func (r *LinksRepo) Disable(ctx context.Context, host, key string) error {
+	ctx, s := start(ctx, r.Tracer, "LinksRepo.Disable", linkAttrs(host, key)...)
+	err := r.Next.Disable(ctx, host, key)
+	end(s, err)
+	return err
+}
+
+func (r *LinksRepo) Enable(ctx context.Context, host, key string) error {
+	ctx, s := start(ctx, r.Tracer, "LinksRepo.Enable", linkAttrs(host, key)...)
+	err := r.Next.Enable(ctx, host, key)
+	end(s, err)
+	return err
+}
+
+func (r *LinksRepo) Update(ctx context.Context, l *domain.Link) (*domain.Link, error) {
+	ctx, s := start(ctx, r.Tracer, "LinksRepo.Update", linkAttrs(l.Domain, l.Key)...)
+	out, err := r.Next.Update(ctx, l)
+	end(s, err)
+	return out, err
+}
+
+func (r *LinksRepo) Delete(ctx context.Context, host, key string) error {
+	ctx, s := start(ctx, r.Tracer, "LinksRepo.Delete", linkAttrs(host, key)...)
+	err := r.Next.Delete(ctx, host, key)
+	end(s, err)
+	return err
+}
+
+func (r *LinksRepo) ListExpired(ctx context.Context, now time.Time, limit int) ([]*domain.Link, error) {
+	ctx, s := start(ctx, r.Tracer, "LinksRepo.ListExpired")
+	ls, err := r.Next.ListExpired(ctx, now, limit)
+	end(s, err)
+	return ls, err
+}
+
+func (r *LinksRepo) ListAfter(ctx context.Context, afterID int64, limit int) ([]*domain.Link, error) {
+	ctx, s := start(ctx, r.Tracer, "LinksRepo.ListAfter")
+	ls, err := r.Next.ListAfter(ctx, afterID, limit)
+	end(s, err)
+	return ls, err
+}
+
+func (r *LinksRepo) ListCreated(ctx context.Context, keyID int64, from, to time.Time, limit int) ([]*domain.Link, error) {
+	ctx, s := start(ctx, r.Tracer, "LinksRepo.ListCreated")
+	ls, err := r.Next.ListCreated(ctx, keyID, from, to, limit)
+	end(s, err)
+	return ls, err
+}
+
+type ClicksRepo struct {
+	Next   storage.ClicksRepo
+	Tracer *tracing.Tracer
+}
+
+func NewClicksRepo(next storage.ClicksRepo, t *tracing.Tracer) *ClicksRepo {
+	return &ClicksRepo{Next: next, Tracer: t}
+}
+
+func (r *ClicksRepo) Insert(ctx context.Context, c *domain.Click) error {
+	ctx, s := start(ctx, r.Tracer, "ClicksRepo.Insert", tracing.Attr{Key: "link.id", Value: c.LinkID})
+	err := r.Next.Insert(ctx, c)
+	end(s, err)
+	return err
+}
+
+func (r *ClicksRepo) PurgeBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	ctx, s := start(ctx, r.Tracer, "ClicksRepo.PurgeBefore")
+	n, err := r.Next.PurgeBefore(ctx, before, limit)
+	s.SetAttr("db.rows_affected", n)
+	end(s, err)
+	return n, err
+}
+
+func (r *ClicksRepo) DeleteByLink(ctx context.Context, linkID int64) (int64, error) {
+	ctx, s := start(ctx, r.Tracer, "ClicksRepo.DeleteByLink", tracing.Attr{Key: "link.id", Value: linkID})
+	n, err := r.Next.DeleteByLink(ctx, linkID)
+	s.SetAttr("db.rows_affected", n)
+	end(s, err)
+	return n, err
+}
+
+func (r *ClicksRepo) DeleteByVisitor(ctx context.Context, visitorHash string) (int64, error) {
+	ctx, s := start(ctx, r.Tracer, "ClicksRepo.DeleteByVisitor")
+	n, err := r.Next.DeleteByVisitor(ctx, visitorHash)
+	s.SetAttr("db.rows_affected", n)
+	end(s, err)
+	return n, err
+}
+
+func (r *ClicksRepo) PageEnd(ctx context.Context, cr storage.ClickRange, limit int) (*storage.ClickCursor, error) {
+	ctx, s := start(ctx, r.Tracer, "ClicksRepo.PageEnd", tracing.Attr{Key: "link.id", Value: cr.LinkID})
+	c, err := r.Next.PageEnd(ctx, cr, limit)
+	end(s, err)
+	return c, err
+}
+
+func (r *ClicksRepo) Each(ctx context.Context, cr storage.ClickRange, endAt *storage.ClickCursor, fn func(*domain.Click) error) error {
+	ctx, s := start(ctx, r.Tracer, "ClicksRepo.Each", tracing.Attr{Key: "link.id", Value: cr.LinkID})
+	var n int64
+	err := r.Next.Each(ctx, cr, endAt, func(c *domain.Click) error {
+		n++
+		return fn(c)
+	})
+	s.SetAttr("db.rows_returned", n)
+	end(s, err)
+	return err
+}
+
+type StatsRepo struct {
+	Next   storage.StatsRepo
+	Tracer *tracing.Tracer
+}
+
+func NewStatsRepo(next storage.StatsRepo, t *tracing.Tracer) *StatsRepo {
+	return &StatsRepo{Next: next, Tracer: t}
+}
+
+func (r *StatsRepo) Totals(ctx context.Context, linkID int64, includeBots bool) (storage.Totals, error) {
+	ctx, s := start(ctx, r.Tracer, "StatsRepo.Totals", tracing.Attr{Key: "link.id", Value: linkID})
+	t, err := r.Next.Totals(ctx, linkID, includeBots)
+	end(s, err)
+	return t, err
+}
+
+func (r *StatsRepo) Series(ctx context.Context, linkID int64, from, to time.Time, granularity domain.Granularity, loc *time.Location, includeBots bool) ([]storage.BucketCount, error) {
+	ctx, s := start(ctx, r.Tracer, "StatsRepo.Series",
+		tracing.Attr{Key: "link.id", Value: linkID}, tracing.Attr{Key: "stats.granularity", Value: string(granularity)})
+	b, err := r.Next.Series(ctx, linkID, from, to, granularity, loc, includeBots)
+	end(s, err)
+	return b, err
+}
+
+type DashboardRepo struct {
+	Next   storage.DashboardRepo
+	Tracer *tracing.Tracer
+}
+
+func NewDashboardRepo(next storage.DashboardRepo, t *tracing.Tracer) *DashboardRepo {
+	return &DashboardRepo{Next: next, Tracer: t}
+}
+
+func (r *DashboardRepo) TopLinks(ctx context.Context, keyID int64, from, to time.Time, includeBots bool, limit int) ([]storage.LinkClicks, error) {
+	ctx, s := start(ctx, r.Tracer, "DashboardRepo.TopLinks")
+	l, err := r.Next.TopLinks(ctx, keyID, from, to, includeBots, limit)
+	end(s, err)
+	return l, err
+}
+
+func (r *DashboardRepo) Series(ctx context.Context, keyID int64, from, to time.Time, granularity domain.Granularity, loc *time.Location, includeBots bool) ([]storage.BucketCount, error) {
+	ctx, s := start(ctx, r.Tracer, "DashboardRepo.Series", tracing.Attr{Key: "stats.granularity", Value: string(granularity)})
+	b, err := r.Next.Series(ctx, keyID, from, to, granularity, loc, includeBots)
+	end(s, err)
+	return b, err
+}
+
+func (r *DashboardRepo) TopValues(ctx context.Context, keyID int64, dimension string, from, to time.Time, includeBots bool, limit int) ([]storage.ValueCount, error) {
+	ctx, s := start(ctx, r.Tracer, "DashboardRepo.TopValues", tracing.Attr{Key: "stats.dimension", Value: dimension})
+	v, err := r.Next.TopValues(ctx, keyID, dimension, from, to, includeBots, limit)
+	end(s, err)
+	return v, err
+}
+
+type APIKeysRepo struct {
+	Next   storage.APIKeysRepo
+	Tracer *tracing.Tracer
+}
+
+func NewAPIKeysRepo(next storage.APIKeysRepo, t *tracing.Tracer) *APIKeysRepo {
+	return &APIKeysRepo{Next: next, Tracer: t}
+}
+
+func (r *APIKeysRepo) Create(ctx context.Context, name, tier, prefix, hash string) (*domain.APIKey, error) {
+	ctx, s := start(ctx, r.Tracer, "APIKeysRepo.Create")
+	k, err := r.Next.Create(ctx, name, tier, prefix, hash)
+	end(s, err)
+	return k, err
+}
+
+func (r *APIKeysRepo) GetByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	ctx, s := start(ctx, r.Tracer, "APIKeysRepo.GetByHash")
+	k, err := r.Next.GetByHash(ctx, hash)
+	end(s, err)
+	return k, err
+}
+
+func (r *APIKeysRepo) Revoke(ctx context.Context, id int64) error {
+	ctx, s := start(ctx, r.Tracer, "APIKeysRepo.Revoke", tracing.Attr{Key: "api_key.id", Value: id})
+	err := r.Next.Revoke(ctx, id)
+	end(s, err)
+	return err
+}
+
+func (r *APIKeysRepo) List(ctx context.Context) ([]*domain.APIKey, error) {
+	ctx, s := start(ctx, r.Tracer, "APIKeysRepo.List")
+	keys, err := r.Next.List(ctx)
+	end(s, err)
+	return keys, err
+}
+
+type DomainsRepo struct {
+	Next   storage.DomainsRepo
+	Tracer *tracing.Tracer
+}
+
+func NewDomainsRepo(next storage.DomainsRepo, t *tracing.Tracer) *DomainsRepo {
+	return &DomainsRepo{Next: next, Tracer: t}
+}
+
+func (r *DomainsRepo) Create(ctx context.Context, host string) (*domain.CustomDomain, error) {
+	ctx, s := start(ctx, r.Tracer, "DomainsRepo.Create", tracing.Attr{Key: "link.domain", Value: host})
+	d, err := r.Next.Create(ctx, host)
+	end(s, err)
+	return d, err
+}
+
+func (r *DomainsRepo) Get(ctx context.Context, host string) (*domain.CustomDomain, error) {
+	ctx, s := start(ctx, r.Tracer, "DomainsRepo.Get", tracing.Attr{Key: "link.domain", Value: host})
+	d, err := r.Next.Get(ctx, host)
+	end(s, err)
+	return d, err
+}
+
+func (r *DomainsRepo) List(ctx context.Context) ([]*domain.CustomDomain, error) {
+	ctx, s := start(ctx, r.Tracer, "DomainsRepo.List")
+	ds, err := r.Next.List(ctx)
+	end(s, err)
+	return ds, err
+}
+
+func (r *DomainsRepo) Delete(ctx context.Context, host string) error {
+	ctx, s := start(ctx, r.Tracer, "DomainsRepo.Delete", tracing.Attr{Key: "link.domain", Value: host})
+	err := r.Next.Delete(ctx, host)
+	end(s, err)
+	return err
+}
+
+func (r *DomainsRepo) Grant(ctx context.Context, host string, keyID int64) error {
+	ctx, s := start(ctx, r.Tracer, "DomainsRepo.Grant", tracing.Attr{Key: "link.domain", Value: host})
+	err := r.Next.Grant(ctx, host, keyID)
+	end(s, err)
+	return err
+}
+
+func (r *DomainsRepo) Revoke(ctx context.Context, host string, keyID int64) error {
+	ctx, s := start(ctx, r.Tracer, "DomainsRepo.Revoke", tracing.Attr{Key: "link.domain", Value: host})
+	err := r.Next.Revoke(ctx, host, keyID)
+	end(s, err)
+	return err
+}
+
+type QRLogoRepo struct {
+	Next   storage.QRLogoRepo
+	Tracer *tracing.Tracer
+}
+
+func NewQRLogoRepo(next storage.QRLogoRepo, t *tracing.Tracer) *QRLogoRepo {
+	return &QRLogoRepo{Next: next, Tracer: t}
+}
+
+func (r *QRLogoRepo) Get(ctx context.Context, keyID int64) (*domain.QRLogo, error) {
+	ctx, s := start(ctx, r.Tracer, "QRLogoRepo.Get", tracing.Attr{Key: "api_key.id", Value: keyID})
+	l, err := r.Next.Get(ctx, keyID)
+	end(s, err)
+	return l, err
+}
+
+func (r *QRLogoRepo) Put(ctx context.Context, keyID int64, contentType string, image []byte) (*domain.QRLogo, error) {
+	ctx, s := start(ctx, r.Tracer, "QRLogoRepo.Put", tracing.Attr{Key: "api_key.id", Value: keyID}, tracing.Attr{Key: "qr_logo.bytes", Value: len(image)})
+	l, err := r.Next.Put(ctx, keyID, contentType, image)
+	end(s, err)
+	return l, err
+}
+
+func (r *QRLogoRepo) Delete(ctx context.Context, keyID int64) error {
+	ctx, s := start(ctx, r.Tracer, "QRLogoRepo.Delete", tracing.Attr{Key: "api_key.id", Value: keyID})
+	err := r.Next.Delete(ctx, keyID)
+	end(s, err)
+	return err
+}
+
+type RateLimitRepo struct {
+	Next   storage.RateLimitRepo
+	Tracer *tracing.Tracer
+}
+
+func NewRateLimitRepo(next storage.RateLimitRepo, t *tracing.Tracer) *RateLimitRepo {
+	return &RateLimitRepo{Next: next, Tracer: t}
+}
+
+func (r *RateLimitRepo) Take(ctx context.Context, key string, windowStart time.Time, window time.Duration, prevWeight float64, limit int) (int64, int64, bool, error) {
+	ctx, s := start(ctx, r.Tracer, "RateLimitRepo.Take")
+	prev, cur, allowed, err := r.Next.Take(ctx, key, windowStart, window, prevWeight, limit)
+	end(s, err)
+	return prev, cur, allowed, err
+}
+
+func (r *RateLimitRepo) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, s := start(ctx, r.Tracer, "RateLimitRepo.PurgeExpired")
+	n, err := r.Next.PurgeExpired(ctx, now)
+	end(s, err)
+	return n, err
+}
@@ -0,0 +1,292 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Span kinds, numbered as in OTLP
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+// Status codes, numbered as in OTLP
+const (
+	StatusUnset = 0
+	StatusOK    = 1
+	StatusError = 2
+)
+
+const (
+	batchSize     = 256
+	queueSize     = 4096
+	flushInterval = 5 * time.Second
+)
+
+type TraceID [16]byte
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+func (s SpanID) IsValid() bool  { return s != SpanID{} }
+
+// SpanContext identifies a span across process boundaries
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Attr is a span attribute; Value is a string, bool, int, int64 or float64
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// SpanData is the immutable record of a finished span handed to exporters
+type SpanData struct {
+	Name          string
+	Kind          int
+	SpanContext   SpanContext
+	Parent        SpanID
+	Start         time.Time
+	End           time.Time
+	Attrs         []Attr
+	StatusCode    int
+	StatusMessage string
+}
+
+// Span is an in-flight operation. A nil *Span is valid and records nothing.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+// SetAttr adds an attribute to the span
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil || s.tracer == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Attrs = append(s.data.Attrs, Attr{Key: key, Value: value})
+	s.mu.Unlock()
+}
+
+// RecordError marks the span failed; a nil err is ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || s.tracer == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.StatusCode = StatusError
+	s.data.StatusMessage = err.Error()
+	s.mu.Unlock()
+}
+
+// SetStatus sets the span status explicitly (e.g. from an HTTP status code)
+func (s *Span) SetStatus(code int, msg string) {
+	if s == nil || s.tracer == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.StatusCode = code
+	s.data.StatusMessage = msg
+	s.mu.Unlock()
+}
+
+// SpanContext returns the identifiers to propagate downstream
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// End finishes the span and queues it for export; later calls are no-ops
+func (s *Span) End() {
+	if s == nil || s.tracer == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	s.tracer.enqueue(&data)
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// ContextWithSpan returns ctx carrying s as the current span
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// SpanFromContext returns the current span, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// ContextWithRemote returns ctx carrying a parent received from another process (traceparent)
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Tracer creates spans and exports them in batches from a background goroutine.
+// A nil *Tracer is valid and produces no spans.
+type Tracer struct {
+	exporter Exporter
+	logger   *slog.Logger
+	queue    chan *SpanData
+	done     chan struct{}
+
+	mu     sync.RWMutex // guards closing queue against concurrent sends
+	closed bool
+}
+
+// New returns a tracer exporting to exporter; call Shutdown to flush pending spans
+func New(exporter Exporter, logger *slog.Logger) *Tracer {
+	t := &Tracer{
+		exporter: exporter,
+		logger:   logger,
+		queue:    make(chan *SpanData, queueSize),
+		done:     make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Start begins a span that is a child of the span (or remote parent) in ctx.
+// The returned context carries the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind int, attrs ...Attr) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	var parent SpanContext
+	if p := SpanFromContext(ctx); p != nil {
+		parent = p.data.SpanContext
+	} else if sc, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		parent = sc
+	}
+
+	sc := SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: true}
+	if parent.IsValid() {
+		sc.Sampled = parent.Sampled
+	} else {
+		sc.TraceID = newTraceID()
+	}
+
+	s := &Span{data: SpanData{
+		Name:        name,
+		Kind:        kind,
+		SpanContext: sc,
+		Parent:      parent.SpanID,
+		Start:       time.Now(),
+		Attrs:       attrs,
+	}}
+	// Unsampled spans still propagate their ids but are never exported
+	if sc.Sampled {
+		s.tracer = t
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+// Shutdown flushes queued spans and stops the exporter
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.queue)
+	}
+	t.mu.Unlock()
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+// enqueue queues d for export; spans ended after Shutdown are dropped
+func (t *Tracer) enqueue(d *SpanData) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return
+	}
+	select {
+	case t.queue <- d:
+	default:
+		// Tracing must never slow requests down; drop when the exporter falls behind
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*SpanData, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := t.exporter.Export(ctx, batch); err != nil {
+			t.logger.Error("trace export failed", "dropped_spans", len(batch), "error", err)
+		}
+		cancel()
+		batch = make([]*SpanData, 0, batchSize)
+	}
+
+	for {
+		select {
+		case d, ok := <-t.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, d)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	_, _ = rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	_, _ = rand.Read(id[:])
+	return id
+}
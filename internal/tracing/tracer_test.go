@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector is a fake OTLP/HTTP endpoint recording the spans it receives
+type collector struct {
+	mu    sync.Mutex
+	spans []otlpSpan
+	attrs []otlpAttr
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+	var req otlpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		c.attrs = append(c.attrs, rs.Resource.Attributes...)
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+}
+
+func newTestTracer(t *testing.T) (*Tracer, *collector) {
+	t.Helper()
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return New(NewOTLPExporter(srv.URL, "shortener-test"), logger), c
+}
+
+func TestOTLPExportOnShutdown(t *testing.T) {
+	tr, c := newTestTracer(t)
+
+	ctx, parent := tr.Start(context.Background(), "GET /{key}", KindServer, Attr{Key: "http.status_code", Value: 302})
+	_, child := tr.Start(ctx, "db.query", KindClient)
+	child.SetAttr("db.system", "postgresql")
+	child.End()
+	parent.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tr.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.spans) != 2 {
+		t.Fatalf("collector got %d spans, want 2", len(c.spans))
+	}
+	got := map[string]otlpSpan{}
+	for _, s := range c.spans {
+		got[s.Name] = s
+	}
+	p, ch := got["GET /{key}"], got["db.query"]
+	if p.TraceID != parent.SpanContext().TraceID.String() || ch.TraceID != p.TraceID {
+		t.Errorf("trace ids = %q, %q, want both %q", p.TraceID, ch.TraceID, parent.SpanContext().TraceID)
+	}
+	if ch.ParentSpanID != p.SpanID {
+		t.Errorf("child parentSpanId = %q, want %q", ch.ParentSpanID, p.SpanID)
+	}
+	if p.Kind != KindServer || ch.Kind != KindClient {
+		t.Errorf("kinds = %d, %d, want %d, %d", p.Kind, ch.Kind, KindServer, KindClient)
+	}
+	if len(p.Attributes) != 1 || p.Attributes[0].Value.IntValue == nil || *p.Attributes[0].Value.IntValue != "302" {
+		t.Errorf("parent attributes = %+v, want http.status_code=302", p.Attributes)
+	}
+	if len(c.attrs) != 1 || c.attrs[0].Key != "service.name" || *c.attrs[0].Value.StringValue != "shortener-test" {
+		t.Errorf("resource attributes = %+v, want service.name=shortener-test", c.attrs)
+	}
+}
+
+func TestSpansEndedAfterShutdownAreDropped(t *testing.T) {
+	tr, c := newTestTracer(t)
+	_, late := tr.Start(context.Background(), "late", KindInternal)
+
+	if err := tr.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	// Ending concurrently with a second Shutdown must neither panic nor export
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, s := tr.Start(context.Background(), "late", KindInternal)
+			s.End()
+		}()
+	}
+	late.End()
+	if err := tr.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second Shutdown: %v", err)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.spans) != 0 {
+		t.Errorf("collector got %d spans after shutdown, want 0", len(c.spans))
+	}
+}
@@ -0,0 +1,73 @@
+package useragent
+
+import "strings"
+
+// Info is a coarse classification of a User-Agent string
+type Info struct {
+	Device  string // desktop, mobile, tablet, bot or unknown
+	Browser string // family, e.g. chrome, safari; "other" if unrecognized
+	OS      string // family, e.g. windows, ios; "other" if unrecognized
+}
+
+// families are matched in order, so more specific markers come first
+var browsers = []struct{ marker, name string }{
+	{"edg/", "edge"},
+	{"opr/", "opera"},
+	{"opera", "opera"},
+	{"samsungbrowser", "samsung"},
+	{"firefox/", "firefox"},
+	{"fxios", "firefox"},
+	{"crios", "chrome"},
+	{"chrome/", "chrome"},
+	{"safari/", "safari"},
+	{"msie", "ie"},
+	{"trident/", "ie"},
+}
+
+var systems = []struct{ marker, name string }{
+	{"android", "android"},
+	{"iphone", "ios"},
+	{"ipad", "ios"},
+	{"ipod", "ios"},
+	{"windows", "windows"},
+	{"mac os x", "macos"},
+	{"macintosh", "macos"},
+	{"cros", "chromeos"},
+	{"linux", "linux"},
+}
+
+// Parse classifies ua; isBot comes from the bot classifier so both agree
+func Parse(ua string, isBot bool) Info {
+	l := strings.ToLower(ua)
+	info := Info{Device: "unknown", Browser: "other", OS: "other"}
+	if l == "" {
+		if isBot {
+			info.Device = "bot"
+		}
+		return info
+	}
+	for _, b := range browsers {
+		if strings.Contains(l, b.marker) {
+			info.Browser = b.name
+			break
+		}
+	}
+	for _, s := range systems {
+		if strings.Contains(l, s.marker) {
+			info.OS = s.name
+			break
+		}
+	}
+	switch {
+	case isBot:
+		info.Device = "bot"
+	case strings.Contains(l, "ipad") || strings.Contains(l, "tablet") ||
+		(strings.Contains(l, "android") && !strings.Contains(l, "mobile")):
+		info.Device = "tablet"
+	case strings.Contains(l, "mobi") || strings.Contains(l, "iphone") || strings.Contains(l, "ipod"):
+		info.Device = "mobile"
+	case info.OS != "other":
+		info.Device = "desktop"
+	}
+	return info
+}
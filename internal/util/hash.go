@@ -6,7 +6,6 @@ import (
 	"encoding/hex"
 	"fmt"
 	"hash/fnv"
-	"net"
 	"net/url"
 	"strings"
 )
@@ -63,33 +62,6 @@ func CanonicalizeURL(rawURL string) (string, error) {
 	return u.String(), nil
 }
 
-// ExtractIP extracts the real IP from request headers
-func ExtractIP(remoteAddr string, headers map[string][]string) string {
-	// Check common proxy headers
-	if xff := headers["X-Forwarded-For"]; len(xff) > 0 && xff[0] != "" {
-		ips := strings.Split(xff[0], ",")
-		if len(ips) > 0 {
-			ip := strings.TrimSpace(ips[0])
-			if net.ParseIP(ip) != nil {
-				return ip
-			}
-		}
-	}
-
-	if xri := headers["X-Real-Ip"]; len(xri) > 0 && xri[0] != "" {
-		if net.ParseIP(xri[0]) != nil {
-			return xri[0]
-		}
-	}
-
-	// Fall back to RemoteAddr
-	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
-		return host
-	}
-
-	return remoteAddr
-}
-
 // MD5Hash creates an MD5 hash (use only for non-security purposes)
 func MD5Hash(s string) string {
 	h := md5.Sum([]byte(s))
@@ -6,8 +6,16 @@ import (
 )
 
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+	Error   string        `json:"error"`
+	Message string        `json:"message,omitempty"`
+	Details []ErrorDetail `json:"details,omitempty"`
+}
+
+// ErrorDetail locates one problem with a request
+type ErrorDetail struct {
+	In      string `json:"in"` // path, query or body
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 func WriteJSON(w http.ResponseWriter, status int, v any) {
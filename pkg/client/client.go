@@ -0,0 +1,176 @@
+// Package client is a Go client for the URL shortener REST API (/v1).
+//
+//	c := client.New("https://sho.rt", client.WithAPIKey(os.Getenv("SHORTENER_API_KEY")))
+//	link, err := c.CreateLink(ctx, client.CreateLinkRequest{OriginalURL: "https://example.com"})
+//	if errors.Is(err, client.ErrAliasInUse) { ... }
+//
+// Failed calls return an *Error, which matches the Err* values with errors.Is.
+// Requests rejected with 429 are retried after the server's Retry-After; idempotent
+// requests (everything but link creation) are also retried on network errors and
+// 502/503/504, with exponential backoff.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const userAgent = "shortener-go-client/1"
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL      string
+	apiKey       string
+	http         *http.Client
+	maxRetries   int
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	maxRetryWait time.Duration
+}
+
+type Option func(*Client)
+
+// WithAPIKey authenticates requests with an API key (sk_...). Batch creation and
+// updates require one; other calls are anonymous without it.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient replaces the default client (30s timeout)
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithRetries sets how many times a failed request is retried (default 3, 0 disables)
+func WithRetries(n int) Option {
+	return func(c *Client) { c.maxRetries = n }
+}
+
+// WithBackoff sets the first and the largest delay between retries (default 200ms, 5s)
+func WithBackoff(min, max time.Duration) Option {
+	return func(c *Client) { c.minBackoff, c.maxBackoff = min, max }
+}
+
+// WithMaxRetryWait sets the longest Retry-After the client will wait for (default 60s);
+// longer waits return the error instead
+func WithMaxRetryWait(d time.Duration) Option {
+	return func(c *Client) { c.maxRetryWait = d }
+}
+
+// New returns a client for the API at baseURL (e.g. "http://localhost:8080")
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		http:         &http.Client{Timeout: 30 * time.Second},
+		maxRetries:   3,
+		minBackoff:   200 * time.Millisecond,
+		maxBackoff:   5 * time.Second,
+		maxRetryWait: 60 * time.Second,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// do sends one API call, retrying as described in the package doc, and decodes a
+// successful JSON response into out (if not nil)
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("client: encode request: %w", err)
+		}
+	}
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	idempotent := method != http.MethodPost
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || !idempotent || attempt >= c.maxRetries {
+				return err
+			}
+			if err := sleep(ctx, c.backoff(attempt)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out == nil {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				return nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("client: decode response: %w", err)
+			}
+			return nil
+		}
+
+		apiErr := readError(resp)
+		retry := resp.StatusCode == http.StatusTooManyRequests ||
+			(idempotent && (resp.StatusCode == http.StatusBadGateway ||
+				resp.StatusCode == http.StatusServiceUnavailable ||
+				resp.StatusCode == http.StatusGatewayTimeout))
+		if !retry || attempt >= c.maxRetries {
+			return apiErr
+		}
+		delay := apiErr.RetryAfter
+		if delay == 0 {
+			delay = c.backoff(attempt)
+		}
+		if delay > c.maxRetryWait {
+			return apiErr
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff returns the delay before retry attempt+1: exponential, capped, with jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.minBackoff << attempt
+	if d <= 0 || d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Errors matched by *Error with errors.Is, from the error code or, failing that, the status
+var (
+	ErrInvalidRequest = errors.New("invalid request") // any 400
+	ErrInvalidURL     = errors.New("invalid URL")
+	ErrInvalidAlias   = errors.New("invalid alias")
+	ErrReservedAlias  = errors.New("reserved alias")
+	ErrAliasInUse     = errors.New("alias already taken")
+	ErrImmutableURL   = errors.New("destination of a generated key cannot change")
+	ErrBlockedURL     = errors.New("destination host is blocked")
+	ErrUnauthorized   = errors.New("unauthorized") // 401
+	ErrForbidden      = errors.New("forbidden")    // 403
+	ErrNotFound       = errors.New("not found")    // 404
+	ErrRateLimited    = errors.New("rate limited") // 429
+	ErrServer         = errors.New("server error") // 5xx
+)
+
+// codeErrors maps the API's error codes to the errors above
+var codeErrors = map[string]error{
+	"invalid_url":    ErrInvalidURL,
+	"invalid_alias":  ErrInvalidAlias,
+	"reserved_key":   ErrReservedAlias,
+	"alias_in_use":   ErrAliasInUse,
+	"immutable_url":  ErrImmutableURL,
+	"blocked_url":    ErrBlockedURL,
+	"unauthorized":   ErrUnauthorized,
+	"admin_disabled": ErrForbidden,
+	"not_found":      ErrNotFound,
+}
+
+// Error is a non-2xx API response
+type Error struct {
+	StatusCode int
+	Code       string // e.g. alias_in_use; empty when the body was not an API error
+	Message    string
+	Details    []ErrorDetail // schema violations, for invalid_request
+	RetryAfter time.Duration // from the Retry-After header, if any
+}
+
+// ErrorDetail locates one problem with a request
+type ErrorDetail struct {
+	In      string `json:"in"` // path, query or body
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Code != "" {
+		msg = e.Code + ": " + msg
+	}
+	for _, d := range e.Details {
+		msg += fmt.Sprintf("; %s %s %s", d.In, d.Field, d.Message)
+	}
+	return fmt.Sprintf("shortener: %d %s", e.StatusCode, msg)
+}
+
+func (e *Error) Is(target error) bool {
+	if err, ok := codeErrors[e.Code]; ok && err == target {
+		return true
+	}
+	switch target {
+	case ErrInvalidRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// apiError is the JSON error body the server sends
+type apiError struct {
+	Error   string        `json:"error"`
+	Message string        `json:"message"`
+	Details []ErrorDetail `json:"details"`
+}
+
+func readError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+	e := &Error{StatusCode: resp.StatusCode, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var body apiError
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		e.Code, e.Message, e.Details = body.Error, body.Message, body.Details
+	}
+	return e
+}
+
+// parseRetryAfter accepts delay-seconds or an HTTP date
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+		return 0
+	}
+	// Older servers sent a Go duration ("1m0s")
+	if d, err := time.ParseDuration(v); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Link is a short link as returned by the API
+type Link struct {
+	Key                 string     `json:"key"`
+	Domain              string     `json:"domain,omitempty"`
+	ShortURL            string     `json:"short_url"`
+	OriginalURL         string     `json:"original_url"`
+	IsCustom            bool       `json:"is_custom"`
+	IsDisabled          bool       `json:"is_disabled"`
+	CreatedAt           time.Time  `json:"created_at"`
+	ExpiresAt           *time.Time `json:"expires_at,omitempty"`
+	DedupeWindowSeconds *int       `json:"dedupe_window_seconds,omitempty"`
+	// Existing is set by create calls when a live link for the same URL was returned
+	// instead of a new one
+	Existing bool `json:"existing,omitempty"`
+}
+
+type CreateLinkRequest struct {
+	OriginalURL string `json:"original_url"`
+	// CustomAlias requests a specific key ([A-Za-z0-9_-]{3,32})
+	CustomAlias string `json:"custom_alias,omitempty"`
+	// Domain creates the link on a custom domain the API key may use instead of the
+	// server's base URL
+	Domain string `json:"domain,omitempty"`
+	// KeyAlphabet picks the alphabet of a generated key: base62, crockford, lower or
+	// words (e.g. brave-otter-lamp); the server's default when empty
+	KeyAlphabet string `json:"key_alphabet,omitempty"`
+	// ExpiresAt defaults to 90 days from now on the server
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// DedupeWindowSeconds overrides the server's click de-duplication window; 0 disables it
+	DedupeWindowSeconds *int `json:"dedupe_window_seconds,omitempty"`
+}
+
+// UpdateLinkRequest changes the fields that are set. Only custom aliases can
+// change their OriginalURL.
+type UpdateLinkRequest struct {
+	OriginalURL         *string
+	ExpiresAt           *time.Time
+	ClearExpiry         bool // the link never expires; ignored if ExpiresAt is set
+	DedupeWindowSeconds *int
+	ResetDedupeWindow   bool // use the server default again; ignored if DedupeWindowSeconds is set
+	Disabled            *bool
+}
+
+func (u UpdateLinkRequest) MarshalJSON() ([]byte, error) {
+	body := map[string]any{}
+	if u.OriginalURL != nil {
+		body["original_url"] = *u.OriginalURL
+	}
+	if u.ExpiresAt != nil {
+		body["expires_at"] = u.ExpiresAt
+	} else if u.ClearExpiry {
+		body["expires_at"] = nil
+	}
+	if u.DedupeWindowSeconds != nil {
+		body["dedupe_window_seconds"] = *u.DedupeWindowSeconds
+	} else if u.ResetDedupeWindow {
+		body["dedupe_window_seconds"] = nil
+	}
+	if u.Disabled != nil {
+		body["disabled"] = *u.Disabled
+	}
+	return json.Marshal(body)
+}
+
+// BatchResult is the outcome of one link of a batch: either Link or Err is set
+type BatchResult struct {
+	Link *Link
+	Err  error
+}
+
+// MaxBatchLinks is the most links the server accepts in one batch
+const MaxBatchLinks = 100
+
+// CreateLink shortens a URL. Without an alias, an existing link for the same URL
+// may be returned (Link.Existing).
+func (c *Client) CreateLink(ctx context.Context, req CreateLinkRequest) (*Link, error) {
+	var l Link
+	if err := c.do(ctx, http.MethodPost, "/v1/links", nil, req, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// BatchCreateLinks creates up to MaxBatchLinks links in one request. It requires an
+// API key. The error is only set when the batch as a whole failed; results are in
+// request order.
+func (c *Client) BatchCreateLinks(ctx context.Context, reqs []CreateLinkRequest) ([]BatchResult, error) {
+	var resp struct {
+		Results []struct {
+			Status int       `json:"status"`
+			Link   *Link     `json:"link"`
+			Error  *apiError `json:"error"`
+		} `json:"results"`
+	}
+	in := struct {
+		Links []CreateLinkRequest `json:"links"`
+	}{reqs}
+	if err := c.do(ctx, http.MethodPost, "/v1/links/batch", nil, in, &resp); err != nil {
+		return nil, err
+	}
+	out := make([]BatchResult, len(resp.Results))
+	for i, r := range resp.Results {
+		if r.Error != nil {
+			out[i].Err = &Error{StatusCode: r.Status, Code: r.Error.Error, Message: r.Error.Message, Details: r.Error.Details}
+			continue
+		}
+		out[i].Link = r.Link
+	}
+	return out, nil
+}
+
+// LinkOption adjusts which link a call addresses
+type LinkOption func(url.Values)
+
+// OnDomain addresses the key in the namespace of a custom domain instead of the
+// server's base URL
+func OnDomain(host string) LinkOption {
+	return func(q url.Values) { q.Set("domain", host) }
+}
+
+func linkQuery(opts []LinkOption) url.Values {
+	if len(opts) == 0 {
+		return nil
+	}
+	q := url.Values{}
+	for _, o := range opts {
+		o(q)
+	}
+	return q
+}
+
+func (c *Client) GetLink(ctx context.Context, key string, opts ...LinkOption) (*Link, error) {
+	var l Link
+	if err := c.do(ctx, http.MethodGet, "/v1/links/"+url.PathEscape(key), linkQuery(opts), nil, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// UpdateLink changes a link and returns it updated. It requires an API key.
+func (c *Client) UpdateLink(ctx context.Context, key string, req UpdateLinkRequest, opts ...LinkOption) (*Link, error) {
+	var l Link
+	if err := c.do(ctx, http.MethodPatch, "/v1/links/"+url.PathEscape(key), linkQuery(opts), req, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// DisableLink stops a link from redirecting (410 Gone). It requires an API key.
+func (c *Client) DisableLink(ctx context.Context, key string, opts ...LinkOption) (*Link, error) {
+	disabled := true
+	return c.UpdateLink(ctx, key, UpdateLinkRequest{Disabled: &disabled}, opts...)
+}
+
+// EnableLink undoes DisableLink. It requires an API key.
+func (c *Client) EnableLink(ctx context.Context, key string, opts ...LinkOption) (*Link, error) {
+	disabled := false
+	return c.UpdateLink(ctx, key, UpdateLinkRequest{Disabled: &disabled}, opts...)
+}
+
+// Stats holds the click counts of a link
+type Stats struct {
+	Key                string     `json:"key"`
+	ShortURL           string     `json:"short_url"`
+	TotalClicks        int64      `json:"total_clicks"`
+	DeduplicatedClicks int64      `json:"deduplicated_clicks"`
+	QRClicks           int64      `json:"qr_clicks"` // clicks from scans of the link's QR code
+	LastClickedAt      *time.Time `json:"last_clicked_at,omitempty"`
+	IncludeBots        bool       `json:"include_bots"`
+	Granularity        string     `json:"granularity"`
+	Timezone           string     `json:"timezone"`
+	From               string     `json:"from"`
+	To                 string     `json:"to"`
+	Series             []Bucket   `json:"series"`
+}
+
+type Bucket struct {
+	Start          time.Time `json:"start"`
+	Clicks         int64     `json:"clicks"`
+	UniqueVisitors *int64    `json:"unique_visitors,omitempty"` // only for buckets spanning one UTC hour or day
+}
+
+// StatsOptions narrows the click series; the zero value is the last 30 days by day in UTC
+type StatsOptions struct {
+	From, To    time.Time // To is exclusive
+	Granularity string    // hour, day, week or month
+	TZ          string    // IANA time zone the buckets are aligned to
+	IncludeBots bool
+	Domain      string // custom domain of the key; the server's base URL when empty
+}
+
+func (c *Client) Stats(ctx context.Context, key string, opts StatsOptions) (*Stats, error) {
+	q := url.Values{}
+	if !opts.From.IsZero() {
+		q.Set("from", opts.From.Format(time.RFC3339))
+	}
+	if !opts.To.IsZero() {
+		q.Set("to", opts.To.Format(time.RFC3339))
+	}
+	if opts.Granularity != "" {
+		q.Set("granularity", opts.Granularity)
+	}
+	if opts.TZ != "" {
+		q.Set("tz", opts.TZ)
+	}
+	if opts.IncludeBots {
+		q.Set("includeBots", strconv.FormatBool(true))
+	}
+	if opts.Domain != "" {
+		q.Set("domain", opts.Domain)
+	}
+	var s Stats
+	if err := c.do(ctx, http.MethodGet, "/v1/links/"+url.PathEscape(key)+"/stats", q, nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
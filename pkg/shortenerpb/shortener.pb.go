@@ -0,0 +1,1435 @@
+// gRPC API of the URL shortener, served on GRPC_PORT next to the REST API.
+// Regenerate the Go code in pkg/shortenerpb after editing (see README).
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: shortener/v1/shortener.proto
+
+package shortenerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Link struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Key         string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	ShortUrl    string                 `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl string                 `protobuf:"bytes,3,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	IsCustom    bool                   `protobuf:"varint,4,opt,name=is_custom,json=isCustom,proto3" json:"is_custom,omitempty"`
+	IsDisabled  bool                   `protobuf:"varint,5,opt,name=is_disabled,json=isDisabled,proto3" json:"is_disabled,omitempty"`
+	CreatedAt   *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// Unset when the link never expires
+	ExpiresAt *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// Unset when the server default applies
+	DedupeWindow *durationpb.Duration `protobuf:"bytes,8,opt,name=dedupe_window,json=dedupeWindow,proto3" json:"dedupe_window,omitempty"`
+	// Custom domain host; empty for the server's base URL
+	Domain        string `protobuf:"bytes,9,opt,name=domain,proto3" json:"domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Link) Reset() {
+	*x = Link{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Link) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Link) ProtoMessage() {}
+
+func (x *Link) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Link.ProtoReflect.Descriptor instead.
+func (*Link) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Link) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Link) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *Link) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *Link) GetIsCustom() bool {
+	if x != nil {
+		return x.IsCustom
+	}
+	return false
+}
+
+func (x *Link) GetIsDisabled() bool {
+	if x != nil {
+		return x.IsDisabled
+	}
+	return false
+}
+
+func (x *Link) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Link) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *Link) GetDedupeWindow() *durationpb.Duration {
+	if x != nil {
+		return x.DedupeWindow
+	}
+	return nil
+}
+
+func (x *Link) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type CreateLinkRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	OriginalUrl string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	// Empty for a generated key
+	CustomAlias string `protobuf:"bytes,2,opt,name=custom_alias,json=customAlias,proto3" json:"custom_alias,omitempty"`
+	// Defaults to 90 days from now
+	ExpiresAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// Overrides the server's click de-duplication window; zero disables it
+	DedupeWindow *durationpb.Duration `protobuf:"bytes,4,opt,name=dedupe_window,json=dedupeWindow,proto3" json:"dedupe_window,omitempty"`
+	// Custom domain host the caller's API key may use; empty for the base URL
+	Domain string `protobuf:"bytes,5,opt,name=domain,proto3" json:"domain,omitempty"`
+	// Alphabet of a generated key: base62, crockford, lower or words; empty for the
+	// server default. Not allowed with custom_alias.
+	KeyAlphabet   string `protobuf:"bytes,6,opt,name=key_alphabet,json=keyAlphabet,proto3" json:"key_alphabet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateLinkRequest) Reset() {
+	*x = CreateLinkRequest{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateLinkRequest) ProtoMessage() {}
+
+func (x *CreateLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateLinkRequest.ProtoReflect.Descriptor instead.
+func (*CreateLinkRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateLinkRequest) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *CreateLinkRequest) GetCustomAlias() string {
+	if x != nil {
+		return x.CustomAlias
+	}
+	return ""
+}
+
+func (x *CreateLinkRequest) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *CreateLinkRequest) GetDedupeWindow() *durationpb.Duration {
+	if x != nil {
+		return x.DedupeWindow
+	}
+	return nil
+}
+
+func (x *CreateLinkRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *CreateLinkRequest) GetKeyAlphabet() string {
+	if x != nil {
+		return x.KeyAlphabet
+	}
+	return ""
+}
+
+type CreateLinkResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Link  *Link                  `protobuf:"bytes,1,opt,name=link,proto3" json:"link,omitempty"`
+	// A live link for the same URL was returned instead of a new one
+	Existing      bool `protobuf:"varint,2,opt,name=existing,proto3" json:"existing,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateLinkResponse) Reset() {
+	*x = CreateLinkResponse{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateLinkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateLinkResponse) ProtoMessage() {}
+
+func (x *CreateLinkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateLinkResponse.ProtoReflect.Descriptor instead.
+func (*CreateLinkResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateLinkResponse) GetLink() *Link {
+	if x != nil {
+		return x.Link
+	}
+	return nil
+}
+
+func (x *CreateLinkResponse) GetExisting() bool {
+	if x != nil {
+		return x.Existing
+	}
+	return false
+}
+
+type BatchCreateLinksRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 1 to 100 links
+	Links         []*CreateLinkRequest `protobuf:"bytes,1,rep,name=links,proto3" json:"links,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCreateLinksRequest) Reset() {
+	*x = BatchCreateLinksRequest{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCreateLinksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateLinksRequest) ProtoMessage() {}
+
+func (x *BatchCreateLinksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateLinksRequest.ProtoReflect.Descriptor instead.
+func (*BatchCreateLinksRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchCreateLinksRequest) GetLinks() []*CreateLinkRequest {
+	if x != nil {
+		return x.Links
+	}
+	return nil
+}
+
+type BatchCreateLinksResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One result per request item, in order
+	Results       []*BatchCreateResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCreateLinksResponse) Reset() {
+	*x = BatchCreateLinksResponse{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCreateLinksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateLinksResponse) ProtoMessage() {}
+
+func (x *BatchCreateLinksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateLinksResponse.ProtoReflect.Descriptor instead.
+func (*BatchCreateLinksResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchCreateLinksResponse) GetResults() []*BatchCreateResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type BatchCreateResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Result:
+	//
+	//	*BatchCreateResult_Created
+	//	*BatchCreateResult_Error
+	Result        isBatchCreateResult_Result `protobuf_oneof:"result"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCreateResult) Reset() {
+	*x = BatchCreateResult{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCreateResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateResult) ProtoMessage() {}
+
+func (x *BatchCreateResult) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateResult.ProtoReflect.Descriptor instead.
+func (*BatchCreateResult) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchCreateResult) GetResult() isBatchCreateResult_Result {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *BatchCreateResult) GetCreated() *CreateLinkResponse {
+	if x != nil {
+		if x, ok := x.Result.(*BatchCreateResult_Created); ok {
+			return x.Created
+		}
+	}
+	return nil
+}
+
+func (x *BatchCreateResult) GetError() *Error {
+	if x != nil {
+		if x, ok := x.Result.(*BatchCreateResult_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+type isBatchCreateResult_Result interface {
+	isBatchCreateResult_Result()
+}
+
+type BatchCreateResult_Created struct {
+	Created *CreateLinkResponse `protobuf:"bytes,1,opt,name=created,proto3,oneof"`
+}
+
+type BatchCreateResult_Error struct {
+	Error *Error `protobuf:"bytes,2,opt,name=error,proto3,oneof"`
+}
+
+func (*BatchCreateResult_Created) isBatchCreateResult_Result() {}
+
+func (*BatchCreateResult_Error) isBatchCreateResult_Result() {}
+
+// Error is a failed batch item
+type Error struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// gRPC status code the item would have failed with on its own
+	Code int32 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	// REST error code, e.g. alias_in_use
+	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Message       string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Error) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *Error) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type GetLinkRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Custom domain of the key; empty for the base URL
+	Domain        string `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLinkRequest) Reset() {
+	*x = GetLinkRequest{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLinkRequest) ProtoMessage() {}
+
+func (x *GetLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLinkRequest.ProtoReflect.Descriptor instead.
+func (*GetLinkRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetLinkRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *GetLinkRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+// UpdateLinkRequest changes the fields that are set
+type UpdateLinkRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Only custom aliases can change their destination
+	OriginalUrl *string `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3,oneof" json:"original_url,omitempty"`
+	// Types that are valid to be assigned to Expiry:
+	//
+	//	*UpdateLinkRequest_ExpiresAt
+	//	*UpdateLinkRequest_NeverExpires
+	Expiry isUpdateLinkRequest_Expiry `protobuf_oneof:"expiry"`
+	// Types that are valid to be assigned to Dedupe:
+	//
+	//	*UpdateLinkRequest_DedupeWindow
+	//	*UpdateLinkRequest_DefaultDedupeWindow
+	Dedupe        isUpdateLinkRequest_Dedupe `protobuf_oneof:"dedupe"`
+	Disabled      *bool                      `protobuf:"varint,7,opt,name=disabled,proto3,oneof" json:"disabled,omitempty"`
+	Domain        string                     `protobuf:"bytes,8,opt,name=domain,proto3" json:"domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateLinkRequest) Reset() {
+	*x = UpdateLinkRequest{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateLinkRequest) ProtoMessage() {}
+
+func (x *UpdateLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateLinkRequest.ProtoReflect.Descriptor instead.
+func (*UpdateLinkRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UpdateLinkRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *UpdateLinkRequest) GetOriginalUrl() string {
+	if x != nil && x.OriginalUrl != nil {
+		return *x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *UpdateLinkRequest) GetExpiry() isUpdateLinkRequest_Expiry {
+	if x != nil {
+		return x.Expiry
+	}
+	return nil
+}
+
+func (x *UpdateLinkRequest) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		if x, ok := x.Expiry.(*UpdateLinkRequest_ExpiresAt); ok {
+			return x.ExpiresAt
+		}
+	}
+	return nil
+}
+
+func (x *UpdateLinkRequest) GetNeverExpires() bool {
+	if x != nil {
+		if x, ok := x.Expiry.(*UpdateLinkRequest_NeverExpires); ok {
+			return x.NeverExpires
+		}
+	}
+	return false
+}
+
+func (x *UpdateLinkRequest) GetDedupe() isUpdateLinkRequest_Dedupe {
+	if x != nil {
+		return x.Dedupe
+	}
+	return nil
+}
+
+func (x *UpdateLinkRequest) GetDedupeWindow() *durationpb.Duration {
+	if x != nil {
+		if x, ok := x.Dedupe.(*UpdateLinkRequest_DedupeWindow); ok {
+			return x.DedupeWindow
+		}
+	}
+	return nil
+}
+
+func (x *UpdateLinkRequest) GetDefaultDedupeWindow() bool {
+	if x != nil {
+		if x, ok := x.Dedupe.(*UpdateLinkRequest_DefaultDedupeWindow); ok {
+			return x.DefaultDedupeWindow
+		}
+	}
+	return false
+}
+
+func (x *UpdateLinkRequest) GetDisabled() bool {
+	if x != nil && x.Disabled != nil {
+		return *x.Disabled
+	}
+	return false
+}
+
+func (x *UpdateLinkRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type isUpdateLinkRequest_Expiry interface {
+	isUpdateLinkRequest_Expiry()
+}
+
+type UpdateLinkRequest_ExpiresAt struct {
+	ExpiresAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3,oneof"`
+}
+
+type UpdateLinkRequest_NeverExpires struct {
+	// Removes the expiry
+	NeverExpires bool `protobuf:"varint,4,opt,name=never_expires,json=neverExpires,proto3,oneof"`
+}
+
+func (*UpdateLinkRequest_ExpiresAt) isUpdateLinkRequest_Expiry() {}
+
+func (*UpdateLinkRequest_NeverExpires) isUpdateLinkRequest_Expiry() {}
+
+type isUpdateLinkRequest_Dedupe interface {
+	isUpdateLinkRequest_Dedupe()
+}
+
+type UpdateLinkRequest_DedupeWindow struct {
+	DedupeWindow *durationpb.Duration `protobuf:"bytes,5,opt,name=dedupe_window,json=dedupeWindow,proto3,oneof"`
+}
+
+type UpdateLinkRequest_DefaultDedupeWindow struct {
+	// Restores the server default
+	DefaultDedupeWindow bool `protobuf:"varint,6,opt,name=default_dedupe_window,json=defaultDedupeWindow,proto3,oneof"`
+}
+
+func (*UpdateLinkRequest_DedupeWindow) isUpdateLinkRequest_Dedupe() {}
+
+func (*UpdateLinkRequest_DefaultDedupeWindow) isUpdateLinkRequest_Dedupe() {}
+
+type DeleteLinkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Domain        string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteLinkRequest) Reset() {
+	*x = DeleteLinkRequest{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteLinkRequest) ProtoMessage() {}
+
+func (x *DeleteLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteLinkRequest.ProtoReflect.Descriptor instead.
+func (*DeleteLinkRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeleteLinkRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *DeleteLinkRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type DeleteLinkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteLinkResponse) Reset() {
+	*x = DeleteLinkResponse{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteLinkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteLinkResponse) ProtoMessage() {}
+
+func (x *DeleteLinkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteLinkResponse.ProtoReflect.Descriptor instead.
+func (*DeleteLinkResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{10}
+}
+
+type ResolveLinkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Domain        string                 `protobuf:"bytes,2,opt,name=domain,proto3" json:"domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolveLinkRequest) Reset() {
+	*x = ResolveLinkRequest{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolveLinkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolveLinkRequest) ProtoMessage() {}
+
+func (x *ResolveLinkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolveLinkRequest.ProtoReflect.Descriptor instead.
+func (*ResolveLinkRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ResolveLinkRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ResolveLinkRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type ResolveLinkResponse struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	OriginalUrl string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	// Unset when the link never expires
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolveLinkResponse) Reset() {
+	*x = ResolveLinkResponse{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolveLinkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolveLinkResponse) ProtoMessage() {}
+
+func (x *ResolveLinkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolveLinkResponse.ProtoReflect.Descriptor instead.
+func (*ResolveLinkResponse) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ResolveLinkResponse) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *ResolveLinkResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type GetStatsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Defaults to the last 30 days; to is exclusive
+	From *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	// hour, day (default), week or month
+	Granularity string `protobuf:"bytes,4,opt,name=granularity,proto3" json:"granularity,omitempty"`
+	// IANA time zone the buckets are aligned to (default UTC)
+	Tz            string `protobuf:"bytes,5,opt,name=tz,proto3" json:"tz,omitempty"`
+	IncludeBots   bool   `protobuf:"varint,6,opt,name=include_bots,json=includeBots,proto3" json:"include_bots,omitempty"`
+	Domain        string `protobuf:"bytes,7,opt,name=domain,proto3" json:"domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsRequest) Reset() {
+	*x = GetStatsRequest{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsRequest) ProtoMessage() {}
+
+func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetStatsRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *GetStatsRequest) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *GetStatsRequest) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *GetStatsRequest) GetGranularity() string {
+	if x != nil {
+		return x.Granularity
+	}
+	return ""
+}
+
+func (x *GetStatsRequest) GetTz() string {
+	if x != nil {
+		return x.Tz
+	}
+	return ""
+}
+
+func (x *GetStatsRequest) GetIncludeBots() bool {
+	if x != nil {
+		return x.IncludeBots
+	}
+	return false
+}
+
+func (x *GetStatsRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type Stats struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Key                string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	ShortUrl           string                 `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	TotalClicks        int64                  `protobuf:"varint,3,opt,name=total_clicks,json=totalClicks,proto3" json:"total_clicks,omitempty"`
+	DeduplicatedClicks int64                  `protobuf:"varint,4,opt,name=deduplicated_clicks,json=deduplicatedClicks,proto3" json:"deduplicated_clicks,omitempty"`
+	LastClickedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=last_clicked_at,json=lastClickedAt,proto3" json:"last_clicked_at,omitempty"`
+	IncludeBots        bool                   `protobuf:"varint,6,opt,name=include_bots,json=includeBots,proto3" json:"include_bots,omitempty"`
+	Granularity        string                 `protobuf:"bytes,7,opt,name=granularity,proto3" json:"granularity,omitempty"`
+	Timezone           string                 `protobuf:"bytes,8,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	From               *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=from,proto3" json:"from,omitempty"`
+	To                 *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=to,proto3" json:"to,omitempty"`
+	// Every bucket in the range, with 0 for buckets without clicks
+	Series []*Bucket `protobuf:"bytes,11,rep,name=series,proto3" json:"series,omitempty"`
+	// Clicks from scans of the QR code served by GET /v1/links/{key}/qr
+	QrClicks      int64 `protobuf:"varint,12,opt,name=qr_clicks,json=qrClicks,proto3" json:"qr_clicks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Stats) Reset() {
+	*x = Stats{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stats) ProtoMessage() {}
+
+func (x *Stats) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stats.ProtoReflect.Descriptor instead.
+func (*Stats) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Stats) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Stats) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *Stats) GetTotalClicks() int64 {
+	if x != nil {
+		return x.TotalClicks
+	}
+	return 0
+}
+
+func (x *Stats) GetDeduplicatedClicks() int64 {
+	if x != nil {
+		return x.DeduplicatedClicks
+	}
+	return 0
+}
+
+func (x *Stats) GetLastClickedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastClickedAt
+	}
+	return nil
+}
+
+func (x *Stats) GetIncludeBots() bool {
+	if x != nil {
+		return x.IncludeBots
+	}
+	return false
+}
+
+func (x *Stats) GetGranularity() string {
+	if x != nil {
+		return x.Granularity
+	}
+	return ""
+}
+
+func (x *Stats) GetTimezone() string {
+	if x != nil {
+		return x.Timezone
+	}
+	return ""
+}
+
+func (x *Stats) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *Stats) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *Stats) GetSeries() []*Bucket {
+	if x != nil {
+		return x.Series
+	}
+	return nil
+}
+
+func (x *Stats) GetQrClicks() int64 {
+	if x != nil {
+		return x.QrClicks
+	}
+	return 0
+}
+
+type Bucket struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Start  *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	Clicks int64                  `protobuf:"varint,2,opt,name=clicks,proto3" json:"clicks,omitempty"`
+	// Distinct visitors, only set for buckets inside the range that span one UTC hour or day
+	UniqueVisitors *int64 `protobuf:"varint,3,opt,name=unique_visitors,json=uniqueVisitors,proto3,oneof" json:"unique_visitors,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Bucket) Reset() {
+	*x = Bucket{}
+	mi := &file_shortener_v1_shortener_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Bucket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bucket) ProtoMessage() {}
+
+func (x *Bucket) ProtoReflect() protoreflect.Message {
+	mi := &file_shortener_v1_shortener_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bucket.ProtoReflect.Descriptor instead.
+func (*Bucket) Descriptor() ([]byte, []int) {
+	return file_shortener_v1_shortener_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Bucket) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *Bucket) GetClicks() int64 {
+	if x != nil {
+		return x.Clicks
+	}
+	return 0
+}
+
+func (x *Bucket) GetUniqueVisitors() int64 {
+	if x != nil && x.UniqueVisitors != nil {
+		return *x.UniqueVisitors
+	}
+	return 0
+}
+
+var File_shortener_v1_shortener_proto protoreflect.FileDescriptor
+
+const file_shortener_v1_shortener_proto_rawDesc = "" +
+	"\n" +
+	"\x1cshortener/v1/shortener.proto\x12\fshortener.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xe4\x02\n" +
+	"\x04Link\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1b\n" +
+	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\x12!\n" +
+	"\foriginal_url\x18\x03 \x01(\tR\voriginalUrl\x12\x1b\n" +
+	"\tis_custom\x18\x04 \x01(\bR\bisCustom\x12\x1f\n" +
+	"\vis_disabled\x18\x05 \x01(\bR\n" +
+	"isDisabled\x129\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"expires_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12>\n" +
+	"\rdedupe_window\x18\b \x01(\v2\x19.google.protobuf.DurationR\fdedupeWindow\x12\x16\n" +
+	"\x06domain\x18\t \x01(\tR\x06domain\"\x8f\x02\n" +
+	"\x11CreateLinkRequest\x12!\n" +
+	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x12!\n" +
+	"\fcustom_alias\x18\x02 \x01(\tR\vcustomAlias\x129\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12>\n" +
+	"\rdedupe_window\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\fdedupeWindow\x12\x16\n" +
+	"\x06domain\x18\x05 \x01(\tR\x06domain\x12!\n" +
+	"\fkey_alphabet\x18\x06 \x01(\tR\vkeyAlphabet\"X\n" +
+	"\x12CreateLinkResponse\x12&\n" +
+	"\x04link\x18\x01 \x01(\v2\x12.shortener.v1.LinkR\x04link\x12\x1a\n" +
+	"\bexisting\x18\x02 \x01(\bR\bexisting\"P\n" +
+	"\x17BatchCreateLinksRequest\x125\n" +
+	"\x05links\x18\x01 \x03(\v2\x1f.shortener.v1.CreateLinkRequestR\x05links\"U\n" +
+	"\x18BatchCreateLinksResponse\x129\n" +
+	"\aresults\x18\x01 \x03(\v2\x1f.shortener.v1.BatchCreateResultR\aresults\"\x88\x01\n" +
+	"\x11BatchCreateResult\x12<\n" +
+	"\acreated\x18\x01 \x01(\v2 .shortener.v1.CreateLinkResponseH\x00R\acreated\x12+\n" +
+	"\x05error\x18\x02 \x01(\v2\x13.shortener.v1.ErrorH\x00R\x05errorB\b\n" +
+	"\x06result\"M\n" +
+	"\x05Error\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\":\n" +
+	"\x0eGetLinkRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x16\n" +
+	"\x06domain\x18\x02 \x01(\tR\x06domain\"\x94\x03\n" +
+	"\x11UpdateLinkRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12&\n" +
+	"\foriginal_url\x18\x02 \x01(\tH\x02R\voriginalUrl\x88\x01\x01\x12;\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampH\x00R\texpiresAt\x12%\n" +
+	"\rnever_expires\x18\x04 \x01(\bH\x00R\fneverExpires\x12@\n" +
+	"\rdedupe_window\x18\x05 \x01(\v2\x19.google.protobuf.DurationH\x01R\fdedupeWindow\x124\n" +
+	"\x15default_dedupe_window\x18\x06 \x01(\bH\x01R\x13defaultDedupeWindow\x12\x1f\n" +
+	"\bdisabled\x18\a \x01(\bH\x03R\bdisabled\x88\x01\x01\x12\x16\n" +
+	"\x06domain\x18\b \x01(\tR\x06domainB\b\n" +
+	"\x06expiryB\b\n" +
+	"\x06dedupeB\x0f\n" +
+	"\r_original_urlB\v\n" +
+	"\t_disabled\"=\n" +
+	"\x11DeleteLinkRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x16\n" +
+	"\x06domain\x18\x02 \x01(\tR\x06domain\"\x14\n" +
+	"\x12DeleteLinkResponse\">\n" +
+	"\x12ResolveLinkRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x16\n" +
+	"\x06domain\x18\x02 \x01(\tR\x06domain\"s\n" +
+	"\x13ResolveLinkResponse\x12!\n" +
+	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x129\n" +
+	"\n" +
+	"expires_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"\xec\x01\n" +
+	"\x0fGetStatsRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12.\n" +
+	"\x04from\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12 \n" +
+	"\vgranularity\x18\x04 \x01(\tR\vgranularity\x12\x0e\n" +
+	"\x02tz\x18\x05 \x01(\tR\x02tz\x12!\n" +
+	"\finclude_bots\x18\x06 \x01(\bR\vincludeBots\x12\x16\n" +
+	"\x06domain\x18\a \x01(\tR\x06domain\"\xd6\x03\n" +
+	"\x05Stats\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1b\n" +
+	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\x12!\n" +
+	"\ftotal_clicks\x18\x03 \x01(\x03R\vtotalClicks\x12/\n" +
+	"\x13deduplicated_clicks\x18\x04 \x01(\x03R\x12deduplicatedClicks\x12B\n" +
+	"\x0flast_clicked_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\rlastClickedAt\x12!\n" +
+	"\finclude_bots\x18\x06 \x01(\bR\vincludeBots\x12 \n" +
+	"\vgranularity\x18\a \x01(\tR\vgranularity\x12\x1a\n" +
+	"\btimezone\x18\b \x01(\tR\btimezone\x12.\n" +
+	"\x04from\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\x02to\x12,\n" +
+	"\x06series\x18\v \x03(\v2\x14.shortener.v1.BucketR\x06series\x12\x1b\n" +
+	"\tqr_clicks\x18\f \x01(\x03R\bqrClicks\"\x94\x01\n" +
+	"\x06Bucket\x120\n" +
+	"\x05start\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12\x16\n" +
+	"\x06clicks\x18\x02 \x01(\x03R\x06clicks\x12,\n" +
+	"\x0funique_visitors\x18\x03 \x01(\x03H\x00R\x0euniqueVisitors\x88\x01\x01B\x12\n" +
+	"\x10_unique_visitors2\xe6\x03\n" +
+	"\vLinkService\x12O\n" +
+	"\n" +
+	"CreateLink\x12\x1f.shortener.v1.CreateLinkRequest\x1a .shortener.v1.CreateLinkResponse\x12a\n" +
+	"\x10BatchCreateLinks\x12%.shortener.v1.BatchCreateLinksRequest\x1a&.shortener.v1.BatchCreateLinksResponse\x12;\n" +
+	"\aGetLink\x12\x1c.shortener.v1.GetLinkRequest\x1a\x12.shortener.v1.Link\x12A\n" +
+	"\n" +
+	"UpdateLink\x12\x1f.shortener.v1.UpdateLinkRequest\x1a\x12.shortener.v1.Link\x12O\n" +
+	"\n" +
+	"DeleteLink\x12\x1f.shortener.v1.DeleteLinkRequest\x1a .shortener.v1.DeleteLinkResponse\x12R\n" +
+	"\vResolveLink\x12 .shortener.v1.ResolveLinkRequest\x1a!.shortener.v1.ResolveLinkResponse2N\n" +
+	"\fStatsService\x12>\n" +
+	"\bGetStats\x12\x1d.shortener.v1.GetStatsRequest\x1a\x13.shortener.v1.StatsB>Z<github.com/Kristiii101/GO_URL_Shortener_ATAD/pkg/shortenerpbb\x06proto3"
+
+var (
+	file_shortener_v1_shortener_proto_rawDescOnce sync.Once
+	file_shortener_v1_shortener_proto_rawDescData []byte
+)
+
+func file_shortener_v1_shortener_proto_rawDescGZIP() []byte {
+	file_shortener_v1_shortener_proto_rawDescOnce.Do(func() {
+		file_shortener_v1_shortener_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_shortener_v1_shortener_proto_rawDesc), len(file_shortener_v1_shortener_proto_rawDesc)))
+	})
+	return file_shortener_v1_shortener_proto_rawDescData
+}
+
+var file_shortener_v1_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_shortener_v1_shortener_proto_goTypes = []any{
+	(*Link)(nil),                     // 0: shortener.v1.Link
+	(*CreateLinkRequest)(nil),        // 1: shortener.v1.CreateLinkRequest
+	(*CreateLinkResponse)(nil),       // 2: shortener.v1.CreateLinkResponse
+	(*BatchCreateLinksRequest)(nil),  // 3: shortener.v1.BatchCreateLinksRequest
+	(*BatchCreateLinksResponse)(nil), // 4: shortener.v1.BatchCreateLinksResponse
+	(*BatchCreateResult)(nil),        // 5: shortener.v1.BatchCreateResult
+	(*Error)(nil),                    // 6: shortener.v1.Error
+	(*GetLinkRequest)(nil),           // 7: shortener.v1.GetLinkRequest
+	(*UpdateLinkRequest)(nil),        // 8: shortener.v1.UpdateLinkRequest
+	(*DeleteLinkRequest)(nil),        // 9: shortener.v1.DeleteLinkRequest
+	(*DeleteLinkResponse)(nil),       // 10: shortener.v1.DeleteLinkResponse
+	(*ResolveLinkRequest)(nil),       // 11: shortener.v1.ResolveLinkRequest
+	(*ResolveLinkResponse)(nil),      // 12: shortener.v1.ResolveLinkResponse
+	(*GetStatsRequest)(nil),          // 13: shortener.v1.GetStatsRequest
+	(*Stats)(nil),                    // 14: shortener.v1.Stats
+	(*Bucket)(nil),                   // 15: shortener.v1.Bucket
+	(*timestamppb.Timestamp)(nil),    // 16: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),      // 17: google.protobuf.Duration
+}
+var file_shortener_v1_shortener_proto_depIdxs = []int32{
+	16, // 0: shortener.v1.Link.created_at:type_name -> google.protobuf.Timestamp
+	16, // 1: shortener.v1.Link.expires_at:type_name -> google.protobuf.Timestamp
+	17, // 2: shortener.v1.Link.dedupe_window:type_name -> google.protobuf.Duration
+	16, // 3: shortener.v1.CreateLinkRequest.expires_at:type_name -> google.protobuf.Timestamp
+	17, // 4: shortener.v1.CreateLinkRequest.dedupe_window:type_name -> google.protobuf.Duration
+	0,  // 5: shortener.v1.CreateLinkResponse.link:type_name -> shortener.v1.Link
+	1,  // 6: shortener.v1.BatchCreateLinksRequest.links:type_name -> shortener.v1.CreateLinkRequest
+	5,  // 7: shortener.v1.BatchCreateLinksResponse.results:type_name -> shortener.v1.BatchCreateResult
+	2,  // 8: shortener.v1.BatchCreateResult.created:type_name -> shortener.v1.CreateLinkResponse
+	6,  // 9: shortener.v1.BatchCreateResult.error:type_name -> shortener.v1.Error
+	16, // 10: shortener.v1.UpdateLinkRequest.expires_at:type_name -> google.protobuf.Timestamp
+	17, // 11: shortener.v1.UpdateLinkRequest.dedupe_window:type_name -> google.protobuf.Duration
+	16, // 12: shortener.v1.ResolveLinkResponse.expires_at:type_name -> google.protobuf.Timestamp
+	16, // 13: shortener.v1.GetStatsRequest.from:type_name -> google.protobuf.Timestamp
+	16, // 14: shortener.v1.GetStatsRequest.to:type_name -> google.protobuf.Timestamp
+	16, // 15: shortener.v1.Stats.last_clicked_at:type_name -> google.protobuf.Timestamp
+	16, // 16: shortener.v1.Stats.from:type_name -> google.protobuf.Timestamp
+	16, // 17: shortener.v1.Stats.to:type_name -> google.protobuf.Timestamp
+	15, // 18: shortener.v1.Stats.series:type_name -> shortener.v1.Bucket
+	16, // 19: shortener.v1.Bucket.start:type_name -> google.protobuf.Timestamp
+	1,  // 20: shortener.v1.LinkService.CreateLink:input_type -> shortener.v1.CreateLinkRequest
+	3,  // 21: shortener.v1.LinkService.BatchCreateLinks:input_type -> shortener.v1.BatchCreateLinksRequest
+	7,  // 22: shortener.v1.LinkService.GetLink:input_type -> shortener.v1.GetLinkRequest
+	8,  // 23: shortener.v1.LinkService.UpdateLink:input_type -> shortener.v1.UpdateLinkRequest
+	9,  // 24: shortener.v1.LinkService.DeleteLink:input_type -> shortener.v1.DeleteLinkRequest
+	11, // 25: shortener.v1.LinkService.ResolveLink:input_type -> shortener.v1.ResolveLinkRequest
+	13, // 26: shortener.v1.StatsService.GetStats:input_type -> shortener.v1.GetStatsRequest
+	2,  // 27: shortener.v1.LinkService.CreateLink:output_type -> shortener.v1.CreateLinkResponse
+	4,  // 28: shortener.v1.LinkService.BatchCreateLinks:output_type -> shortener.v1.BatchCreateLinksResponse
+	0,  // 29: shortener.v1.LinkService.GetLink:output_type -> shortener.v1.Link
+	0,  // 30: shortener.v1.LinkService.UpdateLink:output_type -> shortener.v1.Link
+	10, // 31: shortener.v1.LinkService.DeleteLink:output_type -> shortener.v1.DeleteLinkResponse
+	12, // 32: shortener.v1.LinkService.ResolveLink:output_type -> shortener.v1.ResolveLinkResponse
+	14, // 33: shortener.v1.StatsService.GetStats:output_type -> shortener.v1.Stats
+	27, // [27:34] is the sub-list for method output_type
+	20, // [20:27] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
+}
+
+func init() { file_shortener_v1_shortener_proto_init() }
+func file_shortener_v1_shortener_proto_init() {
+	if File_shortener_v1_shortener_proto != nil {
+		return
+	}
+	file_shortener_v1_shortener_proto_msgTypes[5].OneofWrappers = []any{
+		(*BatchCreateResult_Created)(nil),
+		(*BatchCreateResult_Error)(nil),
+	}
+	file_shortener_v1_shortener_proto_msgTypes[8].OneofWrappers = []any{
+		(*UpdateLinkRequest_ExpiresAt)(nil),
+		(*UpdateLinkRequest_NeverExpires)(nil),
+		(*UpdateLinkRequest_DedupeWindow)(nil),
+		(*UpdateLinkRequest_DefaultDedupeWindow)(nil),
+	}
+	file_shortener_v1_shortener_proto_msgTypes[15].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shortener_v1_shortener_proto_rawDesc), len(file_shortener_v1_shortener_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_shortener_v1_shortener_proto_goTypes,
+		DependencyIndexes: file_shortener_v1_shortener_proto_depIdxs,
+		MessageInfos:      file_shortener_v1_shortener_proto_msgTypes,
+	}.Build()
+	File_shortener_v1_shortener_proto = out.File
+	file_shortener_v1_shortener_proto_goTypes = nil
+	file_shortener_v1_shortener_proto_depIdxs = nil
+}